@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"1337b04rd/config"
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/adapters/memory"
 	"1337b04rd/internal/adapters/postgres"
 	"1337b04rd/internal/adapters/rickmorty"
 	"1337b04rd/internal/adapters/s3"
 	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
 	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/flood"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/thread"
 	"context"
 	"flag"
 	"fmt"
@@ -37,8 +43,15 @@ func Run() {
 
 	// Repositories
 	sessionRepo := postgres.NewSessionRepository(db)
+	boardRepo := postgres.NewBoardRepository(db)
 	threadRepo := postgres.NewThreadRepository(db)
 	commentRepo := postgres.NewCommentRepository(db)
+	searchRepo := postgres.NewSearchRepository(db)
+	revisionRepo := postgres.NewRevisionRepository(db)
+	referenceRepo := postgres.NewReferenceRepository(db)
+	moderatorRepo := postgres.NewModeratorRepository(db)
+	reportRepo := postgres.NewReportRepository(db)
+	banRepo := postgres.NewBanRepository(db)
 
 	// External HTTP clients
 	httpClient := &http.Client{}
@@ -50,17 +63,92 @@ func Run() {
 
 	// Services
 	avatarSvc := services.NewAvatarService(avatarClient)
-	sessionSvc := services.NewSessionService(sessionRepo, avatarSvc, cfg.Session.Duration)
+	sessionSvc := services.NewSessionService(sessionRepo, avatarSvc, cfg.Session.Duration, cfg.Session.TripcodePepper)
 
 	threadS3Adapter := s3.NewAdapter(s3ThreadsClient)
 	commentS3Adapter := s3.NewAdapter(s3CommentsClient)
 
-	threadSvc := services.NewThreadService(threadRepo, threadS3Adapter)
-	commentSvc := services.NewCommentService(commentRepo, threadRepo, commentS3Adapter, sessionRepo)
+	// In-process fan-out for live thread events (SSE)
+	eventBroker := events.NewBroker()
+
+	// Thread expiry policy
+	expiry := services.ExpirySettings{
+		Policy: cfg.Expiry.Policy,
+		Boards: cfg.Expiry.Boards,
+		TTL: thread.FixedTTL{
+			NoReplies:  cfg.Expiry.NoReplyTTL,
+			AfterReply: cfg.Expiry.ReplyTTL,
+		},
+		Capacity:         cfg.Expiry.Capacity,
+		ArchiveRetention: cfg.Expiry.ArchiveRetention,
+	}
+
+	// Post size limits
+	limits := post.Limits{
+		MaxTitleLength:    cfg.Posts.MaxTitleLength,
+		MaxContentLength:  cfg.Posts.MaxContentLength,
+		MaxLines:          cfg.Posts.MaxLines,
+		MaxAttachments:    cfg.Posts.MaxAttachments,
+		MaxAttachmentSize: cfg.Posts.MaxAttachmentSize,
+	}
+
+	// Flood control, shared by all instances when kept in Postgres
+	var floodStore ports.FloodPort = memory.NewFloodStore()
+	if cfg.Flood.Store == "postgres" {
+		floodStore = postgres.NewFloodRepository(db)
+	}
+	postGuard := services.NewPostGuard(floodStore, flood.Rules{
+		PostInterval:    cfg.Flood.PostInterval,
+		ThreadInterval:  cfg.Flood.ThreadInterval,
+		DuplicateWindow: cfg.Flood.DuplicateWindow,
+	})
+
+	threadSvc := services.NewThreadService(threadRepo, boardRepo, revisionRepo, referenceRepo, threadS3Adapter, commentS3Adapter, eventBroker, expiry, cfg.Posts.EditWindow, cfg.Posts.PosterIDSecret, limits, postGuard)
+	commentSvc := services.NewCommentService(commentRepo, threadRepo, boardRepo, revisionRepo, referenceRepo, commentS3Adapter, sessionRepo, eventBroker, cfg.Posts.EditWindow, cfg.Posts.PosterIDSecret, limits, postGuard)
+	searchSvc := services.NewSearchService(searchRepo)
+	boardSvc := services.NewBoardService(boardRepo)
+
+	// CAPTCHA challenges, shared by all instances when kept in Postgres
+	var captchaStore ports.CaptchaPort = memory.NewCaptchaStore()
+	if cfg.Captcha.Store == "postgres" {
+		captchaStore = postgres.NewCaptchaRepository(db)
+	}
+	captchaSvc := services.NewCaptchaService(captchaStore, sessionRepo, services.CaptchaSettings{
+		Actions:    cfg.Captcha.Actions,
+		TTL:        cfg.Captcha.TTL,
+		TrustAfter: cfg.Captcha.TrustAfter,
+	})
+
+	// Proof of work before posting; used challenges are kept with the flood claims
+	var pow *httpadapter.ProofOfWork
+	if cfg.Pow.Difficulty > 0 {
+		pow = httpadapter.NewProofOfWork(cfg.Pow.Secret, httpadapter.PowSettings{
+			Difficulty:    cfg.Pow.Difficulty,
+			MaxDifficulty: cfg.Pow.MaxDifficulty,
+			TTL:           cfg.Pow.TTL,
+			Window:        cfg.Pow.Window,
+			LoadThreshold: cfg.Pow.LoadThreshold,
+		}, floodStore)
+	}
+
+	// Moderator accounts; the first admin comes from the config. Login
+	// attempts are kept with the flood claims.
+	loginGuard := services.NewLoginGuard(floodStore, flood.LoginRules{
+		Attempts: cfg.Mod.LoginAttempts,
+		Window:   cfg.Mod.LoginWindow,
+	}, cfg.Mod.LoginHashes)
+	modSvc := services.NewModeratorService(moderatorRepo, cfg.Mod.LoginTTL, loginGuard)
+	if err := modSvc.Bootstrap(context.Background(), cfg.Mod.AdminUsername, cfg.Mod.AdminPassword); err != nil {
+		logger.Error("failed to create the first admin", "error", err)
+		return
+	}
 
 	// HTTP router
-	router := httpadapter.NewRouter(sessionSvc, avatarSvc, threadSvc, commentSvc)
-	corsRouter := withCORS(router)
+	banSvc := services.NewBanService(banRepo, sessionRepo, boardRepo, threadRepo, commentRepo, cfg.Ban.IPSalt, cfg.Ban.Duration)
+	reportSvc := services.NewReportService(reportRepo, threadRepo, commentRepo, threadSvc, commentSvc, banSvc)
+
+	router := httpadapter.NewRouter(sessionSvc, avatarSvc, threadSvc, commentSvc, searchSvc, boardSvc, captchaSvc, pow, modSvc, reportSvc, banSvc)
+	corsRouter := withCORS(httpadapter.ClientIPMiddleware(cfg.Flood.IPHeader)(router))
 
 	// запуск фонового удаления
 	go func() {
@@ -72,6 +160,18 @@ func Run() {
 			if err := threadSvc.CleanupExpiredThreads(ctx); err != nil {
 				logger.Error("thread cleanup failed", "error", err)
 			}
+			if err := postGuard.Cleanup(ctx); err != nil {
+				logger.Error("flood claim cleanup failed", "error", err)
+			}
+			if err := captchaSvc.Cleanup(ctx); err != nil {
+				logger.Error("captcha cleanup failed", "error", err)
+			}
+			if err := modSvc.Cleanup(ctx); err != nil {
+				logger.Error("moderator login cleanup failed", "error", err)
+			}
+			if err := banSvc.Cleanup(ctx); err != nil {
+				logger.Error("ban cleanup failed", "error", err)
+			}
 		}
 	}()
 
@@ -91,8 +191,8 @@ func withCORS(next http.Handler) http.Handler {
 		if origin == "http://localhost:5500" {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Pow-Challenge, X-Pow-Nonce")
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusNoContent)
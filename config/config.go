@@ -1,6 +1,11 @@
 package config
 
 import (
+	"1337b04rd/internal/domain/flood"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/thread"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"os"
 	"strconv"
@@ -33,12 +38,99 @@ type Config struct {
 	Session struct {
 		CookieName string
 		Duration   time.Duration
+		// TripcodePepper keys secure tripcodes (name##secret). Empty
+		// leaves only ordinary ones.
+		TripcodePepper string
 	}
 
 	AvatarAPI struct {
 		BaseURL string
 	}
 
+	// Expiry selects when active threads get archived. Policy and the
+	// per-board overrides take one of "ttl", "capacity", "ttl+capacity".
+	Expiry struct {
+		Policy     string
+		NoReplyTTL time.Duration
+		ReplyTTL   time.Duration
+		Capacity   int
+		Boards     map[string]string
+		// ArchiveRetention is how long archived threads are kept before
+		// they are purged. Zero keeps them forever.
+		ArchiveRetention time.Duration
+	}
+
+	// Posts holds limits on what authors may do with their own posts.
+	Posts struct {
+		// EditWindow is how long after posting the author may edit or
+		// delete a thread or comment.
+		EditWindow time.Duration
+		// PosterIDSecret keys the per-thread poster IDs. It must stay the
+		// same across restarts, since the IDs are derived on read.
+		PosterIDSecret string
+		// Size limits of a post, see post.Limits. Zero turns a limit off.
+		MaxTitleLength    int
+		MaxContentLength  int
+		MaxLines          int
+		MaxAttachments    int
+		MaxAttachmentSize int64
+	}
+
+	// Flood configures the posting guard, see flood.Rules.
+	Flood struct {
+		PostInterval    time.Duration
+		ThreadInterval  time.Duration
+		DuplicateWindow time.Duration
+		// Store is "memory" for a single instance or "postgres" to share
+		// the claims between instances.
+		Store string
+		// IPHeader names the header a trusted reverse proxy puts the
+		// client address in. Empty uses the peer address.
+		IPHeader string
+	}
+
+	// Captcha chooses which posts need a solved CAPTCHA, see
+	// services.CaptchaSettings.
+	Captcha struct {
+		Actions    map[post.Action]bool
+		TTL        time.Duration
+		TrustAfter int
+		// Store is "memory" or "postgres", like Flood.Store.
+		Store string
+	}
+
+	// Pow configures the proof of work asked before a post, see
+	// http.PowSettings. A Difficulty of 0 turns it off.
+	Pow struct {
+		Difficulty    int
+		MaxDifficulty int
+		TTL           time.Duration
+		Window        time.Duration
+		LoadThreshold int
+		Secret        string
+	}
+
+	// Mod configures moderator logins. AdminUsername and AdminPassword
+	// create the first admin when there are no moderators yet.
+	// LoginAttempts logins per LoginWindow are allowed from one address,
+	// and at most LoginHashes passwords are checked at once.
+	Mod struct {
+		LoginTTL      time.Duration
+		LoginAttempts int
+		LoginWindow   time.Duration
+		LoginHashes   int
+		AdminUsername string
+		AdminPassword string
+	}
+
+	// Ban configures bans. IPSalt keys the stored hashes of addresses;
+	// Duration is the length of a ban when the moderator gives none, 0
+	// for bans that never end.
+	Ban struct {
+		IPSalt   string
+		Duration time.Duration
+	}
+
 	AppEnv string
 }
 
@@ -78,10 +170,70 @@ func Load() *Config {
 	// Session
 	cfg.Session.CookieName = getOrDefault("SESSION_COOKIE_NAME", "1337session")
 	cfg.Session.Duration = time.Hour * 24 * time.Duration(mustGetInt("SESSION_DURATION_DAYS"))
+	cfg.Session.TripcodePepper = os.Getenv("TRIPCODE_PEPPER")
 
 	// Avatar API
 	cfg.AvatarAPI.BaseURL = mustGet("AVATAR_API_BASE_URL")
 
+	// Thread expiry
+	cfg.Expiry.Policy = mustGetPolicy("THREAD_EXPIRY_POLICY", getOrDefault("THREAD_EXPIRY_POLICY", thread.PolicyTTLCapacity))
+	cfg.Expiry.NoReplyTTL = getDuration("THREAD_TTL_NO_REPLIES", 10*time.Minute)
+	cfg.Expiry.ReplyTTL = getDuration("THREAD_TTL_AFTER_REPLY", 15*time.Minute)
+	cfg.Expiry.Capacity = getInt("THREAD_CAPACITY", 0)
+	cfg.Expiry.Boards = getBoardPolicies("THREAD_EXPIRY_BOARDS")
+	cfg.Expiry.ArchiveRetention = getDuration("THREAD_ARCHIVE_RETENTION", 0)
+
+	// Posts
+	cfg.Posts.EditWindow = getDuration("POST_EDIT_WINDOW", 5*time.Minute)
+	// A random secret would give every post a new poster ID after a
+	// restart, so there is no fallback.
+	cfg.Posts.PosterIDSecret = mustGet("POSTER_ID_SECRET")
+	limits := post.DefaultLimits()
+	cfg.Posts.MaxTitleLength = getInt("POST_MAX_TITLE_LENGTH", limits.MaxTitleLength)
+	cfg.Posts.MaxContentLength = getInt("POST_MAX_CONTENT_LENGTH", limits.MaxContentLength)
+	cfg.Posts.MaxLines = getInt("POST_MAX_LINES", limits.MaxLines)
+	cfg.Posts.MaxAttachments = getInt("POST_MAX_ATTACHMENTS", limits.MaxAttachments)
+	cfg.Posts.MaxAttachmentSize = int64(getInt("POST_MAX_ATTACHMENT_SIZE", int(limits.MaxAttachmentSize)))
+
+	// Flood control
+	rules := flood.DefaultRules()
+	cfg.Flood.PostInterval = getDurationOrOff("FLOOD_POST_INTERVAL", rules.PostInterval)
+	cfg.Flood.ThreadInterval = getDurationOrOff("FLOOD_THREAD_INTERVAL", rules.ThreadInterval)
+	cfg.Flood.DuplicateWindow = getDurationOrOff("FLOOD_DUPLICATE_WINDOW", rules.DuplicateWindow)
+	cfg.Flood.Store = mustGetStore("FLOOD_STORE")
+	cfg.Flood.IPHeader = os.Getenv("FLOOD_IP_HEADER")
+
+	// CAPTCHA
+	cfg.Captcha.Actions = getPostActions("CAPTCHA_ACTIONS", "thread")
+	cfg.Captcha.TTL = getDuration("CAPTCHA_TTL", 10*time.Minute)
+	cfg.Captcha.TrustAfter = getInt("CAPTCHA_TRUST_AFTER", 3)
+	cfg.Captcha.Store = mustGetStore("CAPTCHA_STORE")
+
+	// Proof of work
+	cfg.Pow.Difficulty = getInt("POW_DIFFICULTY", 0)
+	cfg.Pow.MaxDifficulty = getInt("POW_MAX_DIFFICULTY", cfg.Pow.Difficulty+8)
+	cfg.Pow.TTL = getDuration("POW_TTL", 5*time.Minute)
+	cfg.Pow.Window = getDuration("POW_WINDOW", 10*time.Minute)
+	cfg.Pow.LoadThreshold = getInt("POW_LOAD_THRESHOLD", 30)
+	if cfg.Pow.Difficulty > 0 {
+		cfg.Pow.Secret = getSecret("POW_SECRET")
+	}
+
+	// Moderators
+	cfg.Mod.LoginTTL = getDuration("MOD_LOGIN_TTL", 12*time.Hour)
+	loginRules := flood.DefaultLoginRules()
+	cfg.Mod.LoginAttempts = getInt("MOD_LOGIN_ATTEMPTS", loginRules.Attempts)
+	cfg.Mod.LoginWindow = getDuration("MOD_LOGIN_WINDOW", loginRules.Window)
+	cfg.Mod.LoginHashes = getInt("MOD_LOGIN_HASHES", 4)
+	cfg.Mod.AdminUsername = os.Getenv("MOD_ADMIN_USERNAME")
+	cfg.Mod.AdminPassword = os.Getenv("MOD_ADMIN_PASSWORD")
+
+	// Bans
+	// A random salt would stop the stored address hashes from matching
+	// after a restart, so there is no fallback.
+	cfg.Ban.IPSalt = mustGet("BAN_IP_SALT")
+	cfg.Ban.Duration = getDurationOrOff("BAN_DURATION", 72*time.Hour)
+
 	// App env
 	cfg.AppEnv = getOrDefault("APP_ENV", "development")
 
@@ -120,6 +272,108 @@ func getBool(key string) bool {
 	return val == "true" || val == "1"
 }
 
+func getInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid integer value for %s: %s", key, val)
+	}
+	return n
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid duration value for %s: %s", key, val)
+	}
+	return d
+}
+
+// getDurationOrOff is getDuration that also takes 0 to turn a check off.
+func getDurationOrOff(key string, def time.Duration) time.Duration {
+	if os.Getenv(key) == "0" {
+		return 0
+	}
+	return getDuration(key, def)
+}
+
+// getSecret returns the value of key, or a random secret for this run when
+// it is unset.
+func getSecret(key string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("Cannot generate a value for %s: %v", key, err)
+	}
+	log.Printf("%s is not set, using a random one until restart", key)
+	return hex.EncodeToString(buf)
+}
+
+func mustGetPolicy(key, val string) string {
+	if !thread.ValidPolicyKind(val) {
+		log.Fatalf("Invalid expiry policy for %s: %s", key, val)
+	}
+	return val
+}
+
+// mustGetStore reads where state shared by instances is kept: "memory"
+// (the default) or "postgres".
+func mustGetStore(key string) string {
+	val := getOrDefault(key, "memory")
+	if val != "memory" && val != "postgres" {
+		log.Fatalf("Invalid value for %s: %s", key, val)
+	}
+	return val
+}
+
+// getPostActions parses a comma-separated list of post actions, e.g.
+// "thread,comment". "off" selects none.
+func getPostActions(key, def string) map[post.Action]bool {
+	actions := make(map[post.Action]bool)
+	val := getOrDefault(key, def)
+	if val == "off" {
+		return actions
+	}
+
+	for _, name := range strings.Split(val, ",") {
+		action := post.Action(strings.TrimSpace(name))
+		if action != post.ActionThread && action != post.ActionComment {
+			log.Fatalf("Invalid entry in %s: %s", key, name)
+		}
+		actions[action] = true
+	}
+	return actions
+}
+
+// getBoardPolicies parses "slug:policy" pairs separated by commas,
+// e.g. "b:ttl,g:capacity".
+func getBoardPolicies(key string) map[string]string {
+	policies := make(map[string]string)
+	val := os.Getenv(key)
+	if val == "" {
+		return policies
+	}
+
+	for _, pair := range strings.Split(val, ",") {
+		slug, policy, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || slug == "" {
+			log.Fatalf("Invalid entry in %s: %s", key, pair)
+		}
+		policies[slug] = mustGetPolicy(key, policy)
+	}
+	return policies
+}
+
 // === .env loader using stdlib ===
 
 func loadDotEnv(path string) {
@@ -1,6 +1,17 @@
 -- Clean up the database
+DROP TABLE IF EXISTS bans;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS report_cases;
+DROP TABLE IF EXISTS moderator_logins;
+DROP TABLE IF EXISTS moderators;
+DROP TABLE IF EXISTS captcha_challenges;
+DROP TABLE IF EXISTS flood_claims;
+DROP TABLE IF EXISTS comment_references;
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS thread_revisions;
 DROP TABLE IF EXISTS comments;
 DROP TABLE IF EXISTS threads;
+DROP TABLE IF EXISTS boards;
 DROP TABLE IF EXISTS sessions;
 
 -- sessions
@@ -8,23 +19,71 @@ CREATE TABLE sessions (
     id UUID PRIMARY KEY,
     avatar_url TEXT NOT NULL,
     display_name TEXT NOT NULL,
+    tripcode TEXT NOT NULL DEFAULT '',
+    captchas_solved INT NOT NULL DEFAULT 0,
+    -- salted hash of the address the session last wrote from; '' for none
+    ip_hash TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     expires_at TIMESTAMP NOT NULL
 );
 
+-- boards
+CREATE TABLE boards (
+    id UUID PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    max_threads INTEGER NOT NULL DEFAULT 0,   -- 0 = no cap on active threads
+    max_images INTEGER NOT NULL DEFAULT 4,    -- per post
+    bump_limit INTEGER NOT NULL DEFAULT 300,  -- 0 = replies always bump
+    poster_ids BOOLEAN NOT NULL DEFAULT FALSE, -- show per-thread poster IDs
+    -- last post number handed out, maintained by trg_*_post_number
+    post_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_board_slug CHECK (slug ~ '^[a-z0-9]{1,16}$'),
+    CONSTRAINT check_board_title_not_empty CHECK (char_length(title) > 0),
+    CONSTRAINT check_board_limits CHECK (max_threads >= 0 AND max_images >= 0 AND bump_limit >= 0)
+);
+
+INSERT INTO boards (id, slug, title, description, max_threads, max_images, bump_limit, poster_ids) VALUES
+    (gen_random_uuid(), 'b', 'Random', 'Anything goes.', 100, 4, 300, TRUE),
+    (gen_random_uuid(), 'g', 'Technology', 'Programming, hardware and software.', 50, 4, 500, FALSE),
+    (gen_random_uuid(), 'a', 'Anime & Manga', 'Japanese animation and comics.', 50, 4, 500, FALSE);
+
 -- threads
 CREATE TABLE threads (
     id UUID PRIMARY KEY,
+    board_id UUID NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+    -- shares the board's sequence with comments, see assign_post_number
+    post_number BIGINT NOT NULL,
     title TEXT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT[],
     session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    -- the session's tripcode when posting; '' for none
+    tripcode TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     last_commented TIMESTAMP,
-    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    edited_at TIMESTAMP,
+    -- purged threads are deleted, so that state is never stored
+    state TEXT NOT NULL DEFAULT 'active',
+    archived_at TIMESTAMP,
+    is_pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    -- maintained by trg_bump_thread
+    bumped_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    bump_count INTEGER NOT NULL DEFAULT 0,
+    reply_count INTEGER NOT NULL DEFAULT 0,
+    -- 'simple' keeps search language-neutral: posts mix languages, so no stemming
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', content), 'B')
+    ) STORED,
 
     CONSTRAINT check_title_not_empty CHECK (char_length(title) > 0),
-    CONSTRAINT check_content_not_empty CHECK (char_length(content) > 0)
+    CONSTRAINT check_content_not_empty CHECK (char_length(content) > 0),
+    CONSTRAINT check_thread_state CHECK (state IN ('active', 'locked', 'archived')),
+    CONSTRAINT unique_thread_post_number UNIQUE (board_id, post_number)
 );
 
 -- comments
@@ -32,32 +91,227 @@ CREATE TABLE comments (
     id UUID PRIMARY KEY,
     thread_id UUID NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
     parent_comment_id UUID REFERENCES comments(id) ON DELETE SET NULL,
+    post_number BIGINT NOT NULL,
     content TEXT NOT NULL,
     image_url TEXT[],
     session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    tripcode TEXT NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    is_sage BOOLEAN NOT NULL DEFAULT FALSE,
+    -- deleted comments are kept as tombstones so replies keep their parent
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    edited_at TIMESTAMP,
+    search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED,
 
     CONSTRAINT check_comment_content_not_empty CHECK (char_length(content) > 0)
 );
 
+-- revisions: the versions of a post replaced by its author's edits
+CREATE TABLE thread_revisions (
+    id UUID PRIMARY KEY,
+    thread_id UUID NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE comment_revisions (
+    id UUID PRIMARY KEY,
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- references: the >> links of a comment to its thread or to comments of
+-- the same thread; backlinks are read in the other direction
+CREATE TABLE comment_references (
+    comment_id UUID NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    thread_id UUID NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL,
+    PRIMARY KEY (comment_id, target_id)
+);
+
+-- flood_claims: keys held by recent posts (per session and per address,
+-- also for the content they posted) and by login attempts until they
+-- expire, shared by every app instance
+CREATE TABLE flood_claims (
+    key TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- captcha_challenges: CAPTCHA answers waiting to be typed back; each is
+-- deleted when it is answered or expires
+CREATE TABLE captcha_challenges (
+    id UUID PRIMARY KEY,
+    answer TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- moderators: accounts of the people who look after the board, unrelated
+-- to the anonymous sessions; the first admin is created from the config
+CREATE TABLE moderators (
+    id UUID PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    -- argon2id in the encoded $argon2id$v=19$m=...$salt$key form
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_moderator_role CHECK (role IN ('janitor', 'moderator', 'admin'))
+);
+
+-- moderator_logins: logged in moderators, by the SHA-256 of their cookie
+CREATE TABLE moderator_logins (
+    token_hash TEXT PRIMARY KEY,
+    moderator_id UUID NOT NULL REFERENCES moderators(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- report_cases: the reports on one post, claimed and resolved together by
+-- a moderator; they go with the thread of the post
+CREATE TABLE report_cases (
+    id UUID PRIMARY KEY,
+    -- comment_id when the post is a comment, thread_id otherwise
+    post_id UUID NOT NULL,
+    thread_id UUID NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+    comment_id UUID,
+    post_number BIGINT NOT NULL,
+    author_session_id UUID NOT NULL,
+    -- sum of the reason weights of the reports, see report.Reason
+    score INTEGER NOT NULL DEFAULT 0,
+    report_count INTEGER NOT NULL DEFAULT 0,
+    opened_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_reported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_by UUID REFERENCES moderators(id) ON DELETE SET NULL,
+    claimed_at TIMESTAMPTZ,
+    -- NULL while open
+    resolution TEXT,
+    resolved_by UUID REFERENCES moderators(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMPTZ,
+
+    CONSTRAINT check_report_resolution CHECK (resolution IN ('dismiss', 'delete', 'ban'))
+);
+
+-- reports: one per session and post, kept after their case is resolved
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    case_id UUID NOT NULL REFERENCES report_cases(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL,
+    session_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_report_reason CHECK (reason IN ('illegal', 'abuse', 'spam', 'off_topic', 'other')),
+    CONSTRAINT unique_report_per_session UNIQUE (post_id, session_id)
+);
+
+-- bans: by session, by salted address hash or both. They outlive the
+-- sessions they name, so session_id is not a foreign key.
+CREATE TABLE bans (
+    id UUID PRIMARY KEY,
+    session_id UUID,
+    ip_hash TEXT NOT NULL DEFAULT '',
+    -- NULL bans from every board
+    board_id UUID REFERENCES boards(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    created_by UUID REFERENCES moderators(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- NULL for a ban that never ends
+    expires_at TIMESTAMPTZ,
+
+    CONSTRAINT check_ban_target CHECK (session_id IS NOT NULL OR ip_hash <> '')
+);
+
 -- triggers
-CREATE OR REPLACE FUNCTION update_last_commented()
+-- Every reply counts towards reply_count and last_commented. Only non-sage
+-- replies made before the board's bump limit move bumped_at forward.
+CREATE OR REPLACE FUNCTION bump_thread()
 RETURNS TRIGGER AS $$
+DECLARE
+  bumps BOOLEAN;
 BEGIN
+  SELECT NOT NEW.is_sage AND (b.bump_limit = 0 OR t.bump_count < b.bump_limit)
+  INTO bumps
+  FROM threads t
+  JOIN boards b ON b.id = t.board_id
+  WHERE t.id = NEW.thread_id
+  FOR UPDATE OF t;
+
   UPDATE threads
-  SET last_commented = NEW.created_at
+  SET last_commented = NEW.created_at,
+      reply_count = reply_count + 1,
+      bump_count = bump_count + CASE WHEN bumps THEN 1 ELSE 0 END,
+      bumped_at = CASE WHEN bumps THEN NEW.created_at ELSE bumped_at END
   WHERE id = NEW.thread_id;
   RETURN NEW;
 END;
 $$ LANGUAGE plpgsql;
 
-CREATE TRIGGER trg_update_last_commented
+CREATE TRIGGER trg_bump_thread
 AFTER INSERT ON comments
 FOR EACH ROW
-EXECUTE FUNCTION update_last_commented();
+EXECUTE FUNCTION bump_thread();
+
+-- Threads and comments are numbered per board from one counter. Bumping
+-- the board row takes its lock, so concurrent posts get distinct numbers
+-- and a rolled back post never leaves a gap visible to others.
+CREATE OR REPLACE FUNCTION assign_post_number()
+RETURNS TRIGGER AS $$
+DECLARE
+  board UUID;
+BEGIN
+  IF TG_TABLE_NAME = 'threads' THEN
+    board := NEW.board_id;
+  ELSE
+    SELECT board_id INTO board FROM threads WHERE id = NEW.thread_id;
+  END IF;
+
+  UPDATE boards
+  SET post_count = post_count + 1
+  WHERE id = board
+  RETURNING post_count INTO NEW.post_number;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_thread_post_number
+BEFORE INSERT ON threads
+FOR EACH ROW
+EXECUTE FUNCTION assign_post_number();
+
+CREATE TRIGGER trg_comment_post_number
+BEFORE INSERT ON comments
+FOR EACH ROW
+EXECUTE FUNCTION assign_post_number();
 
 -- indexes
 CREATE INDEX idx_comments_thread_id ON comments(thread_id);
+CREATE INDEX idx_comments_post_number ON comments(post_number);
 CREATE INDEX idx_comments_parent_comment_id ON comments(parent_comment_id);
 CREATE INDEX idx_threads_last_commented ON threads(last_commented);
+CREATE INDEX idx_threads_created_at_id ON threads(created_at DESC, id DESC);
+CREATE INDEX idx_threads_board_created_at_id ON threads(board_id, created_at DESC, id DESC);
+CREATE INDEX idx_threads_bumped_at_id ON threads(bumped_at DESC, id DESC) WHERE state <> 'archived';
+CREATE INDEX idx_threads_board_bumped_at_id ON threads(board_id, bumped_at DESC, id DESC) WHERE state <> 'archived';
+CREATE INDEX idx_threads_pinned ON threads(board_id) WHERE is_pinned = TRUE AND state <> 'archived';
+CREATE INDEX idx_threads_archived_at ON threads(archived_at) WHERE state = 'archived';
+CREATE INDEX idx_comments_thread_created_at_id ON comments(thread_id, created_at, id);
+CREATE INDEX idx_threads_search_vector ON threads USING GIN (search_vector);
+CREATE INDEX idx_comments_search_vector ON comments USING GIN (search_vector);
 CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+-- Renames look names up here. It is not unique: the names new sessions get
+-- from the avatar API repeat.
+CREATE INDEX idx_sessions_plain_name ON sessions(lower(display_name)) WHERE tripcode = '';
+CREATE INDEX idx_flood_claims_expires_at ON flood_claims(expires_at);
+CREATE INDEX idx_captcha_challenges_expires_at ON captcha_challenges(expires_at);
+CREATE INDEX idx_moderator_logins_expires_at ON moderator_logins(expires_at);
+CREATE UNIQUE INDEX idx_report_cases_open_post ON report_cases(post_id) WHERE resolution IS NULL;
+CREATE INDEX idx_report_cases_queue ON report_cases(score DESC, last_reported_at DESC) WHERE resolution IS NULL;
+CREATE INDEX idx_reports_case_id ON reports(case_id);
+CREATE INDEX idx_bans_session_id ON bans(session_id) WHERE session_id IS NOT NULL;
+CREATE INDEX idx_bans_ip_hash ON bans(ip_hash) WHERE ip_hash <> '';
+CREATE INDEX idx_thread_revisions_thread_id ON thread_revisions(thread_id, created_at);
+CREATE INDEX idx_comment_revisions_comment_id ON comment_revisions(comment_id, created_at);
+CREATE INDEX idx_comment_references_thread_id ON comment_references(thread_id);
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/event"
+	"sync"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+const subscriberBuffer = 16
+
+// Broker fans thread events out to in-process subscribers.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[uuidHelper.UUID]map[chan event.Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[uuidHelper.UUID]map[chan event.Event]struct{}),
+	}
+}
+
+func (b *Broker) Publish(e event.Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[e.ThreadID] {
+		select {
+		case ch <- e:
+		default:
+			logger.Warn("dropping event for slow subscriber", "thread_id", e.ThreadID, "type", e.Type)
+		}
+	}
+}
+
+func (b *Broker) Subscribe(threadID uuidHelper.UUID) (<-chan event.Event, func()) {
+	ch := make(chan event.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[threadID] == nil {
+		b.subscribers[threadID] = make(map[chan event.Event]struct{})
+	}
+	b.subscribers[threadID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[threadID], ch)
+			if len(b.subscribers[threadID]) == 0 {
+				delete(b.subscribers, threadID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
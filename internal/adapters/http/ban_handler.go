@@ -0,0 +1,186 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/errors"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// boardScope finds the board a write goes to, or nil when it is unknown.
+type boardScope func(w http.ResponseWriter, r *http.Request) (*utils.UUID, error)
+
+// banFailFunc answers a refused write: with the ban, or with an error
+// status and message when the ban could not be checked.
+type banFailFunc func(w http.ResponseWriter, r *http.Request, status int, msg string, b *ban.Ban)
+
+// BanGuard refuses writes from banned sessions and addresses. A nil
+// BanGuard lets everything through.
+type BanGuard struct {
+	banSvc    *services.BanService
+	threadSvc *services.ThreadService
+}
+
+func NewBanGuard(banSvc *services.BanService, threadSvc *services.ThreadService) *BanGuard {
+	if banSvc == nil {
+		return nil
+	}
+	return &BanGuard{banSvc: banSvc, threadSvc: threadSvc}
+}
+
+// Require wraps a write handler so that it only runs for a session and
+// address that are not banned from the board scope finds. A nil scope
+// marks a write that belongs to no board, which only bans from every board
+// stop.
+func (g *BanGuard) Require(next http.HandlerFunc, scope boardScope, fail banFailFunc) http.HandlerFunc {
+	if g == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := GetSessionFromContext(r.Context())
+		if !ok {
+			fail(w, r, http.StatusUnauthorized, "session not found", nil)
+			return
+		}
+
+		var board func() (*utils.UUID, error)
+		if scope != nil {
+			board = func() (*utils.UUID, error) { return scope(w, r) }
+		}
+		b, err := g.banSvc.Check(r.Context(), sess, clientIP(r), board)
+		switch {
+		case err != nil:
+			logger.Error("failed to check bans", "error", err)
+			fail(w, r, http.StatusInternalServerError, "could not check bans", nil)
+		case b != nil:
+			fail(w, r, http.StatusForbidden, errors.ErrBanned.Error(), b)
+		default:
+			next(w, r)
+		}
+	}
+}
+
+// newThreadBoard is the board of a new thread. Outside the board routes
+// it is a form field, so the form is parsed here, the way the handler
+// would; a form that fails to parse is left for the handler to refuse.
+func (g *BanGuard) newThreadBoard(w http.ResponseWriter, r *http.Request) (*utils.UUID, error) {
+	if r.PathValue("slug") == "" {
+		if err := parsePostForm(w, r, g.threadSvc.Limits(), 20<<20); err != nil {
+			return nil, nil
+		}
+	}
+	return g.banSvc.BoardBySlug(r.Context(), boardSlug(r))
+}
+
+func (g *BanGuard) threadBoard(w http.ResponseWriter, r *http.Request) (*utils.UUID, error) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		return nil, nil
+	}
+	return g.banSvc.BoardOfThread(r.Context(), id)
+}
+
+func (g *BanGuard) commentBoard(w http.ResponseWriter, r *http.Request) (*utils.UUID, error) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		return nil, nil
+	}
+	return g.banSvc.BoardOfComment(r.Context(), id)
+}
+
+// respondBanError answers a refused API write.
+func respondBanError(w http.ResponseWriter, _ *http.Request, status int, msg string, b *ban.Ban) {
+	if b == nil {
+		RespondError(w, status, msg)
+		return
+	}
+	Respond(w, status, errorResponse{Error: msg, Ban: toBanNoticeResponse(b)})
+}
+
+type BanHandler struct {
+	banSvc *services.BanService
+}
+
+func NewBanHandler(banSvc *services.BanService) *BanHandler {
+	return &BanHandler{banSvc: banSvc}
+}
+
+// GET /mod/bans
+func (h *BanHandler) ListBans(w http.ResponseWriter, r *http.Request) {
+	bans, err := h.banSvc.ListBans(r.Context())
+	if err != nil {
+		respondBanAdminError(w, err, "list bans")
+		return
+	}
+
+	result := make([]banResponse, 0, len(bans))
+	for _, b := range bans {
+		result = append(result, toBanResponse(b))
+	}
+	Respond(w, http.StatusOK, result)
+}
+
+// POST /mod/bans
+func (h *BanHandler) CreateBan(w http.ResponseWriter, r *http.Request) {
+	var req createBanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	postID, err := utils.ParseUUID(req.PostID)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid post ID")
+		return
+	}
+
+	m, _ := GetModeratorFromContext(r.Context())
+	b, err := h.banSvc.BanAuthor(r.Context(), m, postID, req.BoardOnly, req.Reason, banDuration(req.Duration, h.banSvc))
+	if err != nil {
+		respondBanAdminError(w, err, "ban author")
+		return
+	}
+
+	Respond(w, http.StatusCreated, toBanResponse(b))
+}
+
+// DELETE /mod/bans/{id}
+func (h *BanHandler) LiftBan(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid ban ID")
+		return
+	}
+
+	m, _ := GetModeratorFromContext(r.Context())
+	if err := h.banSvc.Lift(r.Context(), id, m); err != nil {
+		respondBanAdminError(w, err, "lift ban")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// banDuration reads a requested ban length in seconds; null takes the
+// default. A negative length is left for the service to refuse.
+func banDuration(seconds *int64, banSvc *services.BanService) time.Duration {
+	if seconds == nil {
+		return banSvc.DefaultDuration()
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+func respondBanAdminError(w http.ResponseWriter, err error, action string) {
+	switch err {
+	case errors.ErrInvalidBanReason, errors.ErrInvalidBanDuration:
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrPostNotFound, errors.ErrBanNotFound:
+		RespondError(w, http.StatusNotFound, err.Error())
+	default:
+		logger.Error("failed to "+action, "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not "+action)
+	}
+}
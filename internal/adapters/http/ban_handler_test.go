@@ -0,0 +1,93 @@
+package http
+
+import (
+	"1337b04rd/internal/domain/moderator"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBans(t *testing.T) {
+	s := newTestServer(t)
+	troll, alice := s.newSession("Rick"), s.newSession("Alice")
+	jan := caller{cookie: s.moderator("jan", moderator.RoleJanitor)}
+	mod := caller{cookie: s.moderator("mod", moderator.RoleModerator)}
+
+	newThread := func(c caller, slug string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.serve("POST", "/api/v1/threads", c, formRequest(t, map[string]string{"title": "hello", "content": "world", "board": slug}), status)
+	}
+	rename := func(c caller, name string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.serve("PUT", "/api/v1/session/name", c, jsonRequest(changeNameRequest{DisplayName: name}), status)
+	}
+	ban := func(rec *httptest.ResponseRecorder) *banNoticeResponse {
+		t.Helper()
+		resp := decode[errorResponse](t, rec)
+		if resp.Ban == nil {
+			t.Fatalf("expected a ban, got %s", rec.Body)
+		}
+		return resp.Ban
+	}
+
+	negative, forever := int64(-1), int64(0)
+	trollAt := caller{sess: troll, addr: "10.0.0.1"}
+
+	// Posting remembers the address, so the ban can follow the troll to a
+	// new session.
+	th := decode[threadResponse](t, newThread(trollAt, "b", 201))
+	s.serve("POST", "/reports", caller{sess: alice, addr: "10.0.0.2"}, jsonRequest(createReportRequest{PostID: th.ID, Reason: "abuse"}), 201)
+	var cases []reportCaseResponse
+	s.get("/mod/reports", jan, &cases)
+	if len(cases) != 1 {
+		t.Fatalf("expected one case, got %+v", cases)
+	}
+	s.serve("POST", "/mod/reports/"+cases[0].ID+"/resolve", mod, jsonRequest(resolveReportRequest{Resolution: "ban", BanDuration: &negative}), 400)
+	s.serve("POST", "/mod/reports/"+cases[0].ID+"/resolve", mod, jsonRequest(resolveReportRequest{Resolution: "ban"}), 200)
+
+	b := ban(newThread(trollAt, "g", 403))
+	if b.Board != nil || b.ExpiresAt == nil || !strings.Contains(b.Reason, "abuse") {
+		t.Errorf("expected an hour-long ban from every board for abuse, got %+v", b)
+	}
+	ban(rename(trollAt, "Morty", 403))
+	ban(rename(caller{sess: s.newSession("Rick"), addr: "10.0.0.1"}, "Morty", 403))
+	rename(caller{sess: s.newSession("Rick"), addr: "10.0.0.3"}, "Summer", 200)
+	page := s.serve("POST", "/create", trollAt, testRequest{body: strings.NewReader("board=b&title=hi&content=again"), contentType: "application/x-www-form-urlencoded"}, 403)
+	if !strings.Contains(page.Body.String(), "You are banned") {
+		t.Errorf("expected the page to explain the ban, got %s", page.Body)
+	}
+
+	var bans []banResponse
+	s.get("/mod/bans", jan, &bans)
+	if len(bans) != 1 || !bans[0].ByAddress || bans[0].SessionID == nil || *bans[0].SessionID != troll.ID.String() {
+		t.Fatalf("expected the troll's ban, got %+v", bans)
+	}
+	s.serve("GET", "/mod/bans", caller{}, testRequest{}, 401)
+	s.serve("DELETE", "/mod/bans/"+bans[0].ID, jan, testRequest{}, 403)
+	s.serve("DELETE", "/mod/bans/"+bans[0].ID, mod, testRequest{}, 204)
+	s.serve("DELETE", "/mod/bans/"+bans[0].ID, mod, testRequest{}, 404)
+	s.serve("DELETE", "/mod/bans/nope", mod, testRequest{}, 400)
+	rename(trollAt, "Jerry", 200)
+
+	// A ban from one board leaves the others open.
+	th = decode[threadResponse](t, newThread(trollAt, "b", 201))
+	create := func(c caller, req createBanRequest, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.serve("POST", "/mod/bans", c, jsonRequest(req), status)
+	}
+	create(jan, createBanRequest{PostID: th.ID, Reason: "spam"}, 403)
+	create(mod, createBanRequest{PostID: th.ID}, 400)
+	create(mod, createBanRequest{PostID: "nope", Reason: "spam"}, 400)
+	create(mod, createBanRequest{PostID: alice.ID.String(), Reason: "spam"}, 404)
+	created := decode[banResponse](t, create(mod, createBanRequest{PostID: th.ID, Reason: "spam", Duration: &forever, BoardOnly: true}, 201))
+	if created.Board == nil || *created.Board != "b" || created.ExpiresAt != nil {
+		t.Errorf("expected a ban from /b/ that never ends, got %+v", created)
+	}
+	b = ban(newThread(trollAt, "b", 403))
+	if b.Board == nil || *b.Board != "b" || b.Reason != "spam" {
+		t.Errorf("expected the /b/ ban, got %+v", b)
+	}
+	ban(s.serve("DELETE", "/api/v1/threads/"+th.ID, trollAt, testRequest{}, 403))
+	newThread(trollAt, "g", 201)
+	rename(trollAt, "Beth", 200)
+}
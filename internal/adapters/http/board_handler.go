@@ -0,0 +1,44 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"net/http"
+)
+
+type BoardHandler struct {
+	boardSvc *services.BoardService
+}
+
+func NewBoardHandler(boardSvc *services.BoardService) *BoardHandler {
+	return &BoardHandler{boardSvc: boardSvc}
+}
+
+// GET /api/v1/boards
+func (h *BoardHandler) ListBoards(w http.ResponseWriter, r *http.Request) {
+	boards, err := h.boardSvc.ListBoards(r.Context())
+	if err != nil {
+		logger.Error("failed to list boards", "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to list boards")
+		return
+	}
+
+	Respond(w, http.StatusOK, toBoardResponses(boards))
+}
+
+// GET /api/v1/b/{slug}
+func (h *BoardHandler) GetBoard(w http.ResponseWriter, r *http.Request) {
+	b, err := h.boardSvc.GetBoardBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		if err == errors.ErrBoardNotFound {
+			RespondError(w, http.StatusNotFound, "board not found")
+			return
+		}
+		logger.Error("failed to get board", "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to get board")
+		return
+	}
+
+	Respond(w, http.StatusOK, toBoardResponse(b))
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"net/http"
+	"strconv"
+)
+
+type CaptchaHandler struct {
+	captchaSvc *services.CaptchaService
+}
+
+func NewCaptchaHandler(captchaSvc *services.CaptchaService) *CaptchaHandler {
+	return &CaptchaHandler{captchaSvc: captchaSvc}
+}
+
+// POST /api/v1/captcha
+func (h *CaptchaHandler) CreateChallenge(w http.ResponseWriter, r *http.Request) {
+	c, err := h.captchaSvc.NewChallenge(r.Context())
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "could not create captcha")
+		return
+	}
+	Respond(w, http.StatusCreated, toCaptchaResponse(c))
+}
+
+// GET /api/v1/captcha/{id}/image
+func (h *CaptchaHandler) Image(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid captcha ID")
+		return
+	}
+
+	img, err := h.captchaSvc.Image(r.Context(), id)
+	if err != nil {
+		if err == errors.ErrCaptchaNotFound {
+			RespondError(w, http.StatusNotFound, "captcha not found")
+			return
+		}
+		logger.Error("failed to serve captcha image", "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not render captcha")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(img)
+}
+
+// respondCaptchaError answers a post without a right CAPTCHA answer, and
+// reports whether err was about the CAPTCHA.
+func respondCaptchaError(w http.ResponseWriter, err error) bool {
+	if err != errors.ErrCaptchaRequired && err != errors.ErrCaptchaInvalid {
+		return false
+	}
+	RespondError(w, http.StatusForbidden, err.Error())
+	return true
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/post"
+	"context"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPosts_Captcha(t *testing.T) {
+	s := newTestServer(t, withCaptcha(services.CaptchaSettings{
+		Actions:    map[post.Action]bool{post.ActionThread: true, post.ActionComment: true},
+		TTL:        time.Minute,
+		TrustAfter: 2,
+	}))
+	sess := s.newSession("Rick")
+	by := caller{sess: sess}
+
+	challenge := func() *captcha.Challenge {
+		t.Helper()
+		resp := decode[captchaResponse](t, s.serve("POST", "/api/v1/captcha", by, testRequest{}, 201))
+		id, err := utils.ParseUUID(resp.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := s.captchas.GetChallenge(context.Background(), id, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	newThread := func(fields map[string]string, status int) threadResponse {
+		t.Helper()
+		fields["title"] = "t"
+		rec := s.serve("POST", "/api/v1/threads", by, formRequest(t, fields), status)
+		if status != 201 {
+			return threadResponse{}
+		}
+		return decode[threadResponse](t, rec)
+	}
+
+	newThread(map[string]string{"content": "no captcha"}, 403)
+
+	c := challenge()
+	img := s.serve("GET", "/api/v1/captcha/"+c.ID.String()+"/image", by, testRequest{}, 200)
+	if _, err := png.Decode(img.Body); err != nil || img.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("expected a PNG image, got %q: %v", img.Header().Get("Content-Type"), err)
+	}
+	s.serve("GET", "/api/v1/captcha/"+newTestSessionID(t).String()+"/image", by, testRequest{}, 404)
+	s.serve("GET", "/api/v1/captcha/nope/image", by, testRequest{}, 400)
+
+	// A wrong answer uses the challenge up, so the right one comes too late.
+	newThread(map[string]string{"content": "wrong", "captcha_id": c.ID.String(), "captcha_answer": "?????"}, 403)
+	newThread(map[string]string{"content": "late", "captcha_id": c.ID.String(), "captcha_answer": c.Answer}, 403)
+
+	c = challenge()
+	th := newThread(map[string]string{"content": "solved", "captcha_id": c.ID.String(), "captcha_answer": " " + strings.ToLower(c.Answer)}, 201)
+	c = challenge()
+	reply := func(fields map[string]string) {
+		t.Helper()
+		s.serve("POST", "/api/v1/threads/"+th.ID+"/comments", by, formRequest(t, fields), 201)
+	}
+	reply(map[string]string{"content": "solved", "captcha_id": c.ID.String(), "captcha_answer": c.Answer})
+
+	// Two solved CAPTCHAs earn the session trust.
+	if stored, _ := s.sessions.GetSessionByID(context.Background(), sess.ID.String()); stored.CaptchasSolved != 2 {
+		t.Errorf("expected 2 solved captchas to be stored, got %d", stored.CaptchasSolved)
+	}
+	reply(map[string]string{"content": "trusted"})
+}
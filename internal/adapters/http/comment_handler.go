@@ -4,15 +4,19 @@ import (
 	"1337b04rd/internal/app/common/logger"
 	"1337b04rd/internal/app/common/utils"
 	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/comment"
 	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 type CommentHandler struct {
 	commentSvc *services.CommentService
+	captchaSvc *services.CaptchaService
 }
 
 func NewCommentHandler(commentSvc *services.CommentService, logger *slog.Logger) *CommentHandler {
@@ -21,41 +25,40 @@ func NewCommentHandler(commentSvc *services.CommentService, logger *slog.Logger)
 	}
 }
 
+// POST /api/v1/threads/{id}/comments
 func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		logger.Warn("invalid method", "method", r.Method, "path", r.URL.Path)
-		Respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
-		return
-	}
-
 	sess, ok := GetSessionFromContext(r.Context())
 	if !ok {
 		logger.Error("session not found in context")
-		Respond(w, http.StatusUnauthorized, map[string]string{"error": "Session not found"})
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 	sessionID := sess.ID
 	displayName := sess.DisplayName
 	avatarURL := sess.AvatarURL
 
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	threadIDStr := r.PathValue("id")
+	threadID, err := utils.ParseUUID(threadIDStr)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
+		return
+	}
+
+	limits := h.commentSvc.Limits()
+	if err := parsePostForm(w, r, limits, 10<<20); err != nil {
+		if respondLimitError(w, err, limits) {
+			return
+		}
 		logger.Error("failed to parse form", "error", err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid form data"})
+		RespondError(w, http.StatusBadRequest, "invalid form data")
 		return
 	}
 
-	threadIDStr := r.FormValue("thread_id")
 	content := strings.TrimSpace(r.FormValue("content"))
 	parentIDStr := r.FormValue("parent_id")
 
 	if content == "" {
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Content is required"})
-		return
-	}
-
-	threadID, err := utils.ParseUUID(threadIDStr)
-	if err != nil {
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid thread_id"})
+		RespondError(w, http.StatusBadRequest, "content is required")
 		return
 	}
 
@@ -63,68 +66,225 @@ func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
 	if parentIDStr != "" {
 		parsedID, err := utils.ParseUUID(parentIDStr)
 		if err != nil {
-			Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid parent_id"})
+			RespondError(w, http.StatusBadRequest, "invalid parent_id")
 			return
 		}
 		parentID = &parsedID
 	}
 
+	if err := h.captchaSvc.Verify(r.Context(), sess, post.ActionComment, r.FormValue("captcha_id"), r.FormValue("captcha_answer")); err != nil {
+		if respondCaptchaError(w, err) {
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "could not check captcha")
+		return
+	}
+
 	files, contentTypes, err := h.commentSvc.PrepareFilesFromMultipart(r.MultipartForm)
 	if err != nil {
+		if respondLimitError(w, err, limits) {
+			return
+		}
 		logger.Error("failed to process uploaded files", "error", err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid image upload"})
+		RespondError(w, http.StatusBadRequest, "invalid image upload")
 		return
 	}
 
-	comment, err := h.commentSvc.CreateComment(r.Context(), threadID, parentID, content, files, contentTypes, sessionID, displayName, avatarURL)
+	comment, err := h.commentSvc.CreateComment(r.Context(), threadID, parentID, content, formSage(r), files, contentTypes, sessionID, displayName, avatarURL, sess.Tripcode, clientIP(r))
 	if err != nil {
+		if respondLimitError(w, err, limits) || respondFloodError(w, err) {
+			return
+		}
 		if err == errors.ErrThreadNotFound {
-			Respond(w, http.StatusNotFound, map[string]string{"error": "Thread not found"})
+			RespondError(w, http.StatusNotFound, "thread not found")
+			return
+		}
+		if err == errors.ErrThreadLocked {
+			RespondError(w, http.StatusForbidden, "thread is locked")
+			return
+		}
+		if err == errors.ErrThreadArchived {
+			RespondError(w, http.StatusForbidden, "thread is archived")
+			return
+		}
+		if err == errors.ErrInvalidParentID || err == errors.ErrInvalidReference || err == errors.ErrTooManyReferences {
+			RespondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		logger.Error("failed to create comment", "error", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create comment"})
+		RespondError(w, http.StatusInternalServerError, "failed to create comment")
 		return
 	}
 
-	Respond(w, http.StatusCreated, comment)
+	Respond(w, http.StatusCreated, toCommentResponse(comment, threadViewer(r, h.commentSvc, threadID)))
 }
 
-func (h *CommentHandler) GetCommentsByThreadID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		logger.Warn("invalid method", "method", r.Method, "path", r.URL.Path)
-		Respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// PATCH /api/v1/comments/{id}
+func (h *CommentHandler) EditComment(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 
-	threadIDStr := r.URL.Query().Get("thread_id")
-	if threadIDStr == "" {
-		logger.Warn("missing thread_id in query")
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Missing thread_id"})
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid comment ID")
+		return
+	}
+
+	var req editCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
 		return
 	}
 
+	comment, err := h.commentSvc.EditComment(r.Context(), id, sess.ID, strings.TrimSpace(req.Content))
+	if err != nil {
+		if respondLimitError(w, err, h.commentSvc.Limits()) {
+			return
+		}
+		respondPostChangeError(w, err, "edit comment")
+		return
+	}
+
+	Respond(w, http.StatusOK, toCommentResponse(comment, threadViewer(r, h.commentSvc, comment.ThreadID)))
+}
+
+// DELETE /api/v1/comments/{id}
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
+		return
+	}
+
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid comment ID")
+		return
+	}
+
+	if err := h.commentSvc.DeleteComment(r.Context(), id, sess.ID); err != nil {
+		respondPostChangeError(w, err, "delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/comments/{id}/revisions
+func (h *CommentHandler) ListCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid comment ID")
+		return
+	}
+
+	revisions, err := h.commentSvc.ListCommentRevisions(r.Context(), id)
+	if err != nil {
+		respondPostChangeError(w, err, "list comment revisions")
+		return
+	}
+
+	Respond(w, http.StatusOK, toRevisionResponses(revisions))
+}
+
+// GET /api/v1/threads/{id}/comments?cursor=&limit=
+func (h *CommentHandler) GetCommentsByThreadID(w http.ResponseWriter, r *http.Request) {
+	threadIDStr := r.PathValue("id")
 	threadID, err := utils.ParseUUID(threadIDStr)
 	if err != nil {
 		logger.Error("invalid thread_id", "error", err, "thread_id", threadIDStr)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid thread ID"})
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
+		return
+	}
+
+	switch r.URL.Query().Get("view") {
+	case "", "flat":
+	case "tree":
+		h.getCommentTree(w, r, threadID)
+		return
+	default:
+		RespondError(w, http.StatusBadRequest, "invalid view")
+		return
+	}
+
+	cursor, limit, err := parsePageParams(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid limit")
 		return
 	}
 
-	comments, err := h.commentSvc.GetCommentsByThreadID(r.Context(), threadID)
+	page, err := h.commentSvc.GetCommentsByThreadIDPage(r.Context(), threadID, cursor, limit)
 	if err != nil {
+		if err == errors.ErrInvalidCursor {
+			RespondError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
 		if err == errors.ErrThreadNotFound {
 			logger.Warn("thread not found", "thread_id", threadID)
-			Respond(w, http.StatusNotFound, map[string]string{"error": "Thread not found"})
+			RespondError(w, http.StatusNotFound, "thread not found")
 			return
 		}
 		logger.Error("failed to get comments", "error", err, "thread_id", threadID)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get comments"})
+		RespondError(w, http.StatusInternalServerError, "failed to get comments")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(comments); err != nil {
-		logger.Error("failed to encode response", "error", err)
+	Respond(w, http.StatusOK, toCommentPageResponse(page, threadViewer(r, h.commentSvc, threadID)))
+}
+
+// getCommentTree serves ?view=tree, with ?root= to fetch the subtree of one
+// comment and ?max_depth= to limit how deep it goes.
+func (h *CommentHandler) getCommentTree(w http.ResponseWriter, r *http.Request, threadID utils.UUID) {
+	q := r.URL.Query()
+
+	var rootID *utils.UUID
+	if raw := q.Get("root"); raw != "" {
+		id, err := utils.ParseUUID(raw)
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "invalid root comment ID")
+			return
+		}
+		rootID = &id
+	}
+
+	maxDepth := comment.DefaultTreeDepth
+	if raw := q.Get("max_depth"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > comment.MaxTreeDepth {
+			RespondError(w, http.StatusBadRequest, "invalid max_depth")
+			return
+		}
+		maxDepth = n
+	}
+
+	nodes, err := h.commentSvc.GetCommentTree(r.Context(), threadID, rootID, maxDepth)
+	if err != nil {
+		switch err {
+		case errors.ErrThreadNotFound:
+			RespondError(w, http.StatusNotFound, "thread not found")
+			return
+		case errors.ErrCommentNotFound:
+			RespondError(w, http.StatusNotFound, "comment not found")
+			return
+		}
+		logger.Error("failed to get comment tree", "error", err, "thread_id", threadID)
+		RespondError(w, http.StatusInternalServerError, "failed to get comments")
+		return
+	}
+
+	Respond(w, http.StatusOK, commentTreeResponse{Items: toCommentNodeResponses(nodes, threadViewer(r, h.commentSvc, threadID)), MaxDepth: maxDepth})
+}
+
+// formSage reports whether the "sage" form field is set. Checkboxes send
+// "on"; API clients may send any boolean strconv accepts.
+func formSage(r *http.Request) bool {
+	v := strings.TrimSpace(r.FormValue("sage"))
+	if v == "on" {
+		return true
 	}
+	sage, _ := strconv.ParseBool(v)
+	return sage
 }
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/errors"
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestComments_LinkReferences(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	th := s.createThread("b", "title", sess.ID)
+	other := s.createThread("b", "other", sess.ID)
+	first := s.reply(th.ID, nil, "first", sess.ID)
+	second := s.reply(th.ID, nil, ">>"+first.ID.String()+" agreed, >>"+th.ID.String()+" >>"+first.ID.String(), sess.ID)
+	if _, err := s.commentSvc.CreateComment(context.Background(), th.ID, nil, ">>"+other.ID.String(), false, nil, nil, sess.ID, "Rick", "http://example.com/rick.png", "", ""); err != errors.ErrInvalidReference {
+		t.Errorf("expected a link to another thread to be rejected, got %v", err)
+	}
+
+	var page commentPageResponse
+	s.get("/api/v1/threads/"+th.ID.String()+"/comments", caller{}, &page)
+	if len(page.Items) != 2 {
+		t.Fatalf("expected 2 comments, got %+v", page.Items)
+	}
+	if got := page.Items[0].RepliedBy; len(got) != 1 || got[0] != second.ID.String() {
+		t.Errorf("expected the first comment to be replied by the second, got %v", got)
+	}
+	if got := page.Items[1].References; len(got) != 2 || got[0] != first.ID.String() || got[1] != th.ID.String() {
+		t.Errorf("expected the second comment to link the first and the thread once each, got %v", got)
+	}
+
+	var thread threadResponse
+	s.get("/api/v1/threads/"+th.ID.String(), caller{}, &thread)
+	if len(thread.RepliedBy) != 1 || thread.RepliedBy[0] != second.ID.String() {
+		t.Errorf("expected the thread to be replied by the second comment, got %v", thread.RepliedBy)
+	}
+
+	// Over HTTP a link out of the thread, by ID or by number, is a bad request.
+	target := "/api/v1/threads/" + th.ID.String() + "/comments"
+	by := caller{sess: sess}
+	s.serve("POST", target, by, formRequest(t, map[string]string{"content": ">>" + other.ID.String()}), 400)
+	s.serve("POST", target, by, formRequest(t, map[string]string{"content": ">>" + strconv.FormatInt(other.PostNumber, 10)}), 400)
+	s.serve("POST", target, by, formRequest(t, map[string]string{"content": ">>" + strconv.FormatInt(first.PostNumber, 10) + " yes"}), 201)
+}
+
+func TestComments_TreeView(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	th := s.createThread("b", "title", sessionID)
+	foreign := s.createThread("b", "foreign", sessionID)
+	reply := func(parent *utils.UUID, content string) utils.UUID {
+		return s.reply(th.ID, parent, content, sessionID).ID
+	}
+	a := reply(nil, "a")
+	b := reply(&a, "b")
+	reply(&b, "c")
+	reply(nil, "d")
+
+	get := func(query string) commentTreeResponse {
+		t.Helper()
+		var tree commentTreeResponse
+		s.get("/api/v1/threads/"+th.ID.String()+"/comments?view=tree&"+query, caller{}, &tree)
+		return tree
+	}
+
+	tree := get("max_depth=1")
+	if len(tree.Items) != 2 || tree.Items[0].Comment.Content != "a" || tree.Items[1].Comment.Content != "d" {
+		t.Fatalf("expected roots a and d in order, got %+v", tree.Items)
+	}
+	children := tree.Items[0].Children
+	if len(children) != 1 || children[0].Comment.Content != "b" || children[0].Depth != 1 {
+		t.Fatalf("expected b under a at depth 1, got %+v", children)
+	}
+	if !children[0].Collapsed || children[0].ChildCount != 1 || len(children[0].Children) != 0 {
+		t.Errorf("expected b collapsed with its reply left out, got %+v", children[0])
+	}
+
+	sub := get("root=" + b.String())
+	if len(sub.Items) != 1 || sub.Items[0].Comment.Content != "b" || sub.Items[0].Depth != 0 {
+		t.Fatalf("expected the subtree rooted at b, got %+v", sub.Items)
+	}
+	if len(sub.Items[0].Children) != 1 || sub.Items[0].Children[0].Comment.Content != "c" {
+		t.Errorf("expected c under b, got %+v", sub.Items[0].Children)
+	}
+
+	s.serve("GET", "/api/v1/threads/"+foreign.ID.String()+"/comments?view=tree&root="+b.String(), caller{}, testRequest{}, 404)
+	s.serve("GET", "/api/v1/threads/"+th.ID.String()+"/comments?view=tree&max_depth=99", caller{}, testRequest{}, 400)
+}
+
+func TestComments_SageDoesNotBump(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	th := s.createThread("b", "title", sess.ID)
+	bumped := th.BumpedAt
+
+	created := decode[commentResponse](t, s.serve("POST", "/api/v1/threads/"+th.ID.String()+"/comments", caller{sess: sess}, formRequest(t, map[string]string{"content": "reply", "sage": "on"}), 201))
+	if !created.IsSage {
+		t.Errorf("expected a sage reply, got %+v", created)
+	}
+	if stored, _ := s.threads.GetThreadByID(context.Background(), th.ID); !stored.BumpedAt.Equal(bumped) {
+		t.Errorf("expected a sage reply to leave the bump time, got %v", stored.BumpedAt)
+	}
+}
+
+func TestComments_ParentMustBeACommentOfTheThread(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	th := s.createThread("b", "title", sess.ID)
+	other := s.createThread("b", "other", sess.ID)
+	elsewhere := s.reply(other.ID, nil, "elsewhere", sess.ID)
+	parent := s.reply(th.ID, nil, "parent", sess.ID)
+
+	target := "/api/v1/threads/" + th.ID.String() + "/comments"
+	by := caller{sess: sess}
+	for _, id := range []string{th.ID.String(), elsewhere.ID.String(), newTestSessionID(t).String(), "nope"} {
+		s.serve("POST", target, by, formRequest(t, map[string]string{"content": "reply", "parent_id": id}), 400)
+	}
+	created := decode[commentResponse](t, s.serve("POST", target, by, formRequest(t, map[string]string{"content": "reply", "parent_id": parent.ID.String()}), 201))
+	if created.ParentCommentID == nil || *created.ParentCommentID != parent.ID.String() {
+		t.Errorf("expected the reply under its parent, got %+v", created)
+	}
+}
@@ -0,0 +1,628 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/markup"
+	"1337b04rd/internal/domain/moderator"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/report"
+	"1337b04rd/internal/domain/revision"
+	"1337b04rd/internal/domain/search"
+	"1337b04rd/internal/domain/session"
+	"1337b04rd/internal/domain/thread"
+	"time"
+)
+
+// Response DTOs for /api/v1. They are the public contract described in
+// openapi.json and must not change when a domain field is renamed.
+
+type errorResponse struct {
+	Error string `json:"error"`
+	// Details name the fields a post was rejected for.
+	Details []fieldError `json:"details,omitempty"`
+	// Ban explains the refusal of a banned poster.
+	Ban *banNoticeResponse `json:"ban,omitempty"`
+}
+
+type boardResponse struct {
+	ID          string `json:"id"`
+	Slug        string `json:"slug"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	MaxThreads  int    `json:"max_threads"`
+	MaxImages   int    `json:"max_images"`
+	BumpLimit   int    `json:"bump_limit"`
+	PosterIDs   bool   `json:"poster_ids"`
+}
+
+type threadResponse struct {
+	ID            string     `json:"id"`
+	PostNumber    int64      `json:"post_number"`
+	BoardID       string     `json:"board_id"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	ContentHTML   string     `json:"content_html"`
+	ImageURLs     []string   `json:"image_urls"`
+	IsOwn         bool       `json:"is_own"`
+	Tripcode      *string    `json:"tripcode"`
+	PosterID      *string    `json:"poster_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastCommented *time.Time `json:"last_commented"`
+	EditedAt      *time.Time `json:"edited_at"`
+	BumpedAt      time.Time  `json:"bumped_at"`
+	BumpCount     int        `json:"bump_count"`
+	ReplyCount    int        `json:"reply_count"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	State         string     `json:"state"`
+	IsArchived    bool       `json:"is_archived"`
+	IsPinned      bool       `json:"is_pinned"`
+	IsLocked      bool       `json:"is_locked"`
+	RepliedBy     []string   `json:"replied_by"`
+}
+
+type commentResponse struct {
+	ID              string     `json:"id"`
+	PostNumber      int64      `json:"post_number"`
+	ThreadID        string     `json:"thread_id"`
+	ParentCommentID *string    `json:"parent_comment_id"`
+	Content         string     `json:"content"`
+	ContentHTML     string     `json:"content_html"`
+	ImageURLs       []string   `json:"image_urls"`
+	IsOwn           bool       `json:"is_own"`
+	RepliesToYou    bool       `json:"replies_to_you"`
+	DisplayName     string     `json:"display_name"`
+	Tripcode        *string    `json:"tripcode"`
+	PosterID        *string    `json:"poster_id"`
+	AvatarURL       string     `json:"avatar_url"`
+	CreatedAt       time.Time  `json:"created_at"`
+	EditedAt        *time.Time `json:"edited_at"`
+	IsSage          bool       `json:"is_sage"`
+	IsDeleted       bool       `json:"is_deleted"`
+	References      []string   `json:"references"`
+	RepliedBy       []string   `json:"replied_by"`
+}
+
+type commentNodeResponse struct {
+	Comment    commentResponse       `json:"comment"`
+	Depth      int                   `json:"depth"`
+	ChildCount int                   `json:"child_count"`
+	Collapsed  bool                  `json:"collapsed"`
+	Children   []commentNodeResponse `json:"children"`
+}
+
+type commentTreeResponse struct {
+	Items    []commentNodeResponse `json:"items"`
+	MaxDepth int                   `json:"max_depth"`
+}
+
+type threadPageResponse struct {
+	Items      []threadResponse `json:"items"`
+	NextCursor *string          `json:"next_cursor"`
+}
+
+type commentPageResponse struct {
+	Items      []commentResponse `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
+type searchResultResponse struct {
+	Kind        string    `json:"kind"`
+	ThreadID    string    `json:"thread_id"`
+	CommentID   *string   `json:"comment_id"`
+	ThreadTitle string    `json:"thread_title"`
+	Snippet     string    `json:"snippet"`
+	Rank        float64   `json:"rank"`
+	CreatedAt   time.Time `json:"created_at"`
+	IsArchived  bool      `json:"is_archived"`
+}
+
+type searchPageResponse struct {
+	Items      []searchResultResponse `json:"items"`
+	NextCursor *string                `json:"next_cursor"`
+}
+
+// postResponse tells where a post number leads; comment_id is null for an
+// opening post.
+type postResponse struct {
+	PostNumber int64   `json:"post_number"`
+	Kind       string  `json:"kind"`
+	ThreadID   string  `json:"thread_id"`
+	CommentID  *string `json:"comment_id"`
+}
+
+type sessionResponse struct {
+	ID          string    `json:"id"`
+	DisplayName string    `json:"display_name"`
+	Tripcode    *string   `json:"tripcode"`
+	AvatarURL   string    `json:"avatar_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// publicSessionResponse is a session as others see it. The ID is the
+// session cookie, so only its owner gets it.
+type publicSessionResponse struct {
+	DisplayName string    `json:"display_name"`
+	Tripcode    *string   `json:"tripcode"`
+	AvatarURL   string    `json:"avatar_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// moderatorResponse never carries the password hash.
+type moderatorResponse struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// reportResponse leaves out the reporting session.
+type reportResponse struct {
+	ID        string    `json:"id"`
+	PostID    string    `json:"post_id"`
+	Reason    string    `json:"reason"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// reportCaseResponse is one post in the moderation queue; resolution is
+// null while the case is open.
+type reportCaseResponse struct {
+	ID             string         `json:"id"`
+	Post           postResponse   `json:"post"`
+	Score          int            `json:"score"`
+	ReportCount    int            `json:"report_count"`
+	Reasons        map[string]int `json:"reasons"`
+	OpenedAt       time.Time      `json:"opened_at"`
+	LastReportedAt time.Time      `json:"last_reported_at"`
+	ClaimedBy      *string        `json:"claimed_by"`
+	ClaimedAt      *time.Time     `json:"claimed_at"`
+	Resolution     *string        `json:"resolution"`
+	ResolvedBy     *string        `json:"resolved_by"`
+	ResolvedAt     *time.Time     `json:"resolved_at"`
+}
+
+type reportCaseDetailResponse struct {
+	Case    reportCaseResponse `json:"case"`
+	Reports []reportResponse   `json:"reports"`
+}
+
+// banResponse leaves out the address hash; by_address tells whether there
+// is one.
+type banResponse struct {
+	ID        string     `json:"id"`
+	SessionID *string    `json:"session_id"`
+	ByAddress bool       `json:"by_address"`
+	Board     *string    `json:"board"`
+	Reason    string     `json:"reason"`
+	CreatedBy *string    `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// banNoticeResponse tells a banned poster why and until when; board is
+// null for a ban from every board, expires_at for one that never ends.
+type banNoticeResponse struct {
+	Reason    string     `json:"reason"`
+	Board     *string    `json:"board"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// captchaResponse is a challenge to show; its answer stays on the server.
+type captchaResponse struct {
+	ID        string    `json:"id"`
+	ImageURL  string    `json:"image_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// powChallengeResponse is a challenge to solve before the next post.
+type powChallengeResponse struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// editThreadRequest leaves out the fields that stay unchanged.
+type editThreadRequest struct {
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
+}
+
+type editCommentRequest struct {
+	Content string `json:"content"`
+}
+
+type revisionResponse struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	PostID    string    `json:"post_id"`
+	Title     *string   `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type previewRequest struct {
+	Content  string  `json:"content"`
+	ThreadID *string `json:"thread_id"`
+}
+
+type previewResponse struct {
+	ContentHTML string `json:"content_html"`
+}
+
+type changeNameRequest struct {
+	DisplayName string `json:"display_name"`
+}
+
+type changeNameResponse struct {
+	Success bool `json:"success"`
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type createModeratorRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type createReportRequest struct {
+	PostID string `json:"post_id"`
+	Reason string `json:"reason"`
+	Text   string `json:"text"`
+}
+
+// resolveReportRequest only needs the ban fields for a ban. BanDuration is
+// in seconds: 0 bans for good, null for the default time.
+type resolveReportRequest struct {
+	Resolution  string `json:"resolution"`
+	BanReason   string `json:"ban_reason"`
+	BanDuration *int64 `json:"ban_duration"`
+	BoardOnly   bool   `json:"board_only"`
+}
+
+// createBanRequest bans the author of a post; Duration works like
+// BanDuration above.
+type createBanRequest struct {
+	PostID    string `json:"post_id"`
+	Reason    string `json:"reason"`
+	Duration  *int64 `json:"duration"`
+	BoardOnly bool   `json:"board_only"`
+}
+
+func toBoardResponse(b *board.Board) boardResponse {
+	return boardResponse{
+		ID:          b.ID.String(),
+		Slug:        b.Slug,
+		Title:       b.Title,
+		Description: b.Description,
+		MaxThreads:  b.MaxThreads,
+		MaxImages:   b.MaxImages,
+		BumpLimit:   b.BumpLimit,
+		PosterIDs:   b.PosterIDs,
+	}
+}
+
+func toBoardResponses(boards []*board.Board) []boardResponse {
+	result := make([]boardResponse, 0, len(boards))
+	for _, b := range boards {
+		result = append(result, toBoardResponse(b))
+	}
+	return result
+}
+
+func toThreadResponse(t *thread.Thread, v viewer) threadResponse {
+	return threadResponse{
+		ID:            t.ID.String(),
+		PostNumber:    t.PostNumber,
+		BoardID:       t.BoardID.String(),
+		Title:         t.Title,
+		Content:       t.Content,
+		ContentHTML:   markup.Render(t.Content, t.ID),
+		ImageURLs:     nonNilStrings(t.ImageURLs),
+		IsOwn:         v.owns(t.SessionID),
+		Tripcode:      optionalString(t.Tripcode),
+		PosterID:      optionalString(t.PosterID),
+		CreatedAt:     t.CreatedAt,
+		LastCommented: t.LastCommented,
+		EditedAt:      t.EditedAt,
+		BumpedAt:      t.BumpedAt,
+		BumpCount:     t.BumpCount,
+		ReplyCount:    t.ReplyCount,
+		ExpiresAt:     t.ExpiresAt,
+		State:         string(t.State),
+		IsArchived:    t.IsArchived(),
+		IsPinned:      t.IsPinned,
+		IsLocked:      t.IsLocked(),
+		RepliedBy:     uuidStrings(t.RepliedBy),
+	}
+}
+
+func toThreadResponses(threads []*thread.Thread, v viewer) []threadResponse {
+	result := make([]threadResponse, 0, len(threads))
+	for _, t := range threads {
+		result = append(result, toThreadResponse(t, v))
+	}
+	return result
+}
+
+func toThreadPageResponse(page *pagination.Page[*thread.Thread], v viewer) threadPageResponse {
+	return threadPageResponse{
+		Items:      toThreadResponses(page.Items, v),
+		NextCursor: nextCursorPtr(page.NextCursor),
+	}
+}
+
+func toCommentResponse(c *comment.Comment, v viewer) commentResponse {
+	resp := commentResponse{
+		ID:           c.ID.String(),
+		PostNumber:   c.PostNumber,
+		ThreadID:     c.ThreadID.String(),
+		Content:      c.Content,
+		ContentHTML:  markup.Render(c.Content, c.ThreadID),
+		ImageURLs:    nonNilStrings(c.ImageURLs),
+		IsOwn:        v.owns(c.SessionID),
+		RepliesToYou: v.repliesTo(c),
+		DisplayName:  c.DisplayName,
+		Tripcode:     optionalString(c.Tripcode),
+		PosterID:     optionalString(c.PosterID),
+		AvatarURL:    c.AvatarURL,
+		CreatedAt:    c.CreatedAt,
+		EditedAt:     c.EditedAt,
+		IsSage:       c.IsSage,
+		IsDeleted:    c.IsDeleted,
+		References:   uuidStrings(c.References),
+		RepliedBy:    uuidStrings(c.RepliedBy),
+	}
+	if c.ParentCommentID != nil {
+		parentID := c.ParentCommentID.String()
+		resp.ParentCommentID = &parentID
+	}
+	if c.IsDeleted {
+		// Tombstone: the comment keeps its place in reply chains but shows
+		// nothing of what was posted.
+		resp.Content = ""
+		resp.ContentHTML = ""
+		resp.ImageURLs = []string{}
+		resp.DisplayName = ""
+		resp.Tripcode = nil
+		resp.PosterID = nil
+		resp.AvatarURL = ""
+	}
+	return resp
+}
+
+func toPostResponse(l *post.Locator) postResponse {
+	resp := postResponse{
+		PostNumber: l.Number,
+		Kind:       "thread",
+		ThreadID:   l.ThreadID.String(),
+	}
+	if l.CommentID != nil {
+		commentID := l.CommentID.String()
+		resp.Kind = "comment"
+		resp.CommentID = &commentID
+	}
+	return resp
+}
+
+func toRevisionResponses(revisions []*revision.Revision) []revisionResponse {
+	result := make([]revisionResponse, 0, len(revisions))
+	for _, r := range revisions {
+		resp := revisionResponse{
+			ID:        r.ID.String(),
+			Kind:      string(r.Kind),
+			PostID:    r.PostID.String(),
+			Content:   r.Content,
+			CreatedAt: r.CreatedAt,
+		}
+		if r.Kind == revision.KindThread {
+			title := r.Title
+			resp.Title = &title
+		}
+		result = append(result, resp)
+	}
+	return result
+}
+
+func toCommentResponses(comments []*comment.Comment, v viewer) []commentResponse {
+	result := make([]commentResponse, 0, len(comments))
+	for _, c := range comments {
+		result = append(result, toCommentResponse(c, v))
+	}
+	return result
+}
+
+func toCommentNodeResponses(nodes []*comment.Node, v viewer) []commentNodeResponse {
+	result := make([]commentNodeResponse, 0, len(nodes))
+	for _, n := range nodes {
+		result = append(result, commentNodeResponse{
+			Comment:    toCommentResponse(n.Comment, v),
+			Depth:      n.Depth,
+			ChildCount: n.ChildCount,
+			Collapsed:  n.Collapsed(),
+			Children:   toCommentNodeResponses(n.Children, v),
+		})
+	}
+	return result
+}
+
+func toCommentPageResponse(page *pagination.Page[*comment.Comment], v viewer) commentPageResponse {
+	return commentPageResponse{
+		Items:      toCommentResponses(page.Items, v),
+		NextCursor: nextCursorPtr(page.NextCursor),
+	}
+}
+
+func toSearchResultResponse(res *search.Result) searchResultResponse {
+	resp := searchResultResponse{
+		Kind:        string(res.Kind),
+		ThreadID:    res.ThreadID.String(),
+		ThreadTitle: res.ThreadTitle,
+		Snippet:     string(snippetHTML(res.Snippet)),
+		Rank:        res.Rank,
+		CreatedAt:   res.CreatedAt,
+		IsArchived:  res.IsArchived,
+	}
+	if res.CommentID != nil {
+		commentID := res.CommentID.String()
+		resp.CommentID = &commentID
+	}
+	return resp
+}
+
+func toSearchPageResponse(page *pagination.Page[*search.Result]) searchPageResponse {
+	items := make([]searchResultResponse, 0, len(page.Items))
+	for _, res := range page.Items {
+		items = append(items, toSearchResultResponse(res))
+	}
+	return searchPageResponse{
+		Items:      items,
+		NextCursor: nextCursorPtr(page.NextCursor),
+	}
+}
+
+func toSessionResponse(s *session.Session) sessionResponse {
+	return sessionResponse{
+		ID:          s.ID.String(),
+		DisplayName: s.DisplayName,
+		Tripcode:    optionalString(s.Tripcode),
+		AvatarURL:   s.AvatarURL,
+		ExpiresAt:   s.ExpiresAt,
+	}
+}
+
+func toPublicSessionResponse(s *session.Session) publicSessionResponse {
+	return publicSessionResponse{
+		DisplayName: s.DisplayName,
+		Tripcode:    optionalString(s.Tripcode),
+		AvatarURL:   s.AvatarURL,
+		ExpiresAt:   s.ExpiresAt,
+	}
+}
+
+func toCaptchaResponse(c *captcha.Challenge) captchaResponse {
+	return captchaResponse{
+		ID:        c.ID.String(),
+		ImageURL:  captchaImageURL(c),
+		ExpiresAt: c.ExpiresAt,
+	}
+}
+
+func captchaImageURL(c *captcha.Challenge) string {
+	return "/api/v1/captcha/" + c.ID.String() + "/image"
+}
+
+func uuidStrings(ids []utils.UUID) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, id.String())
+	}
+	return result
+}
+
+// optionalString maps an empty string to null.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func toModeratorResponse(m *moderator.Moderator) moderatorResponse {
+	return moderatorResponse{
+		ID:        m.ID.String(),
+		Username:  m.Username,
+		Role:      string(m.Role),
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func toReportResponse(r *report.Report) reportResponse {
+	return reportResponse{
+		ID:        r.ID.String(),
+		PostID:    r.PostID.String(),
+		Reason:    string(r.Reason),
+		Text:      r.Text,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func toReportCaseResponse(c *report.Case) reportCaseResponse {
+	resp := reportCaseResponse{
+		ID:             c.ID.String(),
+		Post:           toPostResponse(&c.Post),
+		Score:          c.Score,
+		ReportCount:    c.ReportCount,
+		Reasons:        make(map[string]int, len(c.Reasons)),
+		OpenedAt:       c.OpenedAt,
+		LastReportedAt: c.LastReportedAt,
+		ClaimedAt:      c.ClaimedAt,
+		ResolvedAt:     c.ResolvedAt,
+	}
+	for reason, n := range c.Reasons {
+		resp.Reasons[string(reason)] = n
+	}
+	if c.ClaimedBy != nil {
+		claimedBy := c.ClaimedBy.String()
+		resp.ClaimedBy = &claimedBy
+	}
+	if !c.IsOpen() {
+		resolution := string(c.Resolution)
+		resp.Resolution = &resolution
+	}
+	if c.ResolvedBy != nil {
+		resolvedBy := c.ResolvedBy.String()
+		resp.ResolvedBy = &resolvedBy
+	}
+	return resp
+}
+
+func toBanResponse(b *ban.Ban) banResponse {
+	resp := banResponse{
+		ID:        b.ID.String(),
+		ByAddress: b.IPHash != "",
+		Reason:    b.Reason,
+		CreatedAt: b.CreatedAt,
+		ExpiresAt: b.ExpiresAt,
+	}
+	if b.SessionID != nil {
+		sessionID := b.SessionID.String()
+		resp.SessionID = &sessionID
+	}
+	if b.BoardID != nil {
+		resp.Board = &b.BoardSlug
+	}
+	if b.CreatedBy != nil {
+		createdBy := b.CreatedBy.String()
+		resp.CreatedBy = &createdBy
+	}
+	return resp
+}
+
+func toBanNoticeResponse(b *ban.Ban) *banNoticeResponse {
+	resp := &banNoticeResponse{
+		Reason:    b.Reason,
+		CreatedAt: b.CreatedAt,
+		ExpiresAt: b.ExpiresAt,
+	}
+	if b.BoardID != nil {
+		resp.Board = &b.BoardSlug
+	}
+	return resp
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/event"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const sseHeartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	commentSvc *services.CommentService
+}
+
+func NewEventsHandler(commentSvc *services.CommentService) *EventsHandler {
+	return &EventsHandler{commentSvc: commentSvc}
+}
+
+type threadExpiredResponse struct {
+	ThreadID  string    `json:"thread_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// GET /api/v1/threads/{id}/events
+func (h *EventsHandler) StreamThreadEvents(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	threadID, err := utils.ParseUUID(idStr)
+	if err != nil {
+		logger.Error("invalid thread id", "error", err, "id", idStr)
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
+		return
+	}
+
+	stream, err := h.commentSvc.SubscribeThread(r.Context(), threadID, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		if err == errors.ErrThreadNotFound {
+			RespondError(w, http.StatusNotFound, "thread not found")
+			return
+		}
+		logger.Error("failed to subscribe to thread", "error", err, "thread_id", threadID)
+		RespondError(w, http.StatusInternalServerError, "failed to subscribe to thread")
+		return
+	}
+	defer stream.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	v := threadViewer(r, h.commentSvc, threadID)
+	replayed := make(map[utils.UUID]bool, len(stream.Missed))
+	for _, c := range stream.Missed {
+		replayed[c.ID] = true
+		if err := writeCommentEvent(w, c.ID, services.CommentEventID(c), toCommentResponse(c, v)); err != nil {
+			return
+		}
+	}
+
+	if stream.Thread.IsArchived() {
+		// Archive always stamps the time; now only stands in for rows that
+		// somehow lack it.
+		expiredAt := time.Now()
+		if stream.Thread.ArchivedAt != nil {
+			expiredAt = *stream.Thread.ArchivedAt
+		}
+		writeSSE(w, "", string(event.ThreadExpired), threadExpiredResponse{ThreadID: threadID.String(), ExpiredAt: expiredAt})
+		rc.Flush()
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		logger.Error("streaming not supported", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+
+		case e, ok := <-stream.Events:
+			if !ok {
+				return
+			}
+
+			switch e.Type {
+			case event.CommentCreated:
+				if replayed[e.Comment.ID] {
+					continue
+				}
+				v.add(e.Comment)
+				if err := writeCommentEvent(w, e.Comment.ID, services.CommentEventID(e.Comment), toCommentResponse(e.Comment, v)); err != nil {
+					return
+				}
+
+			case event.ThreadExpired:
+				writeSSE(w, "", string(e.Type), threadExpiredResponse{ThreadID: e.ThreadID.String(), ExpiredAt: e.At})
+				rc.Flush()
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeCommentEvent(w http.ResponseWriter, commentID utils.UUID, eventID string, payload commentResponse) error {
+	err := writeSSE(w, eventID, string(event.CommentCreated), payload)
+	if err != nil {
+		logger.Warn("failed to write comment event", "comment_id", commentID, "error", err)
+	}
+	return err
+}
+
+// writeSSE writes one Server-Sent Event. Events without an id leave the
+// client's Last-Event-ID untouched.
+func writeSSE(w http.ResponseWriter, id, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, data)
+	return err
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	id, typ, data string
+}
+
+// readSSE reads one event from the stream, skipping heartbeats.
+func readSSE(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if ev.typ != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamThreadEvents(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.mux)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	sessionID := newTestSessionID(t)
+	th := s.createThread("b", "title", sessionID)
+	url := srv.URL + "/api/v1/threads/" + th.ID.String() + "/events"
+
+	connect := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		req, _ := http.NewRequest("GET", url, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("unexpected content type %q", ct)
+		}
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	post := func(content string) {
+		s.reply(th.ID, nil, content, sessionID)
+	}
+
+	// Live delivery.
+	resp, stream := connect("")
+	post("first")
+	first := readSSE(t, stream)
+	if first.typ != "comment" || !strings.Contains(first.data, `"content":"first"`) || first.id == "" {
+		t.Fatalf("unexpected first event: %+v", first)
+	}
+	resp.Body.Close()
+
+	// A comment posted while disconnected is replayed from Last-Event-ID.
+	time.Sleep(time.Millisecond)
+	post("second")
+	resp, stream = connect(first.id)
+	missed := readSSE(t, stream)
+	if missed.typ != "comment" || !strings.Contains(missed.data, `"content":"second"`) {
+		t.Fatalf("expected replay of the missed comment, got %+v", missed)
+	}
+
+	// Lifecycle event ends the stream.
+	th.CreatedAt = time.Now().Add(-time.Hour)
+	th.LastCommented = &th.CreatedAt
+	if err := s.threadSvc.CleanupExpiredThreads(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expired := readSSE(t, stream)
+	if expired.typ != "thread_expired" {
+		t.Fatalf("expected thread_expired, got %+v", expired)
+	}
+	resp.Body.Close()
+}
+
+func TestStreamThreadEvents_AlreadyArchived(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s.mux)
+	t.Cleanup(srv.Close)
+
+	th := s.createThread("b", "title", newTestSessionID(t))
+	archivedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := th.Archive(archivedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/threads/" + th.ID.String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	expired := readSSE(t, bufio.NewReader(resp.Body))
+	if expired.typ != "thread_expired" || !strings.Contains(expired.data, `"expired_at":"2024-03-01T12:00:00Z"`) {
+		t.Fatalf("expected thread_expired at the stored archive time, got %+v", expired)
+	}
+}
+
+func TestStreamThreadEvents_UnknownThread(t *testing.T) {
+	s := newTestServer(t)
+	s.serve("GET", "/api/v1/threads/123e4567-e89b-12d3-a456-426614174000/events", caller{}, testRequest{}, http.StatusNotFound)
+}
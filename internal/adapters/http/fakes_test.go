@@ -0,0 +1,809 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/report"
+	"1337b04rd/internal/domain/revision"
+	"1337b04rd/internal/domain/search"
+	"1337b04rd/internal/domain/session"
+	"1337b04rd/internal/domain/thread"
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// In-memory ports used to drive the real services from handler tests.
+
+type fakeThreadRepo struct {
+	mu      sync.Mutex
+	threads map[utils.UUID]*thread.Thread
+	// posts numbers threads and, through fakeCommentRepo.threads, comments
+	// per board like the assign_post_number trigger.
+	posts map[utils.UUID][]*post.Locator
+}
+
+func newFakeThreadRepo() *fakeThreadRepo {
+	return &fakeThreadRepo{
+		threads: make(map[utils.UUID]*thread.Thread),
+		posts:   make(map[utils.UUID][]*post.Locator),
+	}
+}
+
+func (r *fakeThreadRepo) CreateThread(ctx context.Context, t *thread.Thread) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t.PostNumber = r.nextPost(t.BoardID, t.ID, nil)
+	r.threads[t.ID] = t
+	return nil
+}
+
+// numberComment gives a comment the next number of its thread's board.
+func (r *fakeThreadRepo) numberComment(c *comment.Comment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.threads[c.ThreadID]; ok {
+		id := c.ID
+		c.PostNumber = r.nextPost(t.BoardID, t.ID, &id)
+	}
+}
+
+func (r *fakeThreadRepo) nextPost(boardID, threadID utils.UUID, commentID *utils.UUID) int64 {
+	n := int64(len(r.posts[boardID]) + 1)
+	r.posts[boardID] = append(r.posts[boardID], &post.Locator{Number: n, ThreadID: threadID, CommentID: commentID})
+	return n
+}
+
+func (r *fakeThreadRepo) GetPostByNumber(ctx context.Context, boardID utils.UUID, number int64) (*post.Locator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	posts := r.posts[boardID]
+	if number < 1 || number > int64(len(posts)) {
+		return nil, errors.ErrPostNotFound
+	}
+	if _, ok := r.threads[posts[number-1].ThreadID]; !ok {
+		return nil, errors.ErrPostNotFound
+	}
+	return posts[number-1], nil
+}
+
+func (r *fakeThreadRepo) GetThreadByID(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.threads[id]
+	if !ok {
+		return nil, errors.ErrThreadNotFound
+	}
+	return t, nil
+}
+
+func (r *fakeThreadRepo) UpdateThread(ctx context.Context, t *thread.Thread) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.threads[t.ID] = t
+	return nil
+}
+
+func (r *fakeThreadRepo) ListActiveThreads(ctx context.Context) ([]*thread.Thread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*thread.Thread
+	for _, t := range r.threads {
+		if !t.IsArchived() {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeThreadRepo) ListAllThreads(ctx context.Context) ([]*thread.Thread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*thread.Thread
+	for _, t := range r.threads {
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (r *fakeThreadRepo) ListArchivedThreadsBefore(ctx context.Context, before time.Time) ([]*thread.Thread, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*thread.Thread
+	for _, t := range r.threads {
+		if t.State == thread.StateArchived && t.ArchivedAt != nil && t.ArchivedAt.Before(before) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeThreadRepo) DeleteThread(ctx context.Context, id utils.UUID) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.threads, id)
+	return nil, nil
+}
+
+func (r *fakeThreadRepo) ListPinnedThreads(ctx context.Context, boardID *utils.UUID) ([]*thread.Thread, error) {
+	threads, _ := r.ListActiveThreads(ctx)
+	var pinned []*thread.Thread
+	for _, t := range threads {
+		if t.IsPinned {
+			pinned = append(pinned, t)
+		}
+	}
+	return threadsPage(pinned, bumpedAt, boardID, nil, len(pinned)), nil
+}
+
+func (r *fakeThreadRepo) ListActiveThreadsPage(ctx context.Context, boardID *utils.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	threads, _ := r.ListActiveThreads(ctx)
+	var unpinned []*thread.Thread
+	for _, t := range threads {
+		if !t.IsPinned {
+			unpinned = append(unpinned, t)
+		}
+	}
+	return threadsPage(unpinned, bumpedAt, boardID, after, limit), nil
+}
+
+func (r *fakeThreadRepo) ListAllThreadsPage(ctx context.Context, boardID *utils.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	threads, _ := r.ListAllThreads(ctx)
+	return threadsPage(threads, createdAt, boardID, after, limit), nil
+}
+
+func bumpedAt(t *thread.Thread) time.Time  { return t.BumpedAt }
+func createdAt(t *thread.Thread) time.Time { return t.CreatedAt }
+
+// threadsPage mirrors the keyset queries: latest key first, strictly after
+// the cursor.
+func threadsPage(threads []*thread.Thread, key func(*thread.Thread) time.Time, boardID *utils.UUID, after *pagination.Cursor, limit int) []*thread.Thread {
+	sort.Slice(threads, func(i, j int) bool {
+		return keyLess(key(threads[j]), threads[j].ID, key(threads[i]), threads[i].ID)
+	})
+	var result []*thread.Thread
+	for _, t := range threads {
+		if boardID != nil && t.BoardID != *boardID {
+			continue
+		}
+		if after != nil && !keyLess(key(t), t.ID, after.Time, after.ID) {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+func keyLess(at time.Time, aID utils.UUID, bt time.Time, bID utils.UUID) bool {
+	if !at.Equal(bt) {
+		return at.Before(bt)
+	}
+	return bytes.Compare(aID[:], bID[:]) < 0
+}
+
+type fakeBoardRepo struct {
+	boards []*board.Board
+}
+
+// newFakeBoardRepo holds the default board and /g/, which allows one image.
+func newFakeBoardRepo() *fakeBoardRepo {
+	b, _ := board.NewBoard(board.DefaultSlug, "Random", "Anything goes.", 0, 4, 300)
+	b.PosterIDs = true
+	g, _ := board.NewBoard("g", "Technology", "", 0, 1, 300)
+	return &fakeBoardRepo{boards: []*board.Board{b, g}}
+}
+
+func (r *fakeBoardRepo) GetBoardBySlug(ctx context.Context, slug string) (*board.Board, error) {
+	for _, b := range r.boards {
+		if b.Slug == slug {
+			return b, nil
+		}
+	}
+	return nil, errors.ErrBoardNotFound
+}
+
+func (r *fakeBoardRepo) GetBoardByID(ctx context.Context, id utils.UUID) (*board.Board, error) {
+	for _, b := range r.boards {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return nil, errors.ErrBoardNotFound
+}
+
+func (r *fakeBoardRepo) ListBoards(ctx context.Context) ([]*board.Board, error) {
+	return r.boards, nil
+}
+
+type fakeCommentRepo struct {
+	mu       sync.Mutex
+	comments []*comment.Comment
+	// threads, when set, numbers the comments.
+	threads *fakeThreadRepo
+}
+
+func (r *fakeCommentRepo) CreateComment(ctx context.Context, c *comment.Comment) error {
+	if r.threads != nil {
+		r.threads.numberComment(c)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Postgres keeps microseconds; store a copy the way it would come back.
+	stored := *c
+	stored.CreatedAt = c.CreatedAt.Truncate(time.Microsecond)
+	r.comments = append(r.comments, &stored)
+	return nil
+}
+
+func (r *fakeCommentRepo) GetCommentByID(ctx context.Context, id utils.UUID) (*comment.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.comments {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.ErrCommentNotFound
+}
+
+func (r *fakeCommentRepo) UpdateComment(ctx context.Context, c *comment.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.comments {
+		if stored.ID == c.ID {
+			r.comments[i] = c
+			return nil
+		}
+	}
+	return errors.ErrCommentNotFound
+}
+
+func (r *fakeCommentRepo) GetCommentsByThreadID(ctx context.Context, threadID utils.UUID) ([]*comment.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*comment.Comment
+	for _, c := range r.comments {
+		if c.ThreadID == threadID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeCommentRepo) GetCommentIDsBySession(ctx context.Context, threadID, sessionID utils.UUID) ([]utils.UUID, error) {
+	comments, _ := r.GetCommentsByThreadID(ctx, threadID)
+	var ids []utils.UUID
+	for _, c := range comments {
+		if c.SessionID == sessionID {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids, nil
+}
+
+// GetCommentTree walks the thread level by level, the way the recursive
+// query does.
+func (r *fakeCommentRepo) GetCommentTree(ctx context.Context, threadID utils.UUID, rootID *utils.UUID, maxDepth int) ([]*comment.Node, error) {
+	comments, _ := r.GetCommentsByThreadID(ctx, threadID)
+	sort.Slice(comments, func(i, j int) bool {
+		return keyLess(comments[i].CreatedAt, comments[i].ID, comments[j].CreatedAt, comments[j].ID)
+	})
+
+	childCount := make(map[utils.UUID]int)
+	for _, c := range comments {
+		if c.ParentCommentID != nil {
+			childCount[*c.ParentCommentID]++
+		}
+	}
+
+	depth := make(map[utils.UUID]int)
+	for _, c := range comments {
+		if (rootID == nil && c.ParentCommentID == nil) || (rootID != nil && c.ID == *rootID) {
+			depth[c.ID] = 0
+		}
+	}
+	for level := 0; level < maxDepth; level++ {
+		for _, c := range comments {
+			if c.ParentCommentID == nil {
+				continue
+			}
+			if d, ok := depth[*c.ParentCommentID]; ok && d == level {
+				depth[c.ID] = level + 1
+			}
+		}
+	}
+
+	var nodes []*comment.Node
+	for _, c := range comments {
+		if d, ok := depth[c.ID]; ok {
+			nodes = append(nodes, &comment.Node{Comment: c, Depth: d, ChildCount: childCount[c.ID]})
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeCommentRepo) GetCommentsByThreadIDPage(ctx context.Context, threadID utils.UUID, after *pagination.Cursor, limit int) ([]*comment.Comment, error) {
+	comments, _ := r.GetCommentsByThreadID(ctx, threadID)
+	sort.Slice(comments, func(i, j int) bool {
+		return keyLess(comments[i].CreatedAt, comments[i].ID, comments[j].CreatedAt, comments[j].ID)
+	})
+	var result []*comment.Comment
+	for _, c := range comments {
+		if after != nil && !keyLess(after.Time, after.ID, c.CreatedAt, c.ID) {
+			continue
+		}
+		if len(result) == limit {
+			break
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+type fakeRevisionRepo struct {
+	mu        sync.Mutex
+	revisions []*revision.Revision
+}
+
+func (r *fakeRevisionRepo) CreateRevision(ctx context.Context, rev *revision.Revision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revisions = append(r.revisions, rev)
+	return nil
+}
+
+func (r *fakeRevisionRepo) ListRevisions(ctx context.Context, kind revision.Kind, postID utils.UUID) ([]*revision.Revision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*revision.Revision
+	for _, rev := range r.revisions {
+		if rev.Kind == kind && rev.PostID == postID {
+			result = append(result, rev)
+		}
+	}
+	return result, nil
+}
+
+// fakeReferenceRepo keeps the links of each comment in the order the
+// comments were saved.
+type fakeReferenceRepo struct {
+	mu    sync.Mutex
+	order []*comment.Comment
+	refs  map[utils.UUID][]utils.UUID
+}
+
+func (r *fakeReferenceRepo) SaveReferences(ctx context.Context, c *comment.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs == nil {
+		r.refs = make(map[utils.UUID][]utils.UUID)
+	}
+	if _, ok := r.refs[c.ID]; !ok {
+		r.order = append(r.order, c)
+	}
+	r.refs[c.ID] = append([]utils.UUID(nil), c.References...)
+	return nil
+}
+
+func (r *fakeReferenceRepo) ListReferences(ctx context.Context, threadID utils.UUID) ([]*comment.Reference, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*comment.Reference
+	for _, c := range r.order {
+		if c.ThreadID != threadID {
+			continue
+		}
+		for _, target := range r.refs[c.ID] {
+			result = append(result, &comment.Reference{CommentID: c.ID, TargetID: target})
+		}
+	}
+	return result, nil
+}
+
+// fakeSearchRepo returns canned results, already in rank order, filtered
+// by thread status the way the SQL does.
+type fakeSearchRepo struct {
+	results []*search.Result
+}
+
+func (r *fakeSearchRepo) Search(ctx context.Context, q *search.Query, offset, limit int) ([]*search.Result, error) {
+	var matched []*search.Result
+	for _, res := range r.results {
+		if q.Status == search.StatusAll || res.IsArchived == (q.Status == search.StatusArchived) {
+			matched = append(matched, res)
+		}
+	}
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[offset:]
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+type fakeSessionRepo struct {
+	mu       sync.Mutex
+	sessions map[string]*session.Session
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{sessions: make(map[string]*session.Session)}
+}
+
+func (r *fakeSessionRepo) GetSessionByID(ctx context.Context, id string) (*session.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, errors.ErrSessionNotFound
+	}
+	// A copy, like a row read back from Postgres.
+	stored := *s
+	return &stored, nil
+}
+
+func (r *fakeSessionRepo) CreateSession(ctx context.Context, s *session.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[s.ID.String()] = s
+	return nil
+}
+
+func (r *fakeSessionRepo) DeleteExpired(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeSessionRepo) ListActiveSessions(ctx context.Context) ([]*session.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*session.Session
+	for _, s := range r.sessions {
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (r *fakeSessionRepo) UpdateDisplayName(ctx context.Context, id string, name, tripcode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if tripcode == "" {
+		for otherID, other := range r.sessions {
+			if otherID != id && other.Tripcode == "" && !other.IsExpired() && strings.EqualFold(other.DisplayName, name) {
+				return errors.ErrDisplayNameConflict
+			}
+		}
+	}
+	if s, ok := r.sessions[id]; ok {
+		s.DisplayName = name
+		s.Tripcode = tripcode
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) AddSolvedCaptcha(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		s.CaptchasSolved++
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) UpdateIPHash(ctx context.Context, id string, ipHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		s.IPHash = ipHash
+	}
+	return nil
+}
+
+type fakeS3 struct{}
+
+func (fakeS3) UploadImages(files map[string]io.Reader, contentTypes map[string]string) (map[string]string, error) {
+	urls := make(map[string]string, len(files))
+	for name := range files {
+		urls[name] = "http://localhost:9000/bucket/" + name
+	}
+	return urls, nil
+}
+
+func (fakeS3) UploadImage(file io.Reader, size int64, contentType string) (string, error) {
+	return "http://localhost:9000/bucket/image", nil
+}
+
+func (fakeS3) DeleteFile(fileName string) error {
+	return nil
+}
+
+type fakeModeratorRepo struct {
+	mu         sync.Mutex
+	moderators map[string]*moderator.Moderator // by username
+	logins     map[string]*moderator.Login
+}
+
+func newFakeModeratorRepo() *fakeModeratorRepo {
+	return &fakeModeratorRepo{moderators: make(map[string]*moderator.Moderator), logins: make(map[string]*moderator.Login)}
+}
+
+func (r *fakeModeratorRepo) CreateModerator(ctx context.Context, m *moderator.Moderator) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.moderators[m.Username]; ok {
+		return errors.ErrModeratorExists
+	}
+	r.moderators[m.Username] = m
+	return nil
+}
+
+func (r *fakeModeratorRepo) GetModeratorByID(ctx context.Context, id utils.UUID) (*moderator.Moderator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.moderators {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, errors.ErrModeratorNotFound
+}
+
+func (r *fakeModeratorRepo) GetModeratorByUsername(ctx context.Context, username string) (*moderator.Moderator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.moderators[username]; ok {
+		return m, nil
+	}
+	return nil, errors.ErrModeratorNotFound
+}
+
+func (r *fakeModeratorRepo) ListModerators(ctx context.Context) ([]*moderator.Moderator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*moderator.Moderator
+	for _, m := range r.moderators {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Username < result[j].Username })
+	return result, nil
+}
+
+func (r *fakeModeratorRepo) CountModerators(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.moderators), nil
+}
+
+func (r *fakeModeratorRepo) CreateLogin(ctx context.Context, l *moderator.Login) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logins[l.TokenHash] = l
+	return nil
+}
+
+func (r *fakeModeratorRepo) GetLogin(ctx context.Context, tokenHash string, now time.Time) (*moderator.Login, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.logins[tokenHash]
+	if !ok || l.IsExpired(now) {
+		return nil, errors.ErrLoginRequired
+	}
+	return l, nil
+}
+
+func (r *fakeModeratorRepo) DeleteLogin(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.logins, tokenHash)
+	return nil
+}
+
+func (r *fakeModeratorRepo) DeleteExpiredLogins(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+// fakeReportRepo hands out copies of its cases, so that a service only
+// changes one through the port, as it would with Postgres.
+type fakeReportRepo struct {
+	mu      sync.Mutex
+	cases   []*report.Case
+	reports []*report.Report
+	// failResolve, when set, is what ResolveCase fails with.
+	failResolve error
+}
+
+func copyCase(c *report.Case) *report.Case {
+	cp := *c
+	cp.Reasons = make(map[report.Reason]int, len(c.Reasons))
+	for reason, n := range c.Reasons {
+		cp.Reasons[reason] = n
+	}
+	return &cp
+}
+
+func (r *fakeReportRepo) AddReport(ctx context.Context, c *report.Case, rep *report.Report) (*report.Case, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reports {
+		if existing.PostID == rep.PostID && existing.SessionID == rep.SessionID {
+			return nil, errors.ErrAlreadyReported
+		}
+	}
+
+	stored := copyCase(c)
+	found := false
+	for i, open := range r.cases {
+		if open.IsOpen() && open.Post.ID() == rep.PostID {
+			stored, found = r.cases[i], true
+			break
+		}
+	}
+	if !found {
+		r.cases = append(r.cases, stored)
+	}
+	stored.Add(rep)
+	r.reports = append(r.reports, rep)
+	return copyCase(stored), nil
+}
+
+func (r *fakeReportRepo) ListOpenCases(ctx context.Context, limit int) ([]*report.Case, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*report.Case
+	for _, c := range r.cases {
+		if c.IsOpen() {
+			result = append(result, copyCase(c))
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].LastReportedAt.After(result[j].LastReportedAt)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *fakeReportRepo) GetCase(ctx context.Context, id utils.UUID) (*report.Case, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.cases {
+		if c.ID == id {
+			return copyCase(c), nil
+		}
+	}
+	return nil, errors.ErrReportNotFound
+}
+
+func (r *fakeReportRepo) ListReports(ctx context.Context, caseID utils.UUID) ([]*report.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*report.Report
+	for _, rep := range r.reports {
+		if rep.CaseID == caseID {
+			result = append(result, rep)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeReportRepo) ClaimCase(ctx context.Context, id, moderatorID utils.UUID, now, staleBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.cases {
+		if c.ID != id {
+			continue
+		}
+		if !c.IsOpen() {
+			return errors.ErrReportResolved
+		}
+		if c.ClaimedBy != nil && *c.ClaimedBy != moderatorID && !c.ClaimedAt.Before(staleBefore) {
+			return errors.ErrReportClaimed
+		}
+		c.ClaimedBy, c.ClaimedAt = &moderatorID, &now
+		return nil
+	}
+	return errors.ErrReportNotFound
+}
+
+func (r *fakeReportRepo) ResolveCase(ctx context.Context, resolved *report.Case) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failResolve != nil {
+		return r.failResolve
+	}
+	for i, c := range r.cases {
+		if c.ID != resolved.ID {
+			continue
+		}
+		if !c.IsOpen() {
+			return errors.ErrReportResolved
+		}
+		if c.ClaimedBy == nil || *c.ClaimedBy != *resolved.ResolvedBy {
+			return errors.ErrReportClaimed
+		}
+		r.cases[i] = copyCase(resolved)
+		return nil
+	}
+	return errors.ErrReportNotFound
+}
+
+type fakeBanRepo struct {
+	mu   sync.Mutex
+	bans []*ban.Ban
+}
+
+func (r *fakeBanRepo) CreateBan(ctx context.Context, b *ban.Ban) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bans = append(r.bans, b)
+	return nil
+}
+
+func (r *fakeBanRepo) FindBans(ctx context.Context, sessionID utils.UUID, ipHash string, now time.Time) ([]*ban.Ban, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*ban.Ban
+	for _, b := range r.bans {
+		named := b.SessionID != nil && *b.SessionID == sessionID || b.IPHash != "" && b.IPHash == ipHash
+		if banInForce(b, now) && named {
+			result = append(result, b)
+		}
+	}
+	// Bans that never end first, then the ones that end last.
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].ExpiresAt, result[j].ExpiresAt
+		return a == nil && b != nil || a != nil && b != nil && a.After(*b)
+	})
+	return result, nil
+}
+
+func (r *fakeBanRepo) ListActiveBans(ctx context.Context, now time.Time) ([]*ban.Ban, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*ban.Ban
+	for i := len(r.bans) - 1; i >= 0; i-- {
+		if banInForce(r.bans[i], now) {
+			result = append(result, r.bans[i])
+		}
+	}
+	return result, nil
+}
+
+// banInForce is the expiry check the SQL makes.
+func banInForce(b *ban.Ban, now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
+
+func (r *fakeBanRepo) DeleteBan(ctx context.Context, id utils.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, b := range r.bans {
+		if b.ID == id {
+			r.bans = append(r.bans[:i], r.bans[i+1:]...)
+			return nil
+		}
+	}
+	return errors.ErrBanNotFound
+}
+
+func (r *fakeBanRepo) DeleteExpiredBans(ctx context.Context, now time.Time) error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const clientIPKey contextKey = "client_ip"
+
+// ClientIPMiddleware records the address of the client for the flood guard.
+// header names a header set by a trusted reverse proxy, e.g. X-Real-IP or
+// X-Forwarded-For; when it is empty or missing, the peer address is used.
+// Only set it behind a proxy, since clients can send any header they like.
+func ClientIPMiddleware(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ""
+			if header != "" {
+				// A proxy appends the address it saw, so the last entry is
+				// the one it vouches for.
+				values := strings.Split(r.Header.Get(header), ",")
+				ip = strings.TrimSpace(values[len(values)-1])
+			}
+			if ip == "" {
+				ip = peerIP(r)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+// clientIP is the address ClientIPMiddleware recorded, or the peer address.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// floodError returns the refusal of the flood guard inside err, if any.
+func floodError(err error) (*flood.LimitError, bool) {
+	var limitErr *flood.LimitError
+	ok := errors.As(err, &limitErr)
+	return limitErr, ok
+}
+
+// setRetryAfter tells the client how many whole seconds to wait.
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// respondFloodError answers a post refused by the flood guard with 429, and
+// reports whether err was such a refusal.
+func respondFloodError(w http.ResponseWriter, err error) bool {
+	limitErr, ok := floodError(err)
+	if !ok {
+		return false
+	}
+	setRetryAfter(w, limitErr.RetryAfter)
+	RespondError(w, http.StatusTooManyRequests, limitErr.Error())
+	return true
+}
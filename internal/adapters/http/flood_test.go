@@ -0,0 +1,64 @@
+package http
+
+import (
+	"1337b04rd/internal/adapters/memory"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/flood"
+	"1337b04rd/internal/domain/session"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPosts_FloodGuard(t *testing.T) {
+	guard := services.NewPostGuard(memory.NewFloodStore(), flood.Rules{PostInterval: time.Minute, ThreadInterval: time.Hour, DuplicateWindow: time.Hour})
+	s := newTestServer(t, withPostGuard(guard))
+
+	var threadPath string
+	send := func(path, ip, content string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		by := caller{sess: s.newSession("Rick"), headers: map[string]string{"X-Real-IP": ip}}
+		return s.serve("POST", strings.Replace(path, "{id}", threadPath, 1), by, formRequest(t, map[string]string{"title": "hello", "content": content}), status)
+	}
+
+	created := decode[threadResponse](t, send("/api/v1/threads", "10.0.0.1", "first thread", 201))
+	threadPath = "/api/v1/threads/" + created.ID
+
+	tooFast := send("/api/v1/threads", "10.0.0.1", "second thread", 429)
+	if after, err := strconv.Atoi(tooFast.Header().Get("Retry-After")); err != nil || after < 3500 || after > 3600 {
+		t.Errorf("expected Retry-After of about an hour, got %q", tooFast.Header().Get("Retry-After"))
+	}
+
+	// A reply from the same address comes too soon as well, while a reply
+	// from elsewhere passes.
+	send("{id}/comments", "10.0.0.1", "reply", 429)
+	send("{id}/comments", "10.0.0.3", "reply", 201)
+}
+
+func TestPosts_DuplicatesPerPoster(t *testing.T) {
+	guard := services.NewPostGuard(memory.NewFloodStore(), flood.Rules{DuplicateWindow: time.Hour})
+	s := newTestServer(t, withPostGuard(guard))
+	th := s.createThread("b", "title", newTestSessionID(t))
+	target := "/api/v1/threads/" + th.ID.String() + "/comments"
+	rick, morty := s.newSession("Rick"), s.newSession("Morty")
+	send := func(sess *session.Session, ip, content string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		by := caller{sess: sess, headers: map[string]string{"X-Real-IP": ip}}
+		return s.serve("POST", target, by, formRequest(t, map[string]string{"content": content}), status)
+	}
+
+	// Two posters may say the same thing.
+	send(rick, "10.0.0.1", "same text", 201)
+	send(morty, "10.0.0.2", "Same   TEXT", 201)
+
+	// One of them may not, from another address or with a new session.
+	duplicate := send(rick, "10.0.0.3", "same text", 429)
+	if !strings.Contains(duplicate.Body.String(), errors.ErrDuplicatePost.Error()) {
+		t.Errorf("expected a duplicate post error, got %s", duplicate.Body)
+	}
+	send(s.newSession("Rick"), "10.0.0.2", "same text", 429)
+	send(morty, "10.0.0.2", "other text", 201)
+}
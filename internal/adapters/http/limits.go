@@ -0,0 +1,70 @@
+package http
+
+import (
+	domainErrors "1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"errors"
+	"net/http"
+)
+
+// fieldError points a rejected post at the field that broke a limit.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// Limit is the value that was exceeded; left out when it depends on
+	// the board.
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// limitError maps the errors of post.Limits and of the board's image cap to
+// a status and the field they are about. ok is false for any other error.
+func limitError(err error, limits post.Limits) (status int, detail fieldError, ok bool) {
+	detail = fieldError{Message: err.Error()}
+	var limit int64
+	switch err {
+	case domainErrors.ErrTooLongTitle:
+		status, detail.Field, limit = http.StatusUnprocessableEntity, "title", int64(limits.MaxTitleLength)
+	case domainErrors.ErrTooLongContent:
+		status, detail.Field, limit = http.StatusUnprocessableEntity, "content", int64(limits.MaxContentLength)
+	case domainErrors.ErrTooManyLines:
+		status, detail.Field, limit = http.StatusUnprocessableEntity, "content", int64(limits.MaxLines)
+	case domainErrors.ErrTooManyAttachments:
+		status, detail.Field, limit = http.StatusUnprocessableEntity, "images", int64(limits.MaxAttachments)
+	case domainErrors.ErrTooManyImages, domainErrors.ErrImageUploadFailed:
+		status, detail.Field = http.StatusUnprocessableEntity, "images"
+	case domainErrors.ErrAttachmentTooLarge:
+		status, detail.Field, limit = http.StatusRequestEntityTooLarge, "images", limits.MaxAttachmentSize
+	default:
+		return 0, fieldError{}, false
+	}
+	if limit > 0 {
+		detail.Limit = &limit
+	}
+	return status, detail, true
+}
+
+// respondLimitError answers a post that broke a limit with its field
+// details, and reports whether err was such a limit.
+func respondLimitError(w http.ResponseWriter, err error, limits post.Limits) bool {
+	status, detail, ok := limitError(err, limits)
+	if !ok {
+		return false
+	}
+	Respond(w, status, errorResponse{Error: detail.Message, Details: []fieldError{detail}})
+	return true
+}
+
+// parsePostForm parses the multipart form of a new post. A body larger than
+// the limits allow fails with ErrAttachmentTooLarge before it is read in
+// full.
+func parsePostForm(w http.ResponseWriter, r *http.Request, limits post.Limits, maxMemory int64) error {
+	if max := limits.MaxUploadSize(); max > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+	err := r.ParseMultipartForm(maxMemory)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return domainErrors.ErrAttachmentTooLarge
+	}
+	return err
+}
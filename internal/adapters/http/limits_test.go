@@ -0,0 +1,37 @@
+package http
+
+import (
+	"1337b04rd/internal/domain/post"
+	"strings"
+	"testing"
+)
+
+func TestPosts_Limits(t *testing.T) {
+	s := newTestServer(t, withLimits(post.Limits{MaxTitleLength: 40, MaxContentLength: 200, MaxLines: 5, MaxAttachments: 2, MaxAttachmentSize: 64}))
+	sess := s.newSession("Rick")
+	by := caller{sess: sess}
+	th := s.createThread("b", "title", sess.ID)
+	threadPath := "/api/v1/threads/" + th.ID.String()
+	own := s.reply(th.ID, nil, "mine", sess.ID)
+
+	withFiles := func(fields map[string]string, n, size int) testRequest {
+		body, ct := multipartWithFiles(t, fields, n, size)
+		return testRequest{body: body, contentType: ct}
+	}
+
+	s.serve("POST", "/api/v1/threads", by, formRequest(t, map[string]string{"title": strings.Repeat("я", 41), "content": "world"}), 422)
+	s.serve("POST", "/api/v1/threads", by, withFiles(map[string]string{"title": "hello", "content": "world"}, 3, 8), 422)
+	// /g/ takes a single image per post.
+	s.serve("POST", "/api/v1/b/g/threads", by, withFiles(map[string]string{"title": "hello", "content": "world"}, 2, 8), 422)
+	s.serve("POST", "/api/v1/threads", by, withFiles(map[string]string{"title": "hello", "content": "world"}, 2, 8), 201)
+
+	s.serve("POST", threadPath+"/comments", by, formRequest(t, map[string]string{"content": "a\nb\nc\nd\ne\nf"}), 422)
+	s.serve("POST", threadPath+"/comments", by, withFiles(map[string]string{"content": "look"}, 1, 65), 413)
+	// The cap of /g/ holds for replies too.
+	onG := s.createThread("g", "title", sess.ID)
+	s.serve("POST", "/api/v1/threads/"+onG.ID.String()+"/comments", by, withFiles(map[string]string{"content": "two"}, 2, 8), 422)
+	s.serve("POST", "/api/v1/threads/"+onG.ID.String()+"/comments", by, withFiles(map[string]string{"content": "one"}, 1, 8), 201)
+
+	s.serve("PATCH", threadPath, by, rawJSONRequest(`{"title":"`+strings.Repeat("t", 41)+`"}`), 422)
+	s.serve("PATCH", "/api/v1/comments/"+own.ID.String(), by, jsonRequest(map[string]string{"content": strings.Repeat("x", 201)}), 422)
+}
@@ -35,7 +35,7 @@ func SessionMiddleware(svc *services.SessionService, cookieName string) func(htt
 				sess, err = svc.CreateNew(ctx)
 				if err != nil {
 					logger.Error("failed to create new session", "error", err)
-					Respond(w, http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
+					RespondError(w, http.StatusInternalServerError, "failed to create session")
 					return
 				}
 
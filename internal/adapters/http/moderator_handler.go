@@ -0,0 +1,167 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// modCookie carries a moderator's login. It is separate from the session
+// cookie and only sent to /mod, so an anonymous page never sees it.
+const modCookie = "1337mod"
+
+const moderatorKey contextKey = "moderator"
+
+func GetModeratorFromContext(ctx context.Context) (*moderator.Moderator, bool) {
+	m, ok := ctx.Value(moderatorKey).(*moderator.Moderator)
+	return m, ok
+}
+
+// RequireRole lets a request through only for a logged in moderator whose
+// role is at least role, and puts the moderator in its context.
+func RequireRole(svc *services.ModeratorService, role moderator.Role) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var token string
+			if c, err := r.Cookie(modCookie); err == nil {
+				token = c.Value
+			}
+
+			m, err := svc.Authenticate(r.Context(), token)
+			switch {
+			case err == errors.ErrLoginRequired:
+				RespondError(w, http.StatusUnauthorized, err.Error())
+				return
+			case err != nil:
+				logger.Error("failed to authenticate moderator", "error", err)
+				RespondError(w, http.StatusInternalServerError, "could not check login")
+				return
+			case !m.Role.AtLeast(role):
+				logger.Warn("moderator lacks role", "username", m.Username, "role", m.Role, "required", role)
+				RespondError(w, http.StatusForbidden, errors.ErrForbidden.Error())
+				return
+			}
+
+			next(w, r.WithContext(context.WithValue(r.Context(), moderatorKey, m)))
+		}
+	}
+}
+
+type ModeratorHandler struct {
+	modSvc *services.ModeratorService
+}
+
+func NewModeratorHandler(modSvc *services.ModeratorService) *ModeratorHandler {
+	return &ModeratorHandler{modSvc: modSvc}
+}
+
+// POST /mod/login
+func (h *ModeratorHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	m, login, token, err := h.modSvc.Login(r.Context(), strings.TrimSpace(req.Username), req.Password, clientIP(r))
+	if respondFloodError(w, err) {
+		return
+	}
+	if err == errors.ErrInvalidCredentials {
+		RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("failed to log moderator in", "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not log in")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     modCookie,
+		Value:    token,
+		Path:     "/mod",
+		Expires:  login.ExpiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   isHTTPS(r),
+	})
+	Respond(w, http.StatusOK, toModeratorResponse(m))
+}
+
+// POST /mod/logout
+func (h *ModeratorHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(modCookie); err == nil {
+		if err := h.modSvc.Logout(r.Context(), c.Value); err != nil {
+			logger.Error("failed to log moderator out", "error", err)
+			RespondError(w, http.StatusInternalServerError, "could not log out")
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     modCookie,
+		Value:    "",
+		Path:     "/mod",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   isHTTPS(r),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /mod/me
+func (h *ModeratorHandler) Me(w http.ResponseWriter, r *http.Request) {
+	m, _ := GetModeratorFromContext(r.Context())
+	Respond(w, http.StatusOK, toModeratorResponse(m))
+}
+
+// GET /mod/moderators
+func (h *ModeratorHandler) ListModerators(w http.ResponseWriter, r *http.Request) {
+	mods, err := h.modSvc.ListModerators(r.Context())
+	if err != nil {
+		logger.Error("failed to list moderators", "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not list moderators")
+		return
+	}
+
+	result := make([]moderatorResponse, 0, len(mods))
+	for _, m := range mods {
+		result = append(result, toModeratorResponse(m))
+	}
+	Respond(w, http.StatusOK, result)
+}
+
+// POST /mod/moderators
+func (h *ModeratorHandler) CreateModerator(w http.ResponseWriter, r *http.Request) {
+	var req createModeratorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	m, err := h.modSvc.CreateModerator(r.Context(), strings.TrimSpace(req.Username), req.Password, moderator.Role(req.Role))
+	switch err {
+	case nil:
+		Respond(w, http.StatusCreated, toModeratorResponse(m))
+	case errors.ErrInvalidModeratorName, errors.ErrWeakPassword, errors.ErrInvalidRole:
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrModeratorExists:
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		logger.Error("failed to create moderator", "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not create moderator")
+	}
+}
+
+// isHTTPS reports whether the client reached the board over HTTPS, directly
+// or through a proxy that says so.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
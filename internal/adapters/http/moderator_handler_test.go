@@ -0,0 +1,80 @@
+package http
+
+import (
+	"1337b04rd/internal/adapters/memory"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMod_LoginAndRoles(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.modSvc.Bootstrap(context.Background(), "root", "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+
+	as := func(cookie *http.Cookie) caller { return caller{cookie: cookie} }
+	login := func(username, password string) *http.Cookie {
+		t.Helper()
+		rec := s.serve("POST", "/mod/login", caller{}, jsonRequest(loginRequest{Username: username, Password: password}), 200)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == modCookie {
+				if !c.HttpOnly || c.Path != "/mod" || c.SameSite != http.SameSiteStrictMode {
+					t.Errorf("expected a strict, HTTP-only cookie for /mod, got %+v", c)
+				}
+				return c
+			}
+		}
+		t.Fatal("login set no cookie")
+		return nil
+	}
+
+	s.serve("POST", "/mod/login", caller{}, jsonRequest(loginRequest{Username: "root", Password: "wrong password"}), 401)
+	s.serve("POST", "/mod/login", caller{}, jsonRequest(loginRequest{Username: "nobody", Password: "correct horse battery"}), 401)
+	s.serve("GET", "/mod/me", caller{}, testRequest{}, 401)
+	s.serve("GET", "/mod/me", as(&http.Cookie{Name: modCookie, Value: "forged"}), testRequest{}, 401)
+
+	root := as(login("root", "correct horse battery"))
+	s.serve("GET", "/mod/me", root, testRequest{}, 200)
+	s.serve("POST", "/mod/moderators", root, jsonRequest(createModeratorRequest{Username: "jan", Password: "mop and bucket", Role: "janitor"}), 201)
+	s.serve("POST", "/mod/moderators", root, jsonRequest(createModeratorRequest{Username: "jan", Password: "mop and bucket", Role: "janitor"}), 409)
+	s.serve("POST", "/mod/moderators", root, jsonRequest(createModeratorRequest{Username: "boss", Password: "short", Role: "admin"}), 400)
+	s.serve("POST", "/mod/moderators", root, jsonRequest(createModeratorRequest{Username: "boss", Password: "long enough", Role: "king"}), 400)
+	s.serve("GET", "/mod/moderators", root, testRequest{}, 200)
+
+	// A janitor may look around but not manage accounts.
+	jan := as(login("jan", "mop and bucket"))
+	me := decode[moderatorResponse](t, s.serve("GET", "/mod/me", jan, testRequest{}, 200))
+	if me.Username != "jan" || me.Role != "janitor" {
+		t.Errorf("expected janitor jan, got %+v", me)
+	}
+	s.serve("GET", "/mod/moderators", jan, testRequest{}, 403)
+	s.serve("POST", "/mod/moderators", jan, jsonRequest(createModeratorRequest{Username: "jan2", Password: "mop and bucket", Role: "admin"}), 403)
+
+	s.serve("POST", "/mod/logout", jan, testRequest{}, 204)
+	s.serve("GET", "/mod/me", jan, testRequest{}, 401)
+	s.serve("GET", "/mod/me", root, testRequest{}, 200)
+}
+
+func TestMod_LoginThrottledPerAddress(t *testing.T) {
+	guard := services.NewLoginGuard(memory.NewFloodStore(), flood.LoginRules{Attempts: 2, Window: time.Hour}, 1)
+	s := newTestServer(t, withLoginGuard(guard))
+	if err := s.modSvc.Bootstrap(context.Background(), "root", "correct horse battery"); err != nil {
+		t.Fatal(err)
+	}
+
+	from := func(ip string) caller { return caller{headers: map[string]string{"X-Real-IP": ip}} }
+	s.serve("POST", "/mod/login", from("10.0.0.1"), jsonRequest(loginRequest{Username: "root", Password: "wrong password"}), 401)
+	s.serve("POST", "/mod/login", from("10.0.0.1"), jsonRequest(loginRequest{Username: "nobody", Password: "wrong password"}), 401)
+
+	// Out of attempts, even the right password is not checked.
+	refused := s.serve("POST", "/mod/login", from("10.0.0.1"), jsonRequest(loginRequest{Username: "root", Password: "correct horse battery"}), 429)
+	if after, err := strconv.Atoi(refused.Header().Get("Retry-After")); err != nil || after < 3500 || after > 3600 {
+		t.Errorf("expected Retry-After of about an hour, got %q", refused.Header().Get("Retry-After"))
+	}
+	s.serve("POST", "/mod/login", from("10.0.0.2"), jsonRequest(loginRequest{Username: "root", Password: "correct horse battery"}), 200)
+}
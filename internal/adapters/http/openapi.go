@@ -0,0 +1,19 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the contract for /api/v1. openapi_test.go checks it against
+// the registered routes and the response DTOs, so keep them in sync.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// GET /api/v1/openapi.json
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
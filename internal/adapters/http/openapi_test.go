@@ -0,0 +1,279 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/search"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Responses map[string]openAPIResponse `json:"responses"`
+		Schemas   map[string]*openAPISchema  `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *openAPISchema `json:"schema"`
+	} `json:"content"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Nullable   bool                      `json:"nullable"`
+	Required   []string                  `json:"required"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	OneOf      []*openAPISchema          `json:"oneOf"`
+}
+
+var httpMethods = map[string]bool{"get": true, "post": true, "put": true, "patch": true, "delete": true}
+
+func loadOpenAPI(t *testing.T) *openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return &doc
+}
+
+func (d *openAPIDoc) operation(t *testing.T, method, path string) *openAPIOperation {
+	t.Helper()
+	raw, ok := d.Paths[path][strings.ToLower(method)]
+	if !ok {
+		t.Fatalf("operation %s %s is not documented", method, path)
+	}
+	var op openAPIOperation
+	if err := json.Unmarshal(raw, &op); err != nil {
+		t.Fatalf("invalid operation %s %s: %v", method, path, err)
+	}
+	return &op
+}
+
+func (d *openAPIDoc) responseSchema(t *testing.T, op *openAPIOperation, status int) *openAPISchema {
+	t.Helper()
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		t.Fatalf("status %d is not documented", status)
+	}
+	if resp.Ref != "" {
+		resp = d.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	return media.Schema
+}
+
+func (d *openAPIDoc) resolve(s *openAPISchema) *openAPISchema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validate checks a decoded JSON value against the schema. Objects must not
+// carry undocumented properties, so a new DTO field fails until it is added
+// to openapi.json.
+func (d *openAPIDoc) validate(path string, s *openAPISchema, v any) []string {
+	s = d.resolve(s)
+	if s == nil {
+		return []string{path + ": unresolved schema"}
+	}
+	if v == nil {
+		if s.Nullable {
+			return nil
+		}
+		return []string{path + ": null is not allowed"}
+	}
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, alt := range s.OneOf {
+			if len(d.validate(path, alt, v)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of the oneOf schemas, want 1", path, matched)}
+		}
+		return nil
+	}
+
+	var errs []string
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %T", path, v)}
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, path+"."+name+": required property missing")
+			}
+		}
+		for name, val := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if len(s.Properties) > 0 {
+					errs = append(errs, path+"."+name+": undocumented property")
+				}
+				continue
+			}
+			errs = append(errs, d.validate(path+"."+name, prop, val)...)
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array, got %T", path, v)}
+		}
+		for i, item := range arr {
+			errs = append(errs, d.validate(fmt.Sprintf("%s[%d]", path, i), s.Items, item)...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s: expected string, got %T", path, v))
+		}
+	case "integer", "number":
+		if _, ok := v.(float64); !ok {
+			errs = append(errs, fmt.Sprintf("%s: expected number, got %T", path, v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			errs = append(errs, fmt.Sprintf("%s: expected boolean, got %T", path, v))
+		}
+	}
+	return errs
+}
+
+func TestOpenAPI_EveryOperationIsRouted(t *testing.T) {
+	logger.Init("test")
+	doc := loadOpenAPI(t)
+	mux := newMux(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		for method := range doc.Paths[p] {
+			if !httpMethods[method] {
+				continue
+			}
+			method = strings.ToUpper(method)
+			target := strings.ReplaceAll(p, "{id}", "123e4567-e89b-12d3-a456-426614174000")
+			target = strings.ReplaceAll(target, "{slug}", "b")
+			target = strings.ReplaceAll(target, "{number}", "1")
+			req := httptest.NewRequest(method, target, nil)
+			_, pattern := mux.Handler(req)
+			if want := method + " " + p; pattern != want {
+				t.Errorf("%s %s is routed to %q, want %q", method, p, pattern, want)
+			}
+		}
+	}
+}
+
+// TestOpenAPI_ResponsesMatchSchemas sends one request for each documented
+// outcome of the core routes; serve checks every response against its
+// schema. Feature tests next to the handlers cover the rest.
+func TestOpenAPI_ResponsesMatchSchemas(t *testing.T) {
+	commentID := newTestSessionID(t)
+	s := newTestServer(t, withSearchResults(
+		&search.Result{Kind: search.KindThread, ThreadID: newTestSessionID(t), ThreadTitle: "title", Snippet: "\x02hello\x03 world", Rank: 0.6, CreatedAt: time.Now()},
+		&search.Result{Kind: search.KindComment, ThreadID: newTestSessionID(t), CommentID: &commentID, ThreadTitle: "title", Snippet: "say \x02hello\x03", Rank: 0.1, CreatedAt: time.Now(), IsArchived: true},
+	))
+	sess := s.newSession("Rick")
+	s.newSession("Summer")
+
+	created := s.createThread("b", "title", sess.ID)
+	threadPath := "/api/v1/threads/" + created.ID.String()
+	doomed := s.createThread("b", "doomed", sess.ID)
+	foreign := s.createThread("b", "foreign", newTestSessionID(t))
+	own := s.reply(created.ID, nil, "mine", sess.ID)
+	commentPath := "/api/v1/comments/" + own.ID.String()
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   func() testRequest
+		status int
+	}{
+		{"openapi", "GET", "/api/v1/openapi.json", nil, 200},
+		{"session", "GET", "/api/v1/session", nil, 200},
+		{"change name", "PUT", "/api/v1/session/name", func() testRequest { return rawJSONRequest(`{"display_name":"Morty"}`) }, 200},
+		{"change name invalid", "PUT", "/api/v1/session/name", func() testRequest { return rawJSONRequest(`{"display_name":"x"}`) }, 400},
+		{"change name taken", "PUT", "/api/v1/session/name", func() testRequest { return rawJSONRequest(`{"display_name":"summer"}`) }, 409},
+		{"sessions", "GET", "/api/v1/sessions", nil, 200},
+		{"boards", "GET", "/api/v1/boards", nil, 200},
+		{"board", "GET", "/api/v1/b/g", nil, 200},
+		{"board missing", "GET", "/api/v1/b/nope", nil, 404},
+		{"create board thread", "POST", "/api/v1/b/g/threads", func() testRequest { return formRequest(t, map[string]string{"title": "hello", "content": "world"}) }, 201},
+		{"board threads", "GET", "/api/v1/b/g/threads", nil, 200},
+		{"board threads missing", "GET", "/api/v1/b/nope/threads", nil, 404},
+		{"board archive", "GET", "/api/v1/b/g/threads/archive", nil, 200},
+		{"create thread", "POST", "/api/v1/threads", func() testRequest { return formRequest(t, map[string]string{"title": "hello", "content": "world"}) }, 201},
+		{"create thread unknown board", "POST", "/api/v1/threads", func() testRequest {
+			return formRequest(t, map[string]string{"title": "hello", "content": "world", "board": "nope"})
+		}, 404},
+		{"list threads", "GET", "/api/v1/threads", nil, 200},
+		{"archive", "GET", "/api/v1/threads/archive", nil, 200},
+		{"get thread", "GET", threadPath, nil, 200},
+		{"get thread bad id", "GET", "/api/v1/threads/nope", nil, 400},
+		{"get thread missing", "GET", "/api/v1/threads/123e4567-e89b-12d3-a456-426614174000", nil, 404},
+		{"create comment", "POST", threadPath + "/comments", func() testRequest { return formRequest(t, map[string]string{"content": "reply"}) }, 201},
+		{"post", "GET", "/api/v1/posts/" + strconv.FormatInt(own.PostNumber, 10), nil, 200},
+		{"board post", "GET", "/api/v1/b/b/posts/" + strconv.FormatInt(created.PostNumber, 10), nil, 200},
+		{"post missing", "GET", "/api/v1/posts/9999", nil, 404},
+		{"post bad number", "GET", "/api/v1/posts/abc", nil, 400},
+		{"post unknown board", "GET", "/api/v1/b/nope/posts/1", nil, 404},
+		{"edit thread", "PATCH", threadPath, func() testRequest { return rawJSONRequest(`{"title":"edited"}`) }, 200},
+		{"edit thread empty title", "PATCH", threadPath, func() testRequest { return rawJSONRequest(`{"title":"  "}`) }, 400},
+		{"edit foreign thread", "PATCH", "/api/v1/threads/" + foreign.ID.String(), func() testRequest { return rawJSONRequest(`{"title":"mine now"}`) }, 403},
+		{"thread revisions", "GET", threadPath + "/revisions", nil, 200},
+		{"delete foreign thread", "DELETE", "/api/v1/threads/" + foreign.ID.String(), nil, 403},
+		{"delete thread", "DELETE", "/api/v1/threads/" + doomed.ID.String(), nil, 204},
+		{"edit comment", "PATCH", commentPath, func() testRequest { return rawJSONRequest(`{"content":"edited"}`) }, 200},
+		{"comment revisions", "GET", commentPath + "/revisions", nil, 200},
+		{"delete comment", "DELETE", commentPath, nil, 204},
+		{"edit deleted comment", "PATCH", commentPath, func() testRequest { return rawJSONRequest(`{"content":"again"}`) }, 404},
+		{"list comments", "GET", threadPath + "/comments", nil, 200},
+		{"comments bad view", "GET", threadPath + "/comments?view=nested", nil, 400},
+		{"preview", "POST", "/api/v1/preview", func() testRequest {
+			return rawJSONRequest(`{"content":">be me\n[spoiler]<b>x</b>[/spoiler]","thread_id":"` + created.ID.String() + `"}`)
+		}, 200},
+		{"preview bad thread id", "POST", "/api/v1/preview", func() testRequest { return rawJSONRequest(`{"content":"x","thread_id":"nope"}`) }, 400},
+		{"search", "GET", "/api/v1/search?q=hello", nil, 200},
+		{"search archived", "GET", "/api/v1/search?q=hello&status=archived&limit=1", nil, 200},
+		{"search without query", "GET", "/api/v1/search?q=", nil, 400},
+		{"search bad status", "GET", "/api/v1/search?q=hello&status=deleted", nil, 400},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body testRequest
+			if tc.body != nil {
+				body = tc.body()
+			}
+			s.in(t).serve(tc.method, tc.target, caller{sess: sess}, body, tc.status)
+		})
+	}
+}
@@ -0,0 +1,665 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/markup"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/search"
+	"1337b04rd/internal/domain/session"
+	"1337b04rd/internal/domain/thread"
+	"1337b04rd/web"
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pageNames = []string{
+	"catalog",
+	"archive",
+	"post",
+	"archive-post",
+	"create-post",
+	"profile",
+	"search",
+	"error",
+}
+
+type PageHandler struct {
+	threadSvc  *services.ThreadService
+	commentSvc *services.CommentService
+	sessionSvc *services.SessionService
+	searchSvc  *services.SearchService
+	boardSvc   *services.BoardService
+	captchaSvc *services.CaptchaService
+	templates  map[string]*template.Template
+}
+
+type pageData struct {
+	Title    string
+	Heading  string
+	Session  *session.Session
+	Board    *board.Board
+	Boards   []*board.Board
+	Threads  []*thread.Thread
+	Thread   *thread.Thread
+	Comments []*comment.Comment
+	Results  []*search.Result
+	Query    string
+	Filter   string
+	ReplyTo  string
+	// Captcha is the challenge the post form asks to solve, if any.
+	Captcha    *captcha.Challenge
+	NextCursor string
+	Message    string
+	Error      string
+	Status     int
+}
+
+func NewPageHandler(
+	threadSvc *services.ThreadService,
+	commentSvc *services.CommentService,
+	sessionSvc *services.SessionService,
+	searchSvc *services.SearchService,
+	boardSvc *services.BoardService,
+	captchaSvc *services.CaptchaService,
+) *PageHandler {
+	return &PageHandler{
+		threadSvc:  threadSvc,
+		commentSvc: commentSvc,
+		sessionSvc: sessionSvc,
+		searchSvc:  searchSvc,
+		boardSvc:   boardSvc,
+		captchaSvc: captchaSvc,
+		templates:  parsePageTemplates(),
+	}
+}
+
+// parsePageTemplates parses every page together with the shared partials.
+// Each page gets its own set so pages may override blocks like "comment-actions".
+func parsePageTemplates() map[string]*template.Template {
+	funcs := template.FuncMap{
+		"formatTime": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+		"snippet": snippetHTML,
+		"markup": func(content string, threadID utils.UUID) template.HTML {
+			return template.HTML(markup.Render(content, threadID))
+		},
+	}
+
+	templates := make(map[string]*template.Template, len(pageNames))
+	for _, name := range pageNames {
+		templates[name] = template.Must(
+			template.New(name).Funcs(funcs).ParseFS(web.Templates,
+				"templates/partials.html",
+				"templates/"+name+".html",
+			),
+		)
+	}
+	return templates
+}
+
+// GET /
+// GET /b/{slug}
+func (h *PageHandler) Catalog(w http.ResponseWriter, r *http.Request) {
+	data := &pageData{Title: "Catalog", Heading: "Image Board"}
+	if !h.loadBoards(w, r, data) {
+		return
+	}
+
+	page, err := h.threadSvc.ListActiveThreadsPage(r.Context(), r.PathValue("slug"), r.URL.Query().Get("cursor"), 0)
+	if err != nil {
+		if err == errors.ErrInvalidCursor {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid page")
+			return
+		}
+		logger.Error("failed to list active threads for catalog", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to load threads")
+		return
+	}
+
+	data.Threads = page.Items
+	data.NextCursor = page.NextCursor
+	h.render(w, r, http.StatusOK, "catalog", data)
+}
+
+// GET /archive
+// GET /b/{slug}/archive
+func (h *PageHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	data := &pageData{Title: "Archive", Heading: "Image Board - Archive"}
+	if !h.loadBoards(w, r, data) {
+		return
+	}
+
+	page, err := h.threadSvc.ListAllThreadsPage(r.Context(), r.PathValue("slug"), r.URL.Query().Get("cursor"), 0)
+	if err != nil {
+		if err == errors.ErrInvalidCursor {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid page")
+			return
+		}
+		logger.Error("failed to list all threads for archive", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to load threads")
+		return
+	}
+
+	data.Threads = page.Items
+	data.NextCursor = page.NextCursor
+	h.render(w, r, http.StatusOK, "archive", data)
+}
+
+// GET /post/{id}
+func (h *PageHandler) Post(w http.ResponseWriter, r *http.Request) {
+	t, comments, ok := h.loadThread(w, r)
+	if !ok {
+		return
+	}
+
+	if t.IsArchived() {
+		http.Redirect(w, r, "/archive/"+t.ID.String(), http.StatusSeeOther)
+		return
+	}
+
+	replyTo := ""
+	if raw := r.URL.Query().Get("reply_to"); raw != "" {
+		if parentID, err := utils.ParseUUID(raw); err == nil {
+			replyTo = parentID.String()
+		}
+	}
+
+	h.render(w, r, http.StatusOK, "post", &pageData{
+		Title:    t.Title,
+		Heading:  "Thread",
+		Board:    h.threadBoard(r, t),
+		Thread:   t,
+		Comments: comments,
+		ReplyTo:  replyTo,
+		Captcha:  h.captchaFor(r, post.ActionComment),
+	})
+}
+
+// GET /posts/{number}
+// GET /b/{slug}/posts/{number}
+func (h *PageHandler) ResolvePost(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.ParseInt(r.PathValue("number"), 10, 64)
+	if err != nil || number <= 0 {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid post number")
+		return
+	}
+
+	loc, err := h.threadSvc.ResolvePost(r.Context(), boardSlug(r), number)
+	if err != nil {
+		switch err {
+		case errors.ErrBoardNotFound:
+			h.renderError(w, r, http.StatusNotFound, "Board not found")
+		case errors.ErrPostNotFound:
+			h.renderError(w, r, http.StatusNotFound, "Post not found")
+		default:
+			logger.Error("failed to resolve post number", "error", err, "number", number)
+			h.renderError(w, r, http.StatusInternalServerError, "Failed to find post")
+		}
+		return
+	}
+
+	// Archived threads redirect on to their archive page, keeping the anchor.
+	http.Redirect(w, r, "/post/"+loc.ThreadID.String()+"#p"+strconv.FormatInt(loc.Number, 10), http.StatusSeeOther)
+}
+
+// GET /archive/{id}
+func (h *PageHandler) ArchivePost(w http.ResponseWriter, r *http.Request) {
+	t, comments, ok := h.loadThread(w, r)
+	if !ok {
+		return
+	}
+
+	h.render(w, r, http.StatusOK, "archive-post", &pageData{
+		Title:    t.Title,
+		Heading:  "Archived Thread",
+		Board:    h.threadBoard(r, t),
+		Thread:   t,
+		Comments: comments,
+	})
+}
+
+// GET /search?q=&status=&cursor=
+func (h *PageHandler) Search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	data := &pageData{
+		Title:   "Search",
+		Heading: "Search",
+		Query:   strings.TrimSpace(q.Get("q")),
+		Filter:  q.Get("status"),
+	}
+	if data.Query == "" {
+		h.render(w, r, http.StatusOK, "search", data)
+		return
+	}
+
+	page, err := h.searchSvc.Search(r.Context(), data.Query, data.Filter, q.Get("cursor"), 0)
+	if err != nil {
+		if msg, ok := searchErrorMessage(err); ok {
+			data.Error = msg
+			h.render(w, r, http.StatusBadRequest, "search", data)
+			return
+		}
+		logger.Error("failed to search for search page", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Search failed")
+		return
+	}
+
+	data.Results = page.Items
+	data.NextCursor = page.NextCursor
+	h.render(w, r, http.StatusOK, "search", data)
+}
+
+// GET /create?board=
+func (h *PageHandler) CreatePostForm(w http.ResponseWriter, r *http.Request) {
+	boards, err := h.boardSvc.ListBoards(r.Context())
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to load boards")
+		return
+	}
+
+	selected := r.URL.Query().Get("board")
+	if selected == "" {
+		selected = board.DefaultSlug
+	}
+	data := &pageData{
+		Title:   "New Thread",
+		Heading: "Image Board",
+		Boards:  boards,
+		Captcha: h.captchaFor(r, post.ActionThread),
+	}
+	for _, b := range boards {
+		if b.Slug == selected {
+			data.Board = b
+		}
+	}
+
+	h.render(w, r, http.StatusOK, "create-post", data)
+}
+
+// POST /create
+func (h *PageHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		logger.Warn("session not found in CreatePost page")
+		h.renderError(w, r, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	limits := h.threadSvc.Limits()
+	if err := parsePostForm(w, r, limits, 20<<20); err != nil {
+		if h.renderLimitError(w, r, err, limits) {
+			return
+		}
+		logger.Error("failed to parse multipart form", "error", err)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	title := strings.TrimSpace(r.FormValue("title"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	if title == "" || content == "" {
+		h.renderError(w, r, http.StatusBadRequest, "Title and content are required")
+		return
+	}
+
+	if !h.checkCaptcha(w, r, sess, post.ActionThread) {
+		return
+	}
+
+	files, contentTypes, err := h.threadSvc.PrepareFilesFromMultipart(r.MultipartForm)
+	if err != nil {
+		if h.renderLimitError(w, r, err, limits) {
+			return
+		}
+		logger.Error("failed to process files", "error", err)
+		h.renderError(w, r, http.StatusBadRequest, "Failed to process images")
+		return
+	}
+
+	t, err := h.threadSvc.CreateThread(r.Context(), boardSlug(r), title, content, files, contentTypes, sess.ID, sess.Tripcode, clientIP(r))
+	if err != nil {
+		if h.renderLimitError(w, r, err, limits) || h.renderFloodError(w, r, err) {
+			return
+		}
+		if err == errors.ErrBoardNotFound {
+			h.renderError(w, r, http.StatusNotFound, "Board not found")
+			return
+		}
+		logger.Error("failed to create thread", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Could not create thread")
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+t.ID.String(), http.StatusSeeOther)
+}
+
+// POST /post/{id}/comment
+func (h *PageHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		logger.Warn("session not found in CreateComment page")
+		h.renderError(w, r, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	threadID, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid thread ID")
+		return
+	}
+
+	limits := h.commentSvc.Limits()
+	if err := parsePostForm(w, r, limits, 10<<20); err != nil {
+		if h.renderLimitError(w, r, err, limits) {
+			return
+		}
+		logger.Error("failed to parse form", "error", err)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	content := strings.TrimSpace(r.FormValue("content"))
+	if content == "" {
+		h.renderError(w, r, http.StatusBadRequest, "Content is required")
+		return
+	}
+
+	var parentID *utils.UUID
+	if raw := r.FormValue("parent_id"); raw != "" {
+		parsedID, err := utils.ParseUUID(raw)
+		if err != nil {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid parent_id")
+			return
+		}
+		parentID = &parsedID
+	}
+
+	if !h.checkCaptcha(w, r, sess, post.ActionComment) {
+		return
+	}
+
+	files, contentTypes, err := h.commentSvc.PrepareFilesFromMultipart(r.MultipartForm)
+	if err != nil {
+		if h.renderLimitError(w, r, err, limits) {
+			return
+		}
+		logger.Error("failed to process uploaded files", "error", err)
+		h.renderError(w, r, http.StatusBadRequest, "Invalid image upload")
+		return
+	}
+
+	c, err := h.commentSvc.CreateComment(r.Context(), threadID, parentID, content, formSage(r), files, contentTypes, sess.ID, sess.DisplayName, sess.AvatarURL, sess.Tripcode, clientIP(r))
+	if err != nil {
+		if h.renderLimitError(w, r, err, limits) || h.renderFloodError(w, r, err) {
+			return
+		}
+		if err == errors.ErrThreadNotFound {
+			h.renderError(w, r, http.StatusNotFound, "Thread not found")
+			return
+		}
+		if err == errors.ErrThreadLocked {
+			h.renderError(w, r, http.StatusForbidden, "Thread is locked")
+			return
+		}
+		if err == errors.ErrThreadArchived {
+			h.renderError(w, r, http.StatusForbidden, "Thread is archived")
+			return
+		}
+		if err == errors.ErrInvalidParentID {
+			h.renderError(w, r, http.StatusBadRequest, "Replies can only answer comments of this thread")
+			return
+		}
+		if err == errors.ErrInvalidReference {
+			h.renderError(w, r, http.StatusBadRequest, "Replies can only quote posts of this thread")
+			return
+		}
+		if err == errors.ErrTooManyReferences {
+			h.renderError(w, r, http.StatusBadRequest, "Too many quoted posts")
+			return
+		}
+		logger.Error("failed to create comment", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to create comment")
+		return
+	}
+
+	http.Redirect(w, r, "/post/"+threadID.String()+"#c-"+c.ID.String(), http.StatusSeeOther)
+}
+
+// GET /profile
+func (h *PageHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	data := &pageData{
+		Title:   "Profile",
+		Heading: "Profile",
+	}
+	if r.URL.Query().Get("updated") == "1" {
+		data.Message = "Display name updated successfully!"
+	}
+
+	h.render(w, r, http.StatusOK, "profile", data)
+}
+
+// POST /profile
+func (h *PageHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		logger.Warn("session not found in UpdateProfile page")
+		h.renderError(w, r, http.StatusUnauthorized, "Session not found")
+		return
+	}
+
+	_, err := h.sessionSvc.UpdateDisplayName(r.Context(), sess.ID, strings.TrimSpace(r.FormValue("display_name")))
+	if err != nil {
+		var status int
+		var msg string
+		switch err {
+		case errors.ErrInvalidDisplayName:
+			status, msg = http.StatusBadRequest, "Display name must be between 2 and 30 characters, without # or !."
+		case errors.ErrSecureTripcodeDisabled:
+			status, msg = http.StatusBadRequest, "Secure tripcodes (##) are not enabled here."
+		case errors.ErrDisplayNameConflict:
+			status, msg = http.StatusConflict, "That name is taken. Add a tripcode (name#secret) to use it."
+		default:
+			logger.Error("failed to update display name", "session_id", sess.ID, "err", err)
+			h.renderError(w, r, http.StatusInternalServerError, "Could not update name")
+			return
+		}
+		h.render(w, r, status, "profile", &pageData{
+			Title:   "Profile",
+			Heading: "Profile",
+			Error:   msg,
+		})
+		return
+	}
+
+	http.Redirect(w, r, "/profile?updated=1", http.StatusSeeOther)
+}
+
+// NotFound renders the error page for any GET path without a route.
+func (h *PageHandler) NotFound(w http.ResponseWriter, r *http.Request) {
+	h.renderError(w, r, http.StatusNotFound, "Page not found")
+}
+
+// loadBoards fills the board list and, on /b/{slug} routes, the current board.
+func (h *PageHandler) loadBoards(w http.ResponseWriter, r *http.Request, data *pageData) bool {
+	boards, err := h.boardSvc.ListBoards(r.Context())
+	if err != nil {
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to load boards")
+		return false
+	}
+	data.Boards = boards
+
+	slug := r.PathValue("slug")
+	if slug == "" {
+		return true
+	}
+	for _, b := range boards {
+		if b.Slug == slug {
+			data.Board = b
+			data.Title = "/" + b.Slug + "/ - " + b.Title
+			return true
+		}
+	}
+
+	h.renderError(w, r, http.StatusNotFound, "Board not found")
+	return false
+}
+
+// threadBoard returns the board of a thread for the page breadcrumb. A
+// lookup failure only costs the breadcrumb, so it is logged and ignored.
+func (h *PageHandler) threadBoard(r *http.Request, t *thread.Thread) *board.Board {
+	b, err := h.boardSvc.GetBoardByID(r.Context(), t.BoardID)
+	if err != nil {
+		logger.Warn("failed to load board of thread", "error", err, "thread_id", t.ID)
+		return nil
+	}
+	return b
+}
+
+func (h *PageHandler) loadThread(w http.ResponseWriter, r *http.Request) (*thread.Thread, []*comment.Comment, bool) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid thread ID")
+		return nil, nil, false
+	}
+
+	t, err := h.threadSvc.GetThreadByID(r.Context(), id)
+	if err != nil {
+		if err == errors.ErrThreadNotFound {
+			h.renderError(w, r, http.StatusNotFound, "Thread not found")
+			return nil, nil, false
+		}
+		logger.Error("failed to get thread", "error", err, "id", id)
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to get thread")
+		return nil, nil, false
+	}
+
+	comments, err := h.commentSvc.GetCommentsByThreadID(r.Context(), id)
+	if err != nil {
+		logger.Error("failed to get comments", "error", err, "thread_id", id)
+		h.renderError(w, r, http.StatusInternalServerError, "Failed to get comments")
+		return nil, nil, false
+	}
+
+	return t, comments, true
+}
+
+func (h *PageHandler) renderError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	h.render(w, r, status, "error", &pageData{
+		Title:  "Error",
+		Error:  msg,
+		Status: status,
+	})
+}
+
+// renderBanError shows the error page for a refused write, with the reason
+// and end of the ban.
+func (h *PageHandler) renderBanError(w http.ResponseWriter, r *http.Request, status int, msg string, b *ban.Ban) {
+	if b != nil {
+		msg = "You are banned"
+		if b.BoardID != nil {
+			msg += " from /" + b.BoardSlug + "/"
+		}
+		msg += ": " + b.Reason + "."
+		if b.ExpiresAt != nil {
+			msg += " The ban ends " + b.ExpiresAt.Format("2006-01-02 15:04:05") + "."
+		} else {
+			msg += " The ban does not end."
+		}
+	}
+	h.renderError(w, r, status, msg)
+}
+
+// renderLimitError shows the error page for a post that broke a limit,
+// and reports whether err was such a limit.
+func (h *PageHandler) renderLimitError(w http.ResponseWriter, r *http.Request, err error, limits post.Limits) bool {
+	status, detail, ok := limitError(err, limits)
+	if !ok {
+		return false
+	}
+	msg := strings.ToUpper(detail.Message[:1]) + detail.Message[1:]
+	if detail.Limit != nil {
+		msg += fmt.Sprintf(" (the limit is %d)", *detail.Limit)
+	}
+	h.renderError(w, r, status, msg)
+	return true
+}
+
+// captchaFor makes the challenge for a post form when the session has to
+// solve one. Without it the form still shows, and the post is refused.
+func (h *PageHandler) captchaFor(r *http.Request, action post.Action) *captcha.Challenge {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok || !h.captchaSvc.Required(sess, action) {
+		return nil
+	}
+	c, err := h.captchaSvc.NewChallenge(r.Context())
+	if err != nil {
+		logger.Error("failed to create captcha for form", "error", err)
+		return nil
+	}
+	return c
+}
+
+// checkCaptcha verifies the CAPTCHA of a submitted form and shows the error
+// page when it is missing or wrong.
+func (h *PageHandler) checkCaptcha(w http.ResponseWriter, r *http.Request, sess *session.Session, action post.Action) bool {
+	err := h.captchaSvc.Verify(r.Context(), sess, action, r.FormValue("captcha_id"), r.FormValue("captcha_answer"))
+	switch err {
+	case nil:
+		return true
+	case errors.ErrCaptchaRequired:
+		h.renderError(w, r, http.StatusForbidden, "Please solve the captcha")
+	case errors.ErrCaptchaInvalid:
+		h.renderError(w, r, http.StatusForbidden, "Wrong or expired captcha, go back and try a new one")
+	default:
+		logger.Error("failed to check captcha", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Could not check captcha")
+	}
+	return false
+}
+
+// renderFloodError shows the error page for a post refused by the flood
+// guard, and reports whether err was such a refusal.
+func (h *PageHandler) renderFloodError(w http.ResponseWriter, r *http.Request, err error) bool {
+	limitErr, ok := floodError(err)
+	if !ok {
+		return false
+	}
+	setRetryAfter(w, limitErr.RetryAfter)
+	msg := limitErr.Error()
+	h.renderError(w, r, http.StatusTooManyRequests, strings.ToUpper(msg[:1])+msg[1:]+", please wait a moment")
+	return true
+}
+
+// render executes the page into a buffer first so a template error never
+// leaves a half-written response behind.
+func (h *PageHandler) render(w http.ResponseWriter, r *http.Request, status int, name string, data *pageData) {
+	if sess, ok := GetSessionFromContext(r.Context()); ok {
+		data.Session = sess
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates[name].ExecuteTemplate(&buf, name+".html", data); err != nil {
+		logger.Error("failed to render page", "page", name, "error", err)
+		http.Error(w, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		logger.Error("failed to write page", "page", name, "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/search"
+	"1337b04rd/internal/domain/session"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPageTemplatesParse(t *testing.T) {
+	templates := parsePageTemplates()
+	for _, name := range pageNames {
+		if templates[name] == nil {
+			t.Errorf("template %q was not parsed", name)
+		}
+	}
+}
+
+func TestPageHandler_ProfileRendersSession(t *testing.T) {
+	logger.Init("test")
+	h := NewPageHandler(nil, nil, nil, nil, nil, nil)
+
+	sess := &session.Session{DisplayName: "Rick <Sanchez>", AvatarURL: "http://example.com/rick.png"}
+	req := httptest.NewRequest(http.MethodGet, "/profile?updated=1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), sessionKey, sess))
+	rec := httptest.NewRecorder()
+
+	h.Profile(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Rick &lt;Sanchez&gt;") {
+		t.Error("expected escaped display name in page")
+	}
+	if !strings.Contains(body, "Display name updated successfully!") {
+		t.Error("expected success message in page")
+	}
+}
+
+func TestPageHandler_NotFound(t *testing.T) {
+	logger.Init("test")
+	h := NewPageHandler(nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected html content type, got %q", ct)
+	}
+}
+
+func TestPageHandler_SearchEscapesSnippets(t *testing.T) {
+	logger.Init("test")
+	threadID := newTestSessionID(t)
+	searchSvc := services.NewSearchService(&fakeSearchRepo{results: []*search.Result{
+		{Kind: search.KindThread, ThreadID: threadID, ThreadTitle: "title", Snippet: "<b>" + search.HighlightStart + "rick" + search.HighlightStop + "</b>"},
+	}})
+	h := NewPageHandler(nil, nil, nil, searchSvc, nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.Search(rec, httptest.NewRequest(http.MethodGet, "/search?q=rick", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "&lt;b&gt;<mark>rick</mark>&lt;/b&gt;") {
+		t.Errorf("expected escaped snippet with highlighted term, got:\n%s", body)
+	}
+	if !strings.Contains(body, "/post/"+threadID.String()) {
+		t.Error("expected a link to the matching thread")
+	}
+}
+
+func TestPageHandler_BoardCatalog(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	s.createThread("g", "on tech", sessionID)
+	s.createThread("b", "on random", sessionID)
+
+	body := s.serve(http.MethodGet, "/b/g", caller{}, testRequest{}, http.StatusOK).Body.String()
+	if !strings.Contains(body, "on tech") || strings.Contains(body, "on random") {
+		t.Error("expected only threads of /g/ on its catalog")
+	}
+	s.serve(http.MethodGet, "/b/nope", caller{}, testRequest{}, http.StatusNotFound)
+}
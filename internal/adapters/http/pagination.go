@@ -0,0 +1,33 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/domain/errors"
+	"net/http"
+	"strconv"
+)
+
+// parsePageParams reads ?cursor= and ?limit= from the query. A missing limit
+// is returned as 0 and replaced with the default by the service.
+func parsePageParams(r *http.Request) (string, int, error) {
+	q := r.URL.Query()
+	cursor := q.Get("cursor")
+
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > pagination.MaxLimit {
+			return "", 0, errors.ErrInvalidLimit
+		}
+		limit = n
+	}
+
+	return cursor, limit, nil
+}
+
+func nextCursorPtr(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
+}
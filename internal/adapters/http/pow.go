@@ -0,0 +1,283 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/bits"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PowSettings configure the proof-of-work check of new posts: before a post
+// the client finds a nonce that gives SHA-256(challenge + ":" + nonce) at
+// least as many leading zero bits as the challenge asks for.
+type PowSettings struct {
+	// Difficulty is the number of zero bits asked of a calm session on a
+	// calm board; MaxDifficulty caps what load can add to it.
+	Difficulty    int
+	MaxDifficulty int
+	// TTL is how long a challenge may be solved and used.
+	TTL time.Duration
+	// Window is how far back the posts of a session count: every post in
+	// it adds a bit to the session's next challenge.
+	Window time.Duration
+	// LoadThreshold is the number of posts per minute on the whole board
+	// above which every doubling adds a bit. Zero ignores the load.
+	LoadThreshold int
+}
+
+// powPayloadSize is a session ID, an issue time, a difficulty and a salt
+// that makes every challenge unique.
+const powPayloadSize = 16 + 8 + 1 + 8
+
+// ProofOfWork issues signed challenges and checks their solutions. The
+// challenges carry everything needed to check them, so only solutions that
+// were used are stored, to refuse them a second time. A nil ProofOfWork
+// asks for nothing.
+type ProofOfWork struct {
+	secret   []byte
+	settings PowSettings
+	// spent holds the salts of used challenges until they expire.
+	spent ports.FloodPort
+
+	mu       sync.Mutex
+	sessions map[utils.UUID][]time.Time
+	recent   []time.Time // posts of the last minute, oldest first
+	pruned   time.Time
+}
+
+func NewProofOfWork(secret string, settings PowSettings, spent ports.FloodPort) *ProofOfWork {
+	return &ProofOfWork{
+		secret:   []byte(secret),
+		settings: settings,
+		spent:    spent,
+		sessions: make(map[utils.UUID][]time.Time),
+	}
+}
+
+// Difficulty is the number of zero bits the next post of a session needs.
+func (p *ProofOfWork) Difficulty(sessionID utils.UUID, now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune(now)
+	p.pruneSession(sessionID, now)
+
+	difficulty := p.settings.Difficulty + len(p.sessions[sessionID])
+	if threshold := p.settings.LoadThreshold; threshold > 0 && len(p.recent) > threshold {
+		difficulty += 1 + int(math.Log2(float64(len(p.recent))/float64(threshold)))
+	}
+	return min(difficulty, max(p.settings.MaxDifficulty, p.settings.Difficulty))
+}
+
+// Issue signs a new challenge for the session.
+func (p *ProofOfWork) Issue(sessionID utils.UUID, now time.Time) (challenge string, difficulty int, err error) {
+	difficulty = p.Difficulty(sessionID, now)
+
+	payload := make([]byte, powPayloadSize)
+	copy(payload, sessionID[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(now.Unix()))
+	payload[24] = byte(difficulty)
+	if _, err := rand.Read(payload[25:]); err != nil {
+		return "", 0, err
+	}
+	return encodePow(payload) + "." + encodePow(p.sign(payload)), difficulty, nil
+}
+
+// Verify checks a solution and uses the challenge up. A solution is only
+// good for the session it was issued to, until the challenge expires.
+func (p *ProofOfWork) Verify(ctx context.Context, sessionID utils.UUID, challenge, nonce string, now time.Time) error {
+	if challenge == "" || nonce == "" {
+		return errors.ErrProofOfWorkRequired
+	}
+
+	encoded, signature, ok := strings.Cut(challenge, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if !ok || err != nil || len(payload) != powPayloadSize {
+		return errors.ErrProofOfWorkInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, p.sign(payload)) {
+		return errors.ErrProofOfWorkInvalid
+	}
+
+	var issuedTo utils.UUID
+	copy(issuedTo[:], payload)
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0).Add(p.settings.TTL)
+	if issuedTo != sessionID || !now.Before(expires) {
+		return errors.ErrProofOfWorkInvalid
+	}
+
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	if leadingZeroBits(sum[:]) < int(payload[24]) {
+		return errors.ErrProofOfWorkInvalid
+	}
+
+	claim := flood.Claim{Key: "pow:" + hex.EncodeToString(payload[25:]), TTL: expires.Sub(now), Err: errors.ErrProofOfWorkInvalid}
+	held, _, err := p.spent.Acquire(ctx, []flood.Claim{claim}, now)
+	if err != nil {
+		return err
+	}
+	if held != "" {
+		return errors.ErrProofOfWorkInvalid
+	}
+	return nil
+}
+
+// Require wraps a posting handler so that it runs only for a solved
+// challenge, read from the X-Pow-Challenge and X-Pow-Nonce headers or the
+// pow_challenge and pow_nonce query parameters. Nothing of the body is read
+// before that. fail answers a refused request. Only posts the handler
+// accepts raise the difficulty.
+func (p *ProofOfWork) Require(next http.HandlerFunc, fail func(w http.ResponseWriter, r *http.Request, status int, msg string)) http.HandlerFunc {
+	if p == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := GetSessionFromContext(r.Context())
+		if !ok {
+			fail(w, r, http.StatusUnauthorized, "session not found")
+			return
+		}
+
+		challenge, nonce := r.Header.Get("X-Pow-Challenge"), r.Header.Get("X-Pow-Nonce")
+		if challenge == "" && nonce == "" {
+			challenge, nonce = r.URL.Query().Get("pow_challenge"), r.URL.Query().Get("pow_nonce")
+		}
+
+		err := p.Verify(r.Context(), sess.ID, challenge, nonce, time.Now())
+		switch err {
+		case nil:
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next(sw, r)
+			if sw.status < http.StatusBadRequest {
+				p.record(sess.ID, time.Now())
+			}
+		case errors.ErrProofOfWorkRequired, errors.ErrProofOfWorkInvalid:
+			fail(w, r, http.StatusForbidden, err.Error())
+		default:
+			logger.Error("failed to check proof of work", "error", err)
+			fail(w, r, http.StatusInternalServerError, "could not check proof of work")
+		}
+	}
+}
+
+// GET /challenge
+func (p *ProofOfWork) ServeChallenge(w http.ResponseWriter, r *http.Request) {
+	if p == nil {
+		RespondError(w, http.StatusNotFound, "proof of work is not enabled")
+		return
+	}
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
+		return
+	}
+
+	now := time.Now()
+	challenge, difficulty, err := p.Issue(sess.ID, now)
+	if err != nil {
+		logger.Error("failed to issue proof of work challenge", "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not create challenge")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	Respond(w, http.StatusOK, powChallengeResponse{
+		Challenge:  challenge,
+		Difficulty: difficulty,
+		ExpiresAt:  now.Truncate(time.Second).Add(p.settings.TTL),
+	})
+}
+
+// respondPowError answers a refused API request.
+func respondPowError(w http.ResponseWriter, _ *http.Request, status int, msg string) {
+	RespondError(w, status, msg)
+}
+
+// record counts an accepted post towards the difficulty of the next ones.
+func (p *ProofOfWork) record(sessionID utils.UUID, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sessions[sessionID] = append(p.sessions[sessionID], now)
+	p.recent = append(p.recent, now)
+}
+
+// prune drops the posts that no longer count. Sessions are swept once per
+// window, the board's recent posts on every call; pruneSession keeps the
+// session asking in between exact.
+func (p *ProofOfWork) prune(now time.Time) {
+	i := 0
+	for i < len(p.recent) && now.Sub(p.recent[i]) >= time.Minute {
+		i++
+	}
+	p.recent = p.recent[i:]
+
+	if now.Sub(p.pruned) < p.settings.Window {
+		return
+	}
+	p.pruned = now
+	for id := range p.sessions {
+		p.pruneSession(id, now)
+	}
+}
+
+func (p *ProofOfWork) pruneSession(sessionID utils.UUID, now time.Time) {
+	posts := p.sessions[sessionID]
+	j := 0
+	for j < len(posts) && now.Sub(posts[j]) >= p.settings.Window {
+		j++
+	}
+	if j == len(posts) {
+		delete(p.sessions, sessionID)
+	} else {
+		p.sessions[sessionID] = posts[j:]
+	}
+}
+
+// statusWriter remembers the status a handler answered with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (p *ProofOfWork) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func encodePow(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
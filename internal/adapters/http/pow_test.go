@@ -0,0 +1,135 @@
+package http
+
+import (
+	"1337b04rd/internal/adapters/memory"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/session"
+	"context"
+	"crypto/sha256"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestProofOfWork_DifficultyFollowsLoad(t *testing.T) {
+	pow := NewProofOfWork("secret", PowSettings{Difficulty: 4, MaxDifficulty: 8, TTL: time.Minute, Window: time.Hour, LoadThreshold: 2}, memory.NewFloodStore())
+	now := time.Now()
+	calm, busy := newTestSessionID(t), newTestSessionID(t)
+
+	for i, want := range []int{4, 5, 6} {
+		if got := pow.Difficulty(busy, now); got != want {
+			t.Fatalf("after %d posts: expected difficulty %d, got %d", i, want, got)
+		}
+		pow.record(busy, now)
+	}
+	// Five posts a minute are more than twice the threshold of two: a bit
+	// for going over it and one for the doubling.
+	pow.record(busy, now)
+	pow.record(busy, now)
+	pow.record(busy, now)
+	if got := pow.Difficulty(calm, now); got != 6 {
+		t.Errorf("expected difficulty 6 under load, got %d", got)
+	}
+	if got := pow.Difficulty(busy, now); got != 8 {
+		t.Errorf("expected difficulty capped at 8, got %d", got)
+	}
+	if got := pow.Difficulty(calm, now.Add(time.Minute)); got != 4 {
+		t.Errorf("expected the load to pass after a minute, got %d", got)
+	}
+}
+
+func TestProofOfWork_OldPostsStopCounting(t *testing.T) {
+	pow := NewProofOfWork("secret", PowSettings{Difficulty: 4, MaxDifficulty: 8, TTL: time.Minute, Window: time.Hour}, memory.NewFloodStore())
+	now := time.Now()
+	calm, busy := newTestSessionID(t), newTestSessionID(t)
+
+	pow.record(busy, now)
+	// Sweeps every session, the next sweep is due an hour later.
+	pow.Difficulty(calm, now.Add(10*time.Minute))
+	if got := pow.Difficulty(busy, now.Add(59*time.Minute)); got != 5 {
+		t.Errorf("expected the post to count within the window, got %d", got)
+	}
+	if got := pow.Difficulty(busy, now.Add(61*time.Minute)); got != 4 {
+		t.Errorf("expected the post to stop counting after the window, got %d", got)
+	}
+}
+
+func TestProofOfWork_ChallengesExpire(t *testing.T) {
+	pow := NewProofOfWork("secret", PowSettings{Difficulty: 1, MaxDifficulty: 1, TTL: time.Minute, Window: time.Minute}, memory.NewFloodStore())
+	sessionID := newTestSessionID(t)
+	now := time.Now()
+
+	c, difficulty, err := pow.Issue(sessionID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := solvePow(c, difficulty, false)
+	if err := pow.Verify(context.Background(), sessionID, c, nonce, now.Add(2*time.Minute)); err != errors.ErrProofOfWorkInvalid {
+		t.Errorf("expected an expired challenge to be refused, got %v", err)
+	}
+	if err := pow.Verify(context.Background(), sessionID, c, "", now); err != errors.ErrProofOfWorkRequired {
+		t.Errorf("expected a missing nonce to be asked for, got %v", err)
+	}
+	if err := pow.Verify(context.Background(), sessionID, c, nonce, now); err != nil {
+		t.Errorf("expected a fresh solution to pass, got %v", err)
+	}
+}
+
+// solvePow finds the nonce a client would send for a challenge, or one that
+// falls short of it when wrong is set.
+func solvePow(challenge string, difficulty int, wrong bool) string {
+	for n := 0; ; n++ {
+		nonce := strconv.Itoa(n)
+		sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+		if (leadingZeroBits(sum[:]) >= difficulty) != wrong {
+			return nonce
+		}
+	}
+}
+
+func TestPosts_ProofOfWork(t *testing.T) {
+	s := newTestServer(t, withProofOfWork(NewProofOfWork("secret", PowSettings{Difficulty: 8, MaxDifficulty: 9, TTL: time.Minute, Window: time.Minute}, memory.NewFloodStore())))
+	sess, other := s.newSession("Rick"), s.newSession("Morty")
+
+	challenge := func(sess caller, difficulty int) string {
+		t.Helper()
+		resp := decode[powChallengeResponse](t, s.serve("GET", "/challenge", sess, testRequest{}, 200))
+		if resp.Difficulty != difficulty {
+			t.Errorf("expected difficulty %d, got %d", difficulty, resp.Difficulty)
+		}
+		return resp.Challenge
+	}
+	solved := func(sess *session.Session, challenge string, nonce string) caller {
+		return caller{sess: sess, headers: map[string]string{"X-Pow-Challenge": challenge, "X-Pow-Nonce": nonce}}
+	}
+	thread := func() testRequest {
+		return formRequest(t, map[string]string{"title": "t", "content": "worked for it"})
+	}
+
+	s.serve("POST", "/api/v1/threads", caller{sess: sess}, thread(), 403)
+
+	c := challenge(caller{sess: sess}, 8)
+	s.serve("POST", "/api/v1/threads", solved(sess, c, solvePow(c, 8, true)), thread(), 403)
+	s.serve("POST", "/api/v1/threads", solved(other, c, solvePow(c, 8, false)), thread(), 403)
+	// Asking for less work breaks the signature.
+	tampered := []byte(c)
+	tampered[0] ^= 1
+	s.serve("POST", "/api/v1/threads", solved(sess, string(tampered), solvePow(string(tampered), 0, false)), thread(), 403)
+
+	// A solved challenge spent on a rejected post costs nothing more.
+	rejected := challenge(caller{sess: sess}, 8)
+	s.serve("POST", "/api/v1/threads", solved(sess, rejected, solvePow(rejected, 8, false)), formRequest(t, map[string]string{"title": "t"}), 400)
+	challenge(caller{sess: sess}, 8)
+
+	th := decode[threadResponse](t, s.serve("POST", "/api/v1/threads", solved(sess, c, solvePow(c, 8, false)), thread(), 201))
+	s.serve("POST", "/api/v1/threads", solved(sess, c, solvePow(c, 8, false)), thread(), 403)
+
+	// Posting again costs a bit more, up to the cap; forms send the
+	// solution in the query.
+	c = challenge(caller{sess: sess}, 9)
+	target := "/api/v1/threads/" + th.ID + "/comments?pow_challenge=" + url.QueryEscape(c) + "&pow_nonce=" + solvePow(c, 9, false)
+	s.serve("POST", target, caller{sess: sess}, formRequest(t, map[string]string{"content": "reply"}), 201)
+	challenge(caller{sess: sess}, 9)
+	challenge(caller{sess: other}, 8)
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/markup"
+	"encoding/json"
+	"net/http"
+)
+
+// maxPreviewBytes bounds the body of a preview request.
+const maxPreviewBytes = 64 << 10
+
+// POST /api/v1/preview
+// Renders markup the way a post would show it; nothing is saved.
+func PreviewMarkup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewBytes)
+
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	var threadID utils.UUID
+	if req.ThreadID != nil {
+		id, err := utils.ParseUUID(*req.ThreadID)
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "invalid thread ID")
+			return
+		}
+		threadID = id
+	}
+
+	Respond(w, http.StatusOK, previewResponse{ContentHTML: markup.Render(req.Content, threadID)})
+}
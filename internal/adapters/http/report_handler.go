@@ -0,0 +1,158 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/report"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type ReportHandler struct {
+	reportSvc *services.ReportService
+	banSvc    *services.BanService
+}
+
+func NewReportHandler(reportSvc *services.ReportService, banSvc *services.BanService) *ReportHandler {
+	return &ReportHandler{reportSvc: reportSvc, banSvc: banSvc}
+}
+
+// POST /reports
+func (h *ReportHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
+		return
+	}
+
+	var req createReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	postID, err := utils.ParseUUID(req.PostID)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid post ID")
+		return
+	}
+
+	rep, err := h.reportSvc.Report(r.Context(), sess.ID, postID, report.Reason(req.Reason), req.Text)
+	if err != nil {
+		respondReportError(w, err, "report post")
+		return
+	}
+
+	Respond(w, http.StatusCreated, toReportResponse(rep))
+}
+
+// GET /mod/reports?limit=
+func (h *ReportHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	_, limit, err := parsePageParams(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	cases, err := h.reportSvc.Queue(r.Context(), limit)
+	if err != nil {
+		respondReportError(w, err, "list reports")
+		return
+	}
+
+	result := make([]reportCaseResponse, 0, len(cases))
+	for _, c := range cases {
+		result = append(result, toReportCaseResponse(c))
+	}
+	Respond(w, http.StatusOK, result)
+}
+
+// GET /mod/reports/{id}
+func (h *ReportHandler) GetCase(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+
+	c, reports, err := h.reportSvc.Case(r.Context(), id)
+	if err != nil {
+		respondReportError(w, err, "get report")
+		return
+	}
+
+	resp := reportCaseDetailResponse{
+		Case:    toReportCaseResponse(c),
+		Reports: make([]reportResponse, 0, len(reports)),
+	}
+	for _, rep := range reports {
+		resp.Reports = append(resp.Reports, toReportResponse(rep))
+	}
+	Respond(w, http.StatusOK, resp)
+}
+
+// POST /mod/reports/{id}/claim
+func (h *ReportHandler) Claim(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+
+	m, _ := GetModeratorFromContext(r.Context())
+	c, err := h.reportSvc.Claim(r.Context(), id, m)
+	if err != nil {
+		respondReportError(w, err, "claim report")
+		return
+	}
+
+	Respond(w, http.StatusOK, toReportCaseResponse(c))
+}
+
+// POST /mod/reports/{id}/resolve
+func (h *ReportHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid report ID")
+		return
+	}
+
+	var req resolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	resolution := report.Resolution(req.Resolution)
+	var duration time.Duration
+	if resolution == report.ResolutionBan {
+		duration = banDuration(req.BanDuration, h.banSvc)
+	}
+
+	m, _ := GetModeratorFromContext(r.Context())
+	c, err := h.reportSvc.Resolve(r.Context(), id, m, resolution, req.BanReason, duration, req.BoardOnly)
+	if err != nil {
+		respondReportError(w, err, "resolve report")
+		return
+	}
+
+	Respond(w, http.StatusOK, toReportCaseResponse(c))
+}
+
+func respondReportError(w http.ResponseWriter, err error, action string) {
+	switch err {
+	case errors.ErrInvalidReportReason, errors.ErrReportTooLong, errors.ErrInvalidResolution, errors.ErrInvalidBanReason, errors.ErrInvalidBanDuration:
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrForbidden:
+		RespondError(w, http.StatusForbidden, err.Error())
+	case errors.ErrPostNotFound, errors.ErrReportNotFound:
+		RespondError(w, http.StatusNotFound, err.Error())
+	case errors.ErrAlreadyReported, errors.ErrReportClaimed, errors.ErrReportResolved:
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		logger.Error("failed to "+action, "error", err)
+		RespondError(w, http.StatusInternalServerError, "could not "+action)
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReports_Queue(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	author := s.newSession("Rick")
+	alice, bob := caller{sess: s.newSession("Alice")}, caller{sess: s.newSession("Bob")}
+	th := s.createThread("b", "title", author.ID)
+	reply := s.reply(th.ID, nil, "reply", author.ID)
+	jan := caller{cookie: s.moderator("jan", moderator.RoleJanitor)}
+	mod := caller{cookie: s.moderator("mod", moderator.RoleModerator)}
+
+	report := func(by caller, req createReportRequest, status int) {
+		t.Helper()
+		s.serve("POST", "/reports", by, jsonRequest(req), status)
+	}
+	resolve := func(by caller, id string, req resolveReportRequest, status int) reportCaseResponse {
+		t.Helper()
+		rec := s.serve("POST", "/mod/reports/"+id+"/resolve", by, jsonRequest(req), status)
+		if status != 200 {
+			return reportCaseResponse{}
+		}
+		return decode[reportCaseResponse](t, rec)
+	}
+	queue := func() []reportCaseResponse {
+		t.Helper()
+		var cases []reportCaseResponse
+		s.get("/mod/reports", jan, &cases)
+		return cases
+	}
+
+	report(alice, createReportRequest{PostID: th.ID.String(), Reason: "spam", Text: "ads"}, 201)
+	report(alice, createReportRequest{PostID: th.ID.String(), Reason: "abuse"}, 409)
+	report(bob, createReportRequest{PostID: th.ID.String(), Reason: "boring"}, 400)
+	report(bob, createReportRequest{PostID: th.ID.String(), Reason: "other", Text: strings.Repeat("a", 1001)}, 400)
+	report(bob, createReportRequest{PostID: th.BoardID.String(), Reason: "spam"}, 404)
+	report(caller{}, createReportRequest{PostID: th.ID.String(), Reason: "spam"}, 401)
+
+	// One illegal report outweighs the spam report on the thread.
+	report(bob, createReportRequest{PostID: reply.ID.String(), Reason: "illegal"}, 201)
+	cases := queue()
+	if len(cases) != 2 || cases[0].Post.Kind != "comment" || cases[0].Score != 10 || cases[1].Score != 3 {
+		t.Fatalf("expected the comment's case first, got %+v", cases)
+	}
+	commentCase, threadCase := cases[0].ID, cases[1].ID
+
+	// A second report on the thread joins its case.
+	report(bob, createReportRequest{PostID: th.ID.String(), Reason: "off_topic"}, 201)
+	var detail reportCaseDetailResponse
+	s.get("/mod/reports/"+threadCase, jan, &detail)
+	if detail.Case.ReportCount != 2 || detail.Case.Score != 4 || len(detail.Reports) != 2 || detail.Reports[0].Text != "ads" {
+		t.Errorf("expected two reports scoring 4, got %+v", detail)
+	}
+
+	s.serve("GET", "/mod/reports", caller{}, testRequest{}, 401)
+	s.serve("POST", "/mod/reports/"+commentCase+"/claim", jan, testRequest{}, 200)
+	s.serve("POST", "/mod/reports/"+commentCase+"/claim", mod, testRequest{}, 409)
+	resolve(jan, commentCase, resolveReportRequest{Resolution: "ban"}, 403)
+	resolve(jan, commentCase, resolveReportRequest{Resolution: "burn"}, 400)
+	resolve(mod, commentCase, resolveReportRequest{Resolution: "delete"}, 409)
+	resolve(jan, commentCase, resolveReportRequest{Resolution: "delete"}, 200)
+	resolve(jan, commentCase, resolveReportRequest{Resolution: "dismiss"}, 409)
+
+	if c, err := s.comments.GetCommentByID(ctx, reply.ID); err != nil || !c.IsDeleted {
+		t.Errorf("expected the reported comment to be deleted, got %+v, %v", c, err)
+	}
+	report(alice, createReportRequest{PostID: reply.ID.String(), Reason: "spam"}, 404)
+
+	// Only moderators may remove a whole thread, and a janitor refused
+	// does not keep the case from them.
+	resolve(jan, threadCase, resolveReportRequest{Resolution: "delete"}, 403)
+	resolved := resolve(mod, threadCase, resolveReportRequest{Resolution: "dismiss"}, 200)
+	if resolved.Resolution == nil || *resolved.Resolution != "dismiss" || resolved.ResolvedBy == nil {
+		t.Errorf("expected a dismissed case, got %+v", resolved)
+	}
+	if cases := queue(); len(cases) != 0 {
+		t.Errorf("expected an empty queue, got %+v", cases)
+	}
+	if _, err := s.threads.GetThreadByID(ctx, th.ID); err != nil {
+		t.Errorf("expected a dismissed report to keep the thread, got %v", err)
+	}
+}
+
+func TestReports_ResolveIsAllOrNothing(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	author := s.newSession("Rick")
+	th := s.createThread("b", "title", author.ID)
+	reply := s.reply(th.ID, nil, "reply", author.ID)
+	mod := caller{cookie: s.moderator("mod", moderator.RoleModerator)}
+	s.serve("POST", "/reports", caller{sess: s.newSession("Alice")}, jsonRequest(createReportRequest{PostID: reply.ID.String(), Reason: "spam"}), 201)
+	var cases []reportCaseResponse
+	s.get("/mod/reports", mod, &cases)
+	target := "/mod/reports/" + cases[0].ID + "/resolve"
+
+	// A case that cannot be stored stays open, without the ban, for a retry.
+	s.reports.failResolve = errors.New("database is down")
+	s.serve("POST", target, mod, jsonRequest(resolveReportRequest{Resolution: "ban"}), 500)
+	if bans, _ := s.bans.ListActiveBans(ctx, time.Now()); len(bans) != 0 {
+		t.Errorf("expected the ban to be lifted again, got %+v", bans)
+	}
+	if open, _ := s.reports.ListOpenCases(ctx, 10); len(open) != 1 {
+		t.Fatalf("expected the case to stay open, got %+v", open)
+	}
+
+	s.reports.failResolve = nil
+	s.serve("POST", target, mod, jsonRequest(resolveReportRequest{Resolution: "ban"}), 200)
+	if bans, _ := s.bans.ListActiveBans(ctx, time.Now()); len(bans) != 1 {
+		t.Errorf("expected one ban after the retry, got %+v", bans)
+	}
+	if c, err := s.comments.GetCommentByID(ctx, reply.ID); err != nil || !c.IsDeleted {
+		t.Errorf("expected the reported comment to be deleted, got %+v, %v", c, err)
+	}
+}
@@ -14,3 +14,7 @@ func Respond(w http.ResponseWriter, status int, payload interface{}) {
 		}
 	}
 }
+
+func RespondError(w http.ResponseWriter, status int, msg string) {
+	Respond(w, status, errorResponse{Error: msg})
+}
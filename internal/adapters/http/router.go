@@ -2,6 +2,7 @@ package http
 
 import (
 	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/moderator"
 	"net/http"
 )
 
@@ -10,29 +11,137 @@ func NewRouter(
 	avatarSvc *services.AvatarService,
 	threadSvc *services.ThreadService,
 	commentSvc *services.CommentService,
+	searchSvc *services.SearchService,
+	boardSvc *services.BoardService,
+	captchaSvc *services.CaptchaService,
+	pow *ProofOfWork,
+	modSvc *services.ModeratorService,
+	reportSvc *services.ReportService,
+	banSvc *services.BanService,
 ) http.Handler {
+	mux := newMux(sessionSvc, threadSvc, commentSvc, searchSvc, boardSvc, captchaSvc, pow, modSvc, reportSvc, banSvc)
+
+	// === Middleware ===
+	handler := SessionMiddleware(sessionSvc, "1337session")(mux)
+
+	return handler
+}
+
+func newMux(
+	sessionSvc *services.SessionService,
+	threadSvc *services.ThreadService,
+	commentSvc *services.CommentService,
+	searchSvc *services.SearchService,
+	boardSvc *services.BoardService,
+	captchaSvc *services.CaptchaService,
+	pow *ProofOfWork,
+	modSvc *services.ModeratorService,
+	reportSvc *services.ReportService,
+	banSvc *services.BanService,
+) *http.ServeMux {
 	mux := http.NewServeMux()
 	sessionHandler := &SessionHandler{SessionService: sessionSvc}
-	threadHandler := &ThreadHandler{threadSvc: threadSvc}
-	commentHandler := &CommentHandler{commentSvc: commentSvc}
+	boardHandler := NewBoardHandler(boardSvc)
+	threadHandler := &ThreadHandler{threadSvc: threadSvc, captchaSvc: captchaSvc}
+	commentHandler := &CommentHandler{commentSvc: commentSvc, captchaSvc: captchaSvc}
+	captchaHandler := NewCaptchaHandler(captchaSvc)
+	eventsHandler := NewEventsHandler(commentSvc)
+	searchHandler := NewSearchHandler(searchSvc)
+	modHandler := NewModeratorHandler(modSvc)
+	reportHandler := NewReportHandler(reportSvc, banSvc)
+	banHandler := NewBanHandler(banSvc)
+	bans := NewBanGuard(banSvc, threadSvc)
+	pageHandler := NewPageHandler(threadSvc, commentSvc, sessionSvc, searchSvc, boardSvc, captchaSvc)
 
-	// === Сессии ===
-	mux.HandleFunc("POST /session/name", sessionHandler.ChangeDisplayName)
-	mux.HandleFunc("GET /session/me", sessionHandler.GetSessionInfo)
-	mux.HandleFunc("GET /session/list", sessionHandler.ListSessions)
+	// === API v1: документация ===
+	mux.HandleFunc("GET /api/v1/openapi.json", ServeOpenAPI)
 
-	// === Треды ===
-	mux.HandleFunc("POST /threads", threadHandler.CreateThread)
-	mux.HandleFunc("GET /threads/view/", threadHandler.GetThread) // GET /threads/view/{id}
-	mux.HandleFunc("GET /threads", threadHandler.ListActiveThreads)
-	mux.HandleFunc("GET /threads/all", threadHandler.ListAllThreads)
+	// === API v1: сессии ===
+	mux.HandleFunc("GET /api/v1/session", sessionHandler.GetSessionInfo)
+	mux.HandleFunc("PUT /api/v1/session/name", bans.Require(sessionHandler.ChangeDisplayName, nil, respondBanError))
+	mux.HandleFunc("GET /api/v1/sessions", sessionHandler.ListSessions)
 
-	// === Комментарии ===
-	mux.HandleFunc("POST /threads/comment", commentHandler.CreateComment)
-	mux.HandleFunc("GET /threads/comment", commentHandler.GetCommentsByThreadID)
+	// === API v1: капча ===
+	mux.HandleFunc("POST /api/v1/captcha", captchaHandler.CreateChallenge)
+	mux.HandleFunc("GET /api/v1/captcha/{id}/image", captchaHandler.Image)
 
-	// === Middleware ===
-	handler := SessionMiddleware(sessionSvc, "1337session")(mux)
+	// === Proof of work перед постингом ===
+	mux.HandleFunc("GET /challenge", pow.ServeChallenge)
 
-	return handler
+	// === API v1: доски ===
+	mux.HandleFunc("GET /api/v1/boards", boardHandler.ListBoards)
+	mux.HandleFunc("GET /api/v1/b/{slug}", boardHandler.GetBoard)
+	mux.HandleFunc("GET /api/v1/b/{slug}/threads", threadHandler.ListActiveThreads)
+	mux.HandleFunc("POST /api/v1/b/{slug}/threads", bans.Require(pow.Require(threadHandler.CreateThread, respondPowError), bans.newThreadBoard, respondBanError))
+	mux.HandleFunc("GET /api/v1/b/{slug}/threads/archive", threadHandler.ListAllThreads)
+	mux.HandleFunc("GET /api/v1/b/{slug}/posts/{number}", threadHandler.ResolvePost)
+
+	// === API v1: треды ===
+	mux.HandleFunc("GET /api/v1/threads", threadHandler.ListActiveThreads)
+	mux.HandleFunc("POST /api/v1/threads", bans.Require(pow.Require(threadHandler.CreateThread, respondPowError), bans.newThreadBoard, respondBanError))
+	mux.HandleFunc("GET /api/v1/threads/archive", threadHandler.ListAllThreads)
+	mux.HandleFunc("GET /api/v1/threads/{id}", threadHandler.GetThread)
+	mux.HandleFunc("PATCH /api/v1/threads/{id}", bans.Require(threadHandler.EditThread, bans.threadBoard, respondBanError))
+	mux.HandleFunc("DELETE /api/v1/threads/{id}", bans.Require(threadHandler.DeleteThread, bans.threadBoard, respondBanError))
+	mux.HandleFunc("GET /api/v1/threads/{id}/revisions", threadHandler.ListThreadRevisions)
+	mux.HandleFunc("GET /api/v1/posts/{number}", threadHandler.ResolvePost)
+
+	// === API v1: комментарии ===
+	mux.HandleFunc("GET /api/v1/threads/{id}/comments", commentHandler.GetCommentsByThreadID)
+	mux.HandleFunc("POST /api/v1/threads/{id}/comments", bans.Require(pow.Require(commentHandler.CreateComment, respondPowError), bans.threadBoard, respondBanError))
+	mux.HandleFunc("PATCH /api/v1/comments/{id}", bans.Require(commentHandler.EditComment, bans.commentBoard, respondBanError))
+	mux.HandleFunc("DELETE /api/v1/comments/{id}", bans.Require(commentHandler.DeleteComment, bans.commentBoard, respondBanError))
+	mux.HandleFunc("GET /api/v1/comments/{id}/revisions", commentHandler.ListCommentRevisions)
+
+	// === API v1: live-события треда (SSE) ===
+	mux.HandleFunc("GET /api/v1/threads/{id}/events", eventsHandler.StreamThreadEvents)
+
+	// === API v1: предпросмотр разметки ===
+	mux.HandleFunc("POST /api/v1/preview", PreviewMarkup)
+
+	// === Жалобы на посты ===
+	mux.HandleFunc("POST /reports", bans.Require(reportHandler.CreateReport, nil, respondBanError))
+
+	// === API v1: поиск ===
+	mux.HandleFunc("GET /api/v1/search", searchHandler.Search)
+
+	// === Модерация: всё, кроме входа и выхода, требует роли ===
+	janitor, admin := RequireRole(modSvc, moderator.RoleJanitor), RequireRole(modSvc, moderator.RoleAdmin)
+	mod := RequireRole(modSvc, moderator.RoleModerator)
+	mux.HandleFunc("POST /mod/login", modHandler.Login)
+	mux.HandleFunc("POST /mod/logout", modHandler.Logout)
+	mux.HandleFunc("GET /mod/me", janitor(modHandler.Me))
+	mux.HandleFunc("GET /mod/moderators", admin(modHandler.ListModerators))
+	mux.HandleFunc("POST /mod/moderators", admin(modHandler.CreateModerator))
+	mux.HandleFunc("GET /mod/reports", janitor(reportHandler.Queue))
+	mux.HandleFunc("GET /mod/reports/{id}", janitor(reportHandler.GetCase))
+	mux.HandleFunc("POST /mod/reports/{id}/claim", janitor(reportHandler.Claim))
+	mux.HandleFunc("POST /mod/reports/{id}/resolve", janitor(reportHandler.Resolve))
+	mux.HandleFunc("GET /mod/bans", janitor(banHandler.ListBans))
+	mux.HandleFunc("POST /mod/bans", mod(banHandler.CreateBan))
+	mux.HandleFunc("DELETE /mod/bans/{id}", mod(banHandler.LiftBan))
+	mux.HandleFunc("POST /mod/threads/{id}/pin", mod(threadHandler.PinThread))
+	mux.HandleFunc("POST /mod/threads/{id}/unpin", mod(threadHandler.UnpinThread))
+	mux.HandleFunc("POST /mod/threads/{id}/lock", mod(threadHandler.LockThread))
+	mux.HandleFunc("POST /mod/threads/{id}/unlock", mod(threadHandler.UnlockThread))
+	mux.HandleFunc("DELETE /mod/threads/{id}", mod(threadHandler.RemoveThread))
+
+	// === Страницы ===
+	mux.HandleFunc("GET /{$}", pageHandler.Catalog)
+	mux.HandleFunc("GET /archive", pageHandler.Archive)
+	mux.HandleFunc("GET /b/{slug}", pageHandler.Catalog)
+	mux.HandleFunc("GET /b/{slug}/archive", pageHandler.Archive)
+	mux.HandleFunc("GET /archive/{id}", pageHandler.ArchivePost)
+	mux.HandleFunc("GET /post/{id}", pageHandler.Post)
+	mux.HandleFunc("GET /posts/{number}", pageHandler.ResolvePost)
+	mux.HandleFunc("GET /b/{slug}/posts/{number}", pageHandler.ResolvePost)
+	mux.HandleFunc("POST /post/{id}/comment", bans.Require(pow.Require(pageHandler.CreateComment, pageHandler.renderError), bans.threadBoard, pageHandler.renderBanError))
+	mux.HandleFunc("GET /search", pageHandler.Search)
+	mux.HandleFunc("GET /create", pageHandler.CreatePostForm)
+	mux.HandleFunc("POST /create", bans.Require(pow.Require(pageHandler.CreatePost, pageHandler.renderError), bans.newThreadBoard, pageHandler.renderBanError))
+	mux.HandleFunc("GET /profile", pageHandler.Profile)
+	mux.HandleFunc("POST /profile", bans.Require(pageHandler.UpdateProfile, nil, pageHandler.renderBanError))
+	mux.HandleFunc("GET /", pageHandler.NotFound)
+
+	return mux
 }
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/search"
+	"html"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type SearchHandler struct {
+	searchSvc *services.SearchService
+}
+
+func NewSearchHandler(searchSvc *services.SearchService) *SearchHandler {
+	return &SearchHandler{searchSvc: searchSvc}
+}
+
+// GET /api/v1/search?q=&status=&cursor=&limit=
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := parsePageParams(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	q := r.URL.Query()
+	page, err := h.searchSvc.Search(r.Context(), q.Get("q"), q.Get("status"), cursor, limit)
+	if err != nil {
+		if msg, ok := searchErrorMessage(err); ok {
+			RespondError(w, http.StatusBadRequest, msg)
+			return
+		}
+		logger.Error("failed to search", "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to search")
+		return
+	}
+
+	Respond(w, http.StatusOK, toSearchPageResponse(page))
+}
+
+// searchErrorMessage maps client errors of a search request to a message.
+func searchErrorMessage(err error) (string, bool) {
+	switch err {
+	case errors.ErrEmptySearchQuery:
+		return "query is required", true
+	case errors.ErrTooLongSearchQuery:
+		return "query is too long", true
+	case errors.ErrInvalidSearchStatus:
+		return "status must be one of all, active, archived", true
+	case errors.ErrInvalidCursor:
+		return "invalid cursor", true
+	}
+	return "", false
+}
+
+// snippetHTML escapes a search snippet and wraps the highlighted terms in
+// <mark>. The delimiters are control characters, so they survive escaping.
+func snippetHTML(snippet string) template.HTML {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, search.HighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, search.HighlightStop, "</mark>")
+	return template.HTML(escaped)
+}
@@ -0,0 +1,317 @@
+package http
+
+import (
+	"1337b04rd/internal/adapters/events"
+	"1337b04rd/internal/adapters/memory"
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/moderator"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/search"
+	"1337b04rd/internal/domain/session"
+	"1337b04rd/internal/domain/thread"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testServer wires the whole mux around in-memory fakes. The repositories
+// stay reachable so a test can set up or inspect state directly.
+type testServer struct {
+	t   *testing.T
+	doc *openAPIDoc
+
+	sessions *fakeSessionRepo
+	threads  *fakeThreadRepo
+	comments *fakeCommentRepo
+	boards   *fakeBoardRepo
+	refs     *fakeReferenceRepo
+	reports  *fakeReportRepo
+	bans     *fakeBanRepo
+	captchas *memory.CaptchaStore
+
+	sessionSvc *services.SessionService
+	threadSvc  *services.ThreadService
+	commentSvc *services.CommentService
+	captchaSvc *services.CaptchaService
+	modSvc     *services.ModeratorService
+	banSvc     *services.BanService
+
+	// handler serves requests: the mux behind ClientIPMiddleware, which
+	// reads X-Real-IP and falls back to the peer address.
+	handler http.Handler
+	mux     *http.ServeMux
+}
+
+type testServerConfig struct {
+	limits        post.Limits
+	guard         *services.PostGuard
+	loginGuard    *services.LoginGuard
+	captcha       *services.CaptchaSettings
+	pow           *ProofOfWork
+	searchResults []*search.Result
+}
+
+type testServerOption func(*testServerConfig)
+
+func withLimits(limits post.Limits) testServerOption {
+	return func(c *testServerConfig) { c.limits = limits }
+}
+
+func withPostGuard(guard *services.PostGuard) testServerOption {
+	return func(c *testServerConfig) { c.guard = guard }
+}
+
+func withLoginGuard(guard *services.LoginGuard) testServerOption {
+	return func(c *testServerConfig) { c.loginGuard = guard }
+}
+
+func withCaptcha(settings services.CaptchaSettings) testServerOption {
+	return func(c *testServerConfig) { c.captcha = &settings }
+}
+
+func withProofOfWork(pow *ProofOfWork) testServerOption {
+	return func(c *testServerConfig) { c.pow = pow }
+}
+
+func withSearchResults(results ...*search.Result) testServerOption {
+	return func(c *testServerConfig) { c.searchResults = results }
+}
+
+func newTestServer(t *testing.T, opts ...testServerOption) *testServer {
+	t.Helper()
+	logger.Init("test")
+	cfg := testServerConfig{limits: post.DefaultLimits()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s := &testServer{
+		t:        t,
+		doc:      loadOpenAPI(t),
+		sessions: newFakeSessionRepo(),
+		threads:  newFakeThreadRepo(),
+		boards:   newFakeBoardRepo(),
+		refs:     &fakeReferenceRepo{},
+		reports:  &fakeReportRepo{},
+		bans:     &fakeBanRepo{},
+	}
+	s.comments = &fakeCommentRepo{threads: s.threads}
+	broker := events.NewBroker()
+
+	s.sessionSvc = services.NewSessionService(s.sessions, nil, time.Hour, "pepper")
+	s.threadSvc = services.NewThreadService(s.threads, s.boards, &fakeRevisionRepo{}, s.refs, fakeS3{}, fakeS3{}, broker, services.DefaultExpirySettings(), time.Hour, "secret", cfg.limits, cfg.guard)
+	s.commentSvc = services.NewCommentService(s.comments, s.threads, s.boards, &fakeRevisionRepo{}, s.refs, fakeS3{}, s.sessions, broker, time.Hour, "secret", cfg.limits, cfg.guard)
+	if cfg.captcha != nil {
+		s.captchas = memory.NewCaptchaStore()
+		s.captchaSvc = services.NewCaptchaService(s.captchas, s.sessions, *cfg.captcha)
+	}
+	s.modSvc = services.NewModeratorService(newFakeModeratorRepo(), time.Hour, cfg.loginGuard)
+	s.banSvc = services.NewBanService(s.bans, s.sessions, s.boards, s.threads, s.comments, "salt", time.Hour)
+	reportSvc := services.NewReportService(s.reports, s.threads, s.comments, s.threadSvc, s.commentSvc, s.banSvc)
+	searchSvc := services.NewSearchService(&fakeSearchRepo{results: cfg.searchResults})
+
+	s.mux = newMux(s.sessionSvc, s.threadSvc, s.commentSvc, searchSvc, services.NewBoardService(s.boards), s.captchaSvc, cfg.pow, s.modSvc, reportSvc, s.banSvc)
+	s.handler = ClientIPMiddleware("X-Real-IP")(s.mux)
+	return s
+}
+
+// in is the server reporting to t, for use inside a subtest.
+func (s *testServer) in(t *testing.T) *testServer {
+	sub := *s
+	sub.t = t
+	return &sub
+}
+
+// newSession stores a fresh session, the way SessionMiddleware would.
+func (s *testServer) newSession(name string) *session.Session {
+	s.t.Helper()
+	sess, err := session.NewSession("http://example.com/"+strings.ToLower(name)+".png", name, time.Hour)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	stored := *sess
+	if err := s.sessions.CreateSession(context.Background(), &stored); err != nil {
+		s.t.Fatal(err)
+	}
+	return sess
+}
+
+// moderator creates an account with the role and logs it in.
+func (s *testServer) moderator(username string, role moderator.Role) *http.Cookie {
+	s.t.Helper()
+	ctx := context.Background()
+	if _, err := s.modSvc.CreateModerator(ctx, username, "long enough password", role); err != nil {
+		s.t.Fatal(err)
+	}
+	_, _, token, err := s.modSvc.Login(ctx, username, "long enough password", "")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return &http.Cookie{Name: modCookie, Value: token}
+}
+
+func (s *testServer) createThread(slug, title string, sessionID utils.UUID) *thread.Thread {
+	s.t.Helper()
+	th, err := s.threadSvc.CreateThread(context.Background(), slug, title, "content", nil, nil, sessionID, "", "")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return th
+}
+
+// reply posts a comment as a session named Rick.
+func (s *testServer) reply(threadID utils.UUID, parent *utils.UUID, content string, sessionID utils.UUID) *comment.Comment {
+	s.t.Helper()
+	c, err := s.commentSvc.CreateComment(context.Background(), threadID, parent, content, false, nil, nil, sessionID, "Rick", "http://example.com/rick.png", "", "")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return c
+}
+
+// caller is who sends a request: a session, an address and a moderator
+// cookie, each optional.
+type caller struct {
+	sess    *session.Session
+	addr    string
+	cookie  *http.Cookie
+	headers map[string]string
+}
+
+// testRequest is a request body with its content type.
+type testRequest struct {
+	body        io.Reader
+	contentType string
+}
+
+func jsonRequest(v any) testRequest {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(v)
+	return testRequest{body: &buf, contentType: "application/json"}
+}
+
+func rawJSONRequest(body string) testRequest {
+	return testRequest{body: strings.NewReader(body), contentType: "application/json"}
+}
+
+func formRequest(t *testing.T, fields map[string]string) testRequest {
+	t.Helper()
+	body, ct := multipartBody(t, fields)
+	return testRequest{body: body, contentType: ct}
+}
+
+// serve sends the request and fails the test unless it gets status. JSON
+// responses of documented routes are checked against openapi.json.
+func (s *testServer) serve(method, target string, c caller, body testRequest, status int) *httptest.ResponseRecorder {
+	s.t.Helper()
+	if body.body == nil {
+		body.body = &bytes.Buffer{}
+	}
+	req := httptest.NewRequest(method, target, body.body)
+	if body.contentType != "" {
+		req.Header.Set("Content-Type", body.contentType)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	if c.addr != "" {
+		req.RemoteAddr = c.addr + ":1234"
+	}
+	if c.sess != nil {
+		req = req.WithContext(context.WithValue(req.Context(), sessionKey, c.sess))
+	}
+	if c.cookie != nil {
+		req.AddCookie(c.cookie)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: expected %d, got %d: %s", method, target, status, rec.Code, rec.Body)
+	}
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		return rec
+	}
+	_, pattern := s.mux.Handler(req)
+	_, path, _ := strings.Cut(pattern, " ")
+	if _, ok := s.doc.Paths[path]; !ok {
+		s.t.Errorf("%s %s: %s answers JSON but is not documented", method, target, pattern)
+		return rec
+	}
+	if schema := s.doc.responseSchema(s.t, s.doc.operation(s.t, method, path), status); schema != nil {
+		var decoded any
+		if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+			s.t.Fatalf("%s %s: response is not JSON: %v", method, target, err)
+		}
+		for _, e := range s.doc.validate("$", schema, decoded) {
+			s.t.Errorf("%s %s: %s", method, target, e)
+		}
+	}
+	return rec
+}
+
+// get is serve for a GET that decodes the JSON response into out.
+func (s *testServer) get(target string, c caller, out any) {
+	s.t.Helper()
+	rec := s.serve("GET", target, c, testRequest{}, http.StatusOK)
+	if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func newTestSessionID(t *testing.T) utils.UUID {
+	t.Helper()
+	id, err := utils.NewUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func multipartBody(t *testing.T, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	return multipartWithFiles(t, fields, 0, 0)
+}
+
+// multipartWithFiles is multipartBody with n images of size bytes each.
+func multipartWithFiles(t *testing.T, fields map[string]string, n, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		part, err := mw.CreateFormFile("images", fmt.Sprintf("%d.png", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(bytes.Repeat([]byte{0x89}, size))
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf, mw.FormDataContentType()
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"1337b04rd/internal/app/common/logger"
 	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/errors"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -12,33 +13,12 @@ type SessionHandler struct {
 	SessionService *services.SessionService
 }
 
-type changeNameRequest struct {
-	DisplayName string `json:"display_name"`
-}
-
-type changeNameResponse struct {
-	Success bool `json:"success"`
-}
-
-type sessionInfoResponse struct {
-	ID          string `json:"id"`
-	DisplayName string `json:"display_name"`
-	AvatarURL   string `json:"avatar_url"`
-}
-
-type sessionItem struct {
-	ID          string `json:"id"`
-	DisplayName string `json:"display_name"`
-	AvatarURL   string `json:"avatar_url"`
-	ExpiresAt   string `json:"expires_at"`
-}
-
-// POST /session/name
+// PUT /api/v1/session/name
 func (h *SessionHandler) ChangeDisplayName(w http.ResponseWriter, r *http.Request) {
 	sess, ok := GetSessionFromContext(r.Context())
 	if !ok {
 		logger.Warn("session not found in context")
-		Respond(w, http.StatusUnauthorized, map[string]string{"error": "session not found"})
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 
@@ -46,64 +26,55 @@ func (h *SessionHandler) ChangeDisplayName(w http.ResponseWriter, r *http.Reques
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("failed to decode change name request", "err", err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		RespondError(w, http.StatusBadRequest, "invalid request")
 		return
 	}
 
-	name := strings.TrimSpace(req.DisplayName)
-
-	if len(name) < 2 || len(name) > 30 {
-		logger.Warn("invalid name length", "name", name)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "invalid name length"})
-		return
-	}
-
-	err := h.SessionService.UpdateDisplayName(r.Context(), sess.ID, name)
+	// The secret of "name#secret" must not reach the logs.
+	updated, err := h.SessionService.UpdateDisplayName(r.Context(), sess.ID, strings.TrimSpace(req.DisplayName))
 	if err != nil {
-		logger.Error("failed to update display name", "session_id", sess.ID, "err", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "could not update name"})
+		switch err {
+		case errors.ErrInvalidDisplayName:
+			RespondError(w, http.StatusBadRequest, "display name must be 2 to 30 characters without # or !")
+		case errors.ErrSecureTripcodeDisabled:
+			RespondError(w, http.StatusBadRequest, err.Error())
+		case errors.ErrDisplayNameConflict:
+			RespondError(w, http.StatusConflict, err.Error())
+		default:
+			logger.Error("failed to update display name", "session_id", sess.ID, "err", err)
+			RespondError(w, http.StatusInternalServerError, "could not update name")
+		}
 		return
 	}
 
-	logger.Info("display name updated", "session_id", sess.ID, "new_name", name)
+	logger.Info("display name updated", "session_id", sess.ID, "new_name", updated.DisplayName, "tripcode", updated.Tripcode)
 	Respond(w, http.StatusOK, changeNameResponse{Success: true})
 }
 
-// GET /session/me
+// GET /api/v1/session
 func (h *SessionHandler) GetSessionInfo(w http.ResponseWriter, r *http.Request) {
 	sess, ok := GetSessionFromContext(r.Context())
 	if !ok {
 		logger.Warn("session not found in context (me endpoint)")
-		Respond(w, http.StatusUnauthorized, map[string]string{"error": "session not found"})
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 
-	resp := sessionInfoResponse{
-		ID:          sess.ID.String(),
-		DisplayName: sess.DisplayName,
-		AvatarURL:   sess.AvatarURL,
-	}
-
-	Respond(w, http.StatusOK, resp)
+	Respond(w, http.StatusOK, toSessionResponse(sess))
 }
 
-// GET /session/list
+// GET /api/v1/sessions
 func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
 	sessions, err := h.SessionService.ListActiveSessions(r.Context())
 	if err != nil {
 		logger.Error("failed to list active sessions", "err", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "could not list sessions"})
+		RespondError(w, http.StatusInternalServerError, "could not list sessions")
 		return
 	}
 
-	result := make([]sessionItem, 0, len(sessions))
+	result := make([]publicSessionResponse, 0, len(sessions))
 	for _, s := range sessions {
-		result = append(result, sessionItem{
-			ID:          s.ID.String(),
-			DisplayName: s.DisplayName,
-			AvatarURL:   s.AvatarURL,
-			ExpiresAt:   s.ExpiresAt.Format("2006-01-02 15:04:05"),
-		})
+		result = append(result, toPublicSessionResponse(s))
 	}
 
 	Respond(w, http.StatusOK, result)
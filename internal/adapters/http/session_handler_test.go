@@ -0,0 +1,81 @@
+package http
+
+import (
+	"1337b04rd/internal/domain/session"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_TripcodeSignsPosts(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	th := s.createThread("b", "title", sess.ID)
+
+	// Each request reads the session back, as the middleware does.
+	current := func() caller {
+		t.Helper()
+		stored, err := s.sessions.GetSessionByID(context.Background(), sess.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return caller{sess: stored}
+	}
+
+	s.serve("PUT", "/api/v1/session/name", current(), rawJSONRequest(`{"display_name":"Summer !abc"}`), 400)
+	s.serve("PUT", "/api/v1/session/name", current(), rawJSONRequest(`{"display_name":"Rick#secret"}`), 200)
+	me := decode[sessionResponse](t, s.serve("GET", "/api/v1/session", current(), testRequest{}, 200))
+	if me.DisplayName != "Rick" || me.Tripcode == nil || strings.Contains(*me.Tripcode, "secret") {
+		t.Fatalf("expected the name Rick with a tripcode, got %+v", me)
+	}
+
+	posted := decode[commentResponse](t, s.serve("POST", "/api/v1/threads/"+th.ID.String()+"/comments", current(), formRequest(t, map[string]string{"content": "it's me"}), 201))
+	if posted.Tripcode == nil || *posted.Tripcode != *me.Tripcode {
+		t.Errorf("expected the comment to carry tripcode %s, got %v", *me.Tripcode, posted.Tripcode)
+	}
+
+	s.serve("PUT", "/api/v1/session/name", current(), rawJSONRequest(`{"display_name":"Summer##secret"}`), 200)
+	if secure := current().sess; !strings.HasPrefix(secure.Tripcode, "!!") {
+		t.Errorf("expected a secure tripcode, got %q", secure.Tripcode)
+	}
+}
+
+func TestSession_ListHidesIDs(t *testing.T) {
+	s := newTestServer(t)
+	rick, morty := s.newSession("Rick"), s.newSession("Morty")
+
+	rec := s.serve("GET", "/api/v1/sessions", caller{sess: rick}, testRequest{}, 200)
+	for _, id := range []string{rick.ID.String(), morty.ID.String()} {
+		if strings.Contains(rec.Body.String(), id) {
+			t.Errorf("expected the list to hide session %s, got %s", id, rec.Body)
+		}
+	}
+	if list := decode[[]publicSessionResponse](t, rec); len(list) != 2 {
+		t.Errorf("expected both sessions, got %+v", list)
+	}
+
+	if me := decode[sessionResponse](t, s.serve("GET", "/api/v1/session", caller{sess: rick}, testRequest{}, 200)); me.ID != rick.ID.String() {
+		t.Errorf("expected the owner to get the ID, got %+v", me)
+	}
+}
+
+func TestSession_PlainNamesStayUnique(t *testing.T) {
+	s := newTestServer(t)
+	rick, morty, summer := s.newSession("Rick"), s.newSession("Morty"), s.newSession("Summer")
+	rename := func(sess *session.Session, name string, status int) {
+		t.Helper()
+		s.serve("PUT", "/api/v1/session/name", caller{sess: sess}, jsonRequest(map[string]string{"display_name": name}), status)
+	}
+
+	rename(rick, "Пикл Рик", 200)
+	rename(morty, "пикл рик", 409)
+	// A tripcode tells a repeated name apart, and does not take it.
+	rename(morty, "Пикл Рик#secret", 200)
+	rename(summer, "Tiny Rick#secret", 200)
+	rename(rick, "tiny rick", 200)
+
+	// An expired session lets go of its name.
+	s.sessions.sessions[rick.ID.String()].ExpiresAt = time.Now().Add(-time.Minute)
+	rename(summer, "Пикл Рик", 200)
+}
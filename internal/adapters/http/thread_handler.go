@@ -4,14 +4,20 @@ import (
 	"1337b04rd/internal/app/common/logger"
 	"1337b04rd/internal/app/common/utils"
 	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/board"
 	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/thread"
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
 type ThreadHandler struct {
-	threadSvc *services.ThreadService
+	threadSvc  *services.ThreadService
+	captchaSvc *services.CaptchaService
 }
 
 func NewThreadHandler(threadSvc *services.ThreadService) *ThreadHandler {
@@ -20,17 +26,23 @@ func NewThreadHandler(threadSvc *services.ThreadService) *ThreadHandler {
 	}
 }
 
+// POST /api/v1/threads
+// POST /api/v1/b/{slug}/threads
 func (h *ThreadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 	sess, ok := GetSessionFromContext(r.Context())
 	if !ok {
 		logger.Warn("session not found in CreateThread", "context_value", r.Context().Value(sessionKey))
-		Respond(w, http.StatusUnauthorized, map[string]string{"error": "session not found"})
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 
-	if err := r.ParseMultipartForm(20 << 20); err != nil {
+	limits := h.threadSvc.Limits()
+	if err := parsePostForm(w, r, limits, 20<<20); err != nil {
+		if respondLimitError(w, err, limits) {
+			return
+		}
 		logger.Error("failed to parse multipart form", "error", err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "invalid form data"})
+		RespondError(w, http.StatusBadRequest, "invalid form data")
 		return
 	}
 
@@ -38,120 +50,337 @@ func (h *ThreadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 	content := strings.TrimSpace(r.FormValue("content"))
 
 	if title == "" || content == "" {
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "title and content are required"})
+		RespondError(w, http.StatusBadRequest, "title and content are required")
+		return
+	}
+
+	if err := h.captchaSvc.Verify(r.Context(), sess, post.ActionThread, r.FormValue("captcha_id"), r.FormValue("captcha_answer")); err != nil {
+		if respondCaptchaError(w, err) {
+			return
+		}
+		RespondError(w, http.StatusInternalServerError, "could not check captcha")
 		return
 	}
 
 	files, contentTypes, err := h.threadSvc.PrepareFilesFromMultipart(r.MultipartForm)
 	if err != nil {
+		if respondLimitError(w, err, limits) {
+			return
+		}
 		logger.Error("failed to process files", "error", err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "failed to process images"})
+		RespondError(w, http.StatusBadRequest, "failed to process images")
 		return
 	}
 
-	thread, err := h.threadSvc.CreateThread(r.Context(), title, content, files, contentTypes, sess.ID)
+	thread, err := h.threadSvc.CreateThread(r.Context(), boardSlug(r), title, content, files, contentTypes, sess.ID, sess.Tripcode, clientIP(r))
 	if err != nil {
+		if respondLimitError(w, err, limits) || respondFloodError(w, err) {
+			return
+		}
+		if err == errors.ErrBoardNotFound {
+			RespondError(w, http.StatusNotFound, "board not found")
+			return
+		}
 		logger.Error("failed to create thread", "error", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "could not create thread"})
+		RespondError(w, http.StatusInternalServerError, "could not create thread")
 		return
 	}
 
-	Respond(w, http.StatusCreated, map[string]string{
-		"thread_id": thread.ID.String(),
-	})
+	Respond(w, http.StatusCreated, toThreadResponse(thread, viewerOf(r)))
 }
 
+// GET /api/v1/threads/{id}
 func (h *ThreadHandler) GetThread(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		logger.Warn("invalid method", "method", r.Method, "path", r.URL.Path)
-		Respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	idStr := r.PathValue("id")
+	id, err := utils.ParseUUID(idStr)
+	if err != nil {
+		logger.Error("invalid thread id", "error", err, "id", idStr)
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
 		return
 	}
 
-	ctx := r.Context()
-	path := r.URL.Path
-	if !strings.HasPrefix(path, "/threads/view/") {
-		logger.Warn("invalid path", "path", path)
-		Respond(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+	thread, err := h.threadSvc.GetThreadByID(r.Context(), id)
+	if err != nil {
+		if err == errors.ErrThreadNotFound {
+			logger.Warn("thread not found", "id", id)
+			RespondError(w, http.StatusNotFound, "thread not found")
+			return
+		}
+		logger.Error("failed to get thread", "error", err, "id", id)
+		RespondError(w, http.StatusInternalServerError, "failed to get thread")
 		return
 	}
 
-	idStr := strings.TrimPrefix(path, "/threads/view/")
-	id, err := utils.ParseUUID(idStr)
+	Respond(w, http.StatusOK, toThreadResponse(thread, viewerOf(r)))
+}
+
+// GET /api/v1/posts/{number}
+// GET /api/v1/b/{slug}/posts/{number}
+func (h *ThreadHandler) ResolvePost(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.ParseInt(r.PathValue("number"), 10, 64)
+	if err != nil || number <= 0 {
+		RespondError(w, http.StatusBadRequest, "invalid post number")
+		return
+	}
+
+	loc, err := h.threadSvc.ResolvePost(r.Context(), boardSlug(r), number)
 	if err != nil {
-		logger.Error("invalid thread id", "error", err, "id", idStr)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": "Invalid thread ID"})
+		switch err {
+		case errors.ErrBoardNotFound:
+			RespondError(w, http.StatusNotFound, "board not found")
+		case errors.ErrPostNotFound:
+			RespondError(w, http.StatusNotFound, "post not found")
+		default:
+			logger.Error("failed to resolve post number", "error", err, "number", number)
+			RespondError(w, http.StatusInternalServerError, "failed to resolve post")
+		}
+		return
+	}
+
+	Respond(w, http.StatusOK, toPostResponse(loc))
+}
+
+// PATCH /api/v1/threads/{id}
+func (h *ThreadHandler) EditThread(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
 		return
 	}
 
-	thread, err := h.threadSvc.GetThreadByID(ctx, id)
+	id, err := utils.ParseUUID(r.PathValue("id"))
 	if err != nil {
-		if err == errors.ErrThreadNotFound {
-			logger.Warn("thread not found", "id", id)
-			Respond(w, http.StatusNotFound, map[string]string{"error": "Thread not found"})
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
+		return
+	}
+
+	var req editThreadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		req.Title = &title
+	}
+	if req.Content != nil {
+		content := strings.TrimSpace(*req.Content)
+		req.Content = &content
+	}
+
+	thread, err := h.threadSvc.EditThread(r.Context(), id, sess.ID, req.Title, req.Content)
+	if err != nil {
+		if respondLimitError(w, err, h.threadSvc.Limits()) {
 			return
 		}
-		logger.Error("failed to get thread", "error", err, "id", id)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "Failed to get thread"})
+		respondPostChangeError(w, err, "edit thread")
+		return
+	}
+
+	Respond(w, http.StatusOK, toThreadResponse(thread, viewerOf(r)))
+}
+
+// DELETE /api/v1/threads/{id}
+func (h *ThreadHandler) DeleteThread(w http.ResponseWriter, r *http.Request) {
+	sess, ok := GetSessionFromContext(r.Context())
+	if !ok {
+		RespondError(w, http.StatusUnauthorized, "session not found")
+		return
+	}
+
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(thread); err != nil {
-		logger.Error("failed to encode response", "error", err)
+	if err := h.threadSvc.DeleteThread(r.Context(), id, sess.ID); err != nil {
+		respondPostChangeError(w, err, "delete thread")
+		return
 	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *ThreadHandler) ListActiveThreads(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		logger.Warn("invalid method", "method", r.Method, "path", r.URL.Path)
-		Respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+// GET /api/v1/threads/{id}/revisions
+func (h *ThreadHandler) ListThreadRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
 		return
 	}
 
-	if r.URL.Path != "/threads" {
-		logger.Warn("invalid path", "path", r.URL.Path)
-		Respond(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+	revisions, err := h.threadSvc.ListThreadRevisions(r.Context(), id)
+	if err != nil {
+		respondPostChangeError(w, err, "list thread revisions")
 		return
 	}
 
-	ctx := r.Context()
-	threads, err := h.threadSvc.ListActiveThreads(ctx)
+	Respond(w, http.StatusOK, toRevisionResponses(revisions))
+}
+
+// GET /api/v1/threads?cursor=&limit=
+// GET /api/v1/b/{slug}/threads?cursor=&limit=
+func (h *ThreadHandler) ListActiveThreads(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := parsePageParams(r)
 	if err != nil {
-		logger.Error("failed to list active threads", "error", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list threads"})
+		RespondError(w, http.StatusBadRequest, "invalid limit")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(threads); err != nil {
-		logger.Error("failed to encode response", "error", err)
+	page, err := h.threadSvc.ListActiveThreadsPage(r.Context(), r.PathValue("slug"), cursor, limit)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidCursor:
+			RespondError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		case errors.ErrBoardNotFound:
+			RespondError(w, http.StatusNotFound, "board not found")
+			return
+		}
+		logger.Error("failed to list active threads", "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to list threads")
+		return
 	}
+
+	Respond(w, http.StatusOK, toThreadPageResponse(page, viewerOf(r)))
 }
 
+// GET /api/v1/threads/archive?cursor=&limit=
+// GET /api/v1/b/{slug}/threads/archive?cursor=&limit=
 func (h *ThreadHandler) ListAllThreads(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		logger.Warn("invalid method", "method", r.Method, "path", r.URL.Path)
-		Respond(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+	cursor, limit, err := parsePageParams(r)
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	page, err := h.threadSvc.ListAllThreadsPage(r.Context(), r.PathValue("slug"), cursor, limit)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidCursor:
+			RespondError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		case errors.ErrBoardNotFound:
+			RespondError(w, http.StatusNotFound, "board not found")
+			return
+		}
+		logger.Error("failed to list all threads", "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to list threads")
+		return
+	}
+
+	Respond(w, http.StatusOK, toThreadPageResponse(page, viewerOf(r)))
+}
+
+// boardSlug picks the board a new thread goes to: the {slug} of a board route,
+// otherwise the "board" form field, otherwise the default board.
+func boardSlug(r *http.Request) string {
+	if slug := r.PathValue("slug"); slug != "" {
+		return slug
+	}
+	if slug := strings.TrimSpace(r.FormValue("board")); slug != "" {
+		return slug
+	}
+	return board.DefaultSlug
+}
+
+// POST /mod/threads/{id}/pin
+func (h *ThreadHandler) PinThread(w http.ResponseWriter, r *http.Request) {
+	h.moderateThread(w, r, "pin thread", func(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+		return h.threadSvc.PinThread(ctx, id, true)
+	})
+}
+
+// POST /mod/threads/{id}/unpin
+func (h *ThreadHandler) UnpinThread(w http.ResponseWriter, r *http.Request) {
+	h.moderateThread(w, r, "unpin thread", func(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+		return h.threadSvc.PinThread(ctx, id, false)
+	})
+}
+
+// POST /mod/threads/{id}/lock
+func (h *ThreadHandler) LockThread(w http.ResponseWriter, r *http.Request) {
+	h.moderateThread(w, r, "lock thread", func(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+		return h.threadSvc.LockThread(ctx, id, true)
+	})
+}
+
+// POST /mod/threads/{id}/unlock
+func (h *ThreadHandler) UnlockThread(w http.ResponseWriter, r *http.Request) {
+	h.moderateThread(w, r, "unlock thread", func(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+		return h.threadSvc.LockThread(ctx, id, false)
+	})
+}
+
+// DELETE /mod/threads/{id}
+func (h *ThreadHandler) RemoveThread(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
+	if err != nil {
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
 		return
 	}
 
-	if r.URL.Path != "/threads/all" {
-		logger.Warn("invalid path", "path", r.URL.Path)
-		Respond(w, http.StatusNotFound, map[string]string{"error": "Not found"})
+	if err := h.threadSvc.RemoveThread(r.Context(), id); err != nil {
+		respondModThreadError(w, err, "remove thread")
 		return
 	}
 
-	ctx := r.Context()
-	threads, err := h.threadSvc.ListAllThreads(ctx)
+	m, _ := GetModeratorFromContext(r.Context())
+	logger.Info("thread removed by moderator", "thread_id", id, "moderator", m.Username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// moderateThread runs a moderator's change of the thread in the path and
+// answers with the thread.
+func (h *ThreadHandler) moderateThread(w http.ResponseWriter, r *http.Request, action string, change func(context.Context, utils.UUID) (*thread.Thread, error)) {
+	id, err := utils.ParseUUID(r.PathValue("id"))
 	if err != nil {
-		logger.Error("failed to list all threads", "error", err)
-		Respond(w, http.StatusInternalServerError, map[string]string{"error": "Failed to list threads"})
+		RespondError(w, http.StatusBadRequest, "invalid thread ID")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(threads); err != nil {
-		logger.Error("failed to encode response", "error", err)
+	t, err := change(r.Context(), id)
+	if err != nil {
+		respondModThreadError(w, err, action)
+		return
+	}
+
+	m, _ := GetModeratorFromContext(r.Context())
+	logger.Info("moderator changed thread", "action", action, "thread_id", id, "moderator", m.Username)
+	Respond(w, http.StatusOK, toThreadResponse(t, viewerOf(r)))
+}
+
+// respondModThreadError maps the errors of moderator actions on threads. A
+// thread that cannot take the action in its state is a conflict.
+func respondModThreadError(w http.ResponseWriter, err error, action string) {
+	switch err {
+	case errors.ErrThreadNotFound:
+		RespondError(w, http.StatusNotFound, "thread not found")
+	case errors.ErrThreadArchived, errors.ErrInvalidThreadTransition:
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		logger.Error("failed to "+action, "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+// respondPostChangeError maps the errors of author edits and deletions of
+// threads and comments.
+func respondPostChangeError(w http.ResponseWriter, err error, action string) {
+	switch err {
+	case errors.ErrThreadNotFound:
+		RespondError(w, http.StatusNotFound, "thread not found")
+	case errors.ErrCommentNotFound:
+		RespondError(w, http.StatusNotFound, "comment not found")
+	case errors.ErrEmptyTitle, errors.ErrEmptyContent, errors.ErrInvalidReference, errors.ErrTooManyReferences:
+		RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.ErrNotPostAuthor, errors.ErrEditWindowClosed, errors.ErrThreadLocked, errors.ErrThreadArchived:
+		RespondError(w, http.StatusForbidden, err.Error())
+	case errors.ErrInvalidThreadTransition:
+		RespondError(w, http.StatusConflict, err.Error())
+	default:
+		logger.Error("failed to "+action, "error", err)
+		RespondError(w, http.StatusInternalServerError, "failed to "+action)
 	}
 }
@@ -0,0 +1,258 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListThreads_PaginatesWithCursor(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	for i := 0; i < 3; i++ {
+		s.createThread("b", fmt.Sprintf("t%d", i), sessionID)
+	}
+
+	seen := map[string]bool{}
+	target := "/api/v1/threads?limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		var page threadPageResponse
+		s.get(target, caller{}, &page)
+		for _, item := range page.Items {
+			if seen[item.ID] {
+				t.Errorf("thread %s returned twice", item.ID)
+			}
+			seen[item.ID] = true
+		}
+
+		target = ""
+		if page.NextCursor != nil {
+			target = "/api/v1/threads?limit=2&cursor=" + *page.NextCursor
+		}
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("expected 3 threads across pages, got %d", len(seen))
+	}
+	s.serve("GET", "/api/v1/threads?cursor=garbage", caller{}, testRequest{}, 400)
+}
+
+func TestListThreads_PinnedFirst(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	var ids []utils.UUID
+	for i := 0; i < 3; i++ {
+		ids = append(ids, s.createThread("b", fmt.Sprintf("t%d", i), sessionID).ID)
+	}
+	if _, err := s.threadSvc.PinThread(context.Background(), ids[0], true); err != nil {
+		t.Fatal(err)
+	}
+
+	var page threadPageResponse
+	s.get("/api/v1/threads?limit=1", caller{}, &page)
+	if len(page.Items) != 2 || page.Items[0].ID != ids[0].String() || !page.Items[0].IsPinned || page.Items[0].ExpiresAt != nil {
+		t.Fatalf("expected the pinned thread without a deadline in front of the first page, got %+v", page.Items)
+	}
+	if page.NextCursor == nil {
+		t.Fatal("expected a next page")
+	}
+
+	cursor := *page.NextCursor
+	page = threadPageResponse{}
+	s.get("/api/v1/threads?limit=1&cursor="+cursor, caller{}, &page)
+	if len(page.Items) != 1 || page.Items[0].ID == ids[0].String() {
+		t.Fatalf("expected the pinned thread only on the first page, got %+v", page.Items)
+	}
+}
+
+func TestListThreads_OrdersByBump(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	older := s.createThread("b", "older", sessionID)
+	newer := s.createThread("b", "newer", sessionID)
+	older.BumpedAt = newer.CreatedAt.Add(time.Second)
+
+	var page threadPageResponse
+	s.get("/api/v1/threads", caller{}, &page)
+	if len(page.Items) != 2 || page.Items[0].ID != older.ID.String() {
+		t.Fatalf("expected the bumped thread first, got %+v", page.Items)
+	}
+
+	page = threadPageResponse{}
+	s.get("/api/v1/threads/archive", caller{}, &page)
+	if len(page.Items) != 2 || page.Items[0].ID != newer.ID.String() {
+		t.Fatalf("expected the archive to stay in creation order, got %+v", page.Items)
+	}
+}
+
+func TestThreads_ClosedToReplies(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	locked := s.createThread("b", "locked", sess.ID)
+	if _, err := s.threadSvc.LockThread(context.Background(), locked.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	archived := s.createThread("b", "archived", sess.ID)
+	if err := archived.Archive(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, th := range []utils.UUID{locked.ID, archived.ID} {
+		s.serve("POST", "/api/v1/threads/"+th.String()+"/comments", caller{sess: sess}, formRequest(t, map[string]string{"content": "reply"}), 403)
+	}
+}
+
+func TestPosts_NumberedPerBoard(t *testing.T) {
+	s := newTestServer(t)
+	sessionID := newTestSessionID(t)
+	th := s.createThread("b", "title", sessionID)
+	onG := s.createThread("g", "title", sessionID)
+	first := s.reply(th.ID, nil, "first", sessionID)
+	if th.PostNumber != 1 || onG.PostNumber != 1 || first.PostNumber != 2 {
+		t.Fatalf("expected numbers 1, 1 and 2, got %d, %d and %d", th.PostNumber, onG.PostNumber, first.PostNumber)
+	}
+
+	second := s.reply(th.ID, nil, ">>2 and >>1", sessionID)
+	if got := second.References; len(got) != 2 || got[0] != first.ID || got[1] != th.ID {
+		t.Errorf("expected the numbers to link the first comment and the thread, got %v", got)
+	}
+
+	var resp postResponse
+	s.get("/api/v1/posts/2", caller{}, &resp)
+	if resp.Kind != "comment" || resp.ThreadID != th.ID.String() || resp.CommentID == nil || *resp.CommentID != first.ID.String() {
+		t.Errorf("expected post 2 to be the first comment, got %+v", resp)
+	}
+	s.get("/api/v1/b/g/posts/"+strconv.FormatInt(onG.PostNumber, 10), caller{}, &resp)
+	if resp.Kind != "thread" || resp.ThreadID != onG.ID.String() {
+		t.Errorf("expected post 1 of /g/ to be its thread, got %+v", resp)
+	}
+
+	rec := s.serve("GET", "/posts/2", caller{}, testRequest{}, 303)
+	if want := "/post/" + th.ID.String() + "#p2"; rec.Header().Get("Location") != want {
+		t.Errorf("expected a redirect to %s, got %s", want, rec.Header().Get("Location"))
+	}
+}
+
+func TestPosts_PosterIDsPerThread(t *testing.T) {
+	s := newTestServer(t)
+	op, stranger := newTestSessionID(t), newTestSessionID(t)
+	first := s.createThread("b", "first", op)
+	second := s.createThread("b", "second", op)
+	plain := s.createThread("g", "plain", op)
+	for _, sessionID := range []utils.UUID{op, stranger} {
+		s.reply(first.ID, nil, "reply", sessionID)
+	}
+
+	getThread := func(id utils.UUID) threadResponse {
+		var resp threadResponse
+		s.get("/api/v1/threads/"+id.String(), caller{}, &resp)
+		return resp
+	}
+
+	opID := getThread(first.ID).PosterID
+	if opID == nil {
+		t.Fatal("expected a poster ID on /b/")
+	}
+	if other := getThread(second.ID).PosterID; other == nil || *other == *opID {
+		t.Errorf("expected another poster ID in another thread, got %v", other)
+	}
+	if id := getThread(plain.ID).PosterID; id != nil {
+		t.Errorf("expected no poster ID on /g/, got %s", *id)
+	}
+
+	var page commentPageResponse
+	s.get("/api/v1/threads/"+first.ID.String()+"/comments", caller{}, &page)
+	if len(page.Items) != 2 {
+		t.Fatalf("expected 2 comments, got %+v", page.Items)
+	}
+	if id := page.Items[0].PosterID; id == nil || *id != *opID {
+		t.Errorf("expected the author's reply to show %s, got %v", *opID, id)
+	}
+	if id := page.Items[1].PosterID; id == nil || *id == *opID {
+		t.Errorf("expected another poster to show another ID, got %v", id)
+	}
+}
+
+func TestPosts_MarkOwnAndRepliesToYou(t *testing.T) {
+	s := newTestServer(t)
+	op := s.newSession("Rick")
+	stranger := newTestSessionID(t)
+	th := s.createThread("b", "title", op.ID)
+	s.reply(th.ID, nil, "bump", op.ID)
+	s.reply(th.ID, nil, ">>"+th.ID.String()+" nice", stranger)
+	s.reply(th.ID, nil, "unrelated", stranger)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := s.serve("GET", target, caller{sess: op}, testRequest{}, 200)
+		if strings.Contains(rec.Body.String(), "session_id") {
+			t.Errorf("GET %s leaks session IDs: %s", target, rec.Body)
+		}
+		return rec
+	}
+
+	thread := decode[threadResponse](t, serve("/api/v1/threads/"+th.ID.String()))
+	if !thread.IsOwn {
+		t.Error("expected the thread to be marked as the viewer's own")
+	}
+
+	page := decode[commentPageResponse](t, serve("/api/v1/threads/"+th.ID.String()+"/comments"))
+	if len(page.Items) != 3 {
+		t.Fatalf("expected 3 comments, got %+v", page.Items)
+	}
+	want := []struct{ own, reply bool }{{true, false}, {false, true}, {false, false}}
+	for i, c := range page.Items {
+		if c.IsOwn != want[i].own || c.RepliesToYou != want[i].reply {
+			t.Errorf("comment %d %q: got is_own=%v replies_to_you=%v, want %v %v", i, c.Content, c.IsOwn, c.RepliesToYou, want[i].own, want[i].reply)
+		}
+	}
+}
+
+func TestThreads_Moderation(t *testing.T) {
+	s := newTestServer(t)
+	sess := s.newSession("Rick")
+	th := s.createThread("b", "title", sess.ID)
+	other := s.createThread("b", "other", sess.ID)
+	jan := caller{cookie: s.moderator("jan", moderator.RoleJanitor)}
+	mod := caller{cookie: s.moderator("mod", moderator.RoleModerator)}
+	path := "/mod/threads/" + th.ID.String()
+
+	s.serve("POST", path+"/pin", caller{sess: sess}, testRequest{}, 401)
+	s.serve("POST", path+"/pin", jan, testRequest{}, 403)
+	s.serve("POST", "/mod/threads/nope/pin", mod, testRequest{}, 400)
+	s.serve("POST", "/mod/threads/"+newTestSessionID(t).String()+"/pin", mod, testRequest{}, 404)
+
+	if pinned := decode[threadResponse](t, s.serve("POST", path+"/pin", mod, testRequest{}, 200)); !pinned.IsPinned {
+		t.Errorf("expected a pinned thread, got %+v", pinned)
+	}
+	var page threadPageResponse
+	s.get("/api/v1/threads", caller{}, &page)
+	if len(page.Items) != 2 || page.Items[0].ID != th.ID.String() {
+		t.Errorf("expected the pinned thread first, got %+v", page.Items)
+	}
+
+	locked := decode[threadResponse](t, s.serve("POST", path+"/lock", mod, testRequest{}, 200))
+	if !locked.IsLocked || locked.State != "locked" {
+		t.Errorf("expected a locked thread, got %+v", locked)
+	}
+	s.serve("POST", path+"/lock", mod, testRequest{}, 409)
+	s.serve("POST", "/api/v1/threads/"+th.ID.String()+"/comments", caller{sess: sess}, formRequest(t, map[string]string{"content": "reply"}), 403)
+
+	s.serve("POST", path+"/unlock", mod, testRequest{}, 200)
+	s.serve("POST", path+"/unpin", mod, testRequest{}, 200)
+	s.serve("POST", "/api/v1/threads/"+th.ID.String()+"/comments", caller{sess: sess}, formRequest(t, map[string]string{"content": "reply"}), 201)
+
+	s.serve("DELETE", "/mod/threads/"+other.ID.String(), jan, testRequest{}, 403)
+	s.serve("DELETE", "/mod/threads/"+other.ID.String(), mod, testRequest{}, 204)
+	s.serve("GET", "/api/v1/threads/"+other.ID.String(), caller{}, testRequest{}, 404)
+	s.serve("DELETE", "/mod/threads/"+other.ID.String(), mod, testRequest{}, 404)
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/comment"
+	"net/http"
+)
+
+// viewer is the session a response is rendered for. Posts are marked as
+// its own or as replies to it, so nobody's session ID has to be sent.
+type viewer struct {
+	sessionID utils.UUID
+	// own are the viewer's posts in the thread being rendered. Only
+	// needed to mark replies.
+	own map[utils.UUID]bool
+}
+
+// viewerOf returns the viewer of r. Requests without a session get the
+// zero viewer, which owns nothing.
+func viewerOf(r *http.Request) viewer {
+	if sess, ok := GetSessionFromContext(r.Context()); ok {
+		return viewer{sessionID: sess.ID}
+	}
+	return viewer{}
+}
+
+// threadViewer is viewerOf with the viewer's posts in the thread loaded.
+// If they cannot be loaded the posts are still marked as own, just no
+// reply is marked.
+func threadViewer(r *http.Request, commentSvc *services.CommentService, threadID utils.UUID) viewer {
+	v := viewerOf(r)
+	if v.sessionID.IsZero() {
+		return v
+	}
+	own, err := commentSvc.PostsBy(r.Context(), threadID, v.sessionID)
+	if err != nil {
+		logger.Warn("cannot load the viewer's posts", "error", err, "thread_id", threadID)
+		return v
+	}
+	v.own = own
+	return v
+}
+
+func (v viewer) owns(sessionID utils.UUID) bool {
+	return !v.sessionID.IsZero() && sessionID == v.sessionID
+}
+
+// repliesTo reports whether someone else's comment quotes or answers one of
+// the viewer's posts.
+func (v viewer) repliesTo(c *comment.Comment) bool {
+	if v.owns(c.SessionID) {
+		return false
+	}
+	if c.ParentCommentID != nil && v.own[*c.ParentCommentID] {
+		return true
+	}
+	for _, id := range c.References {
+		if v.own[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a post the viewer wrote after its posts were loaded.
+func (v viewer) add(c *comment.Comment) {
+	if v.own != nil && v.owns(c.SessionID) {
+		v.own[c.ID] = true
+	}
+}
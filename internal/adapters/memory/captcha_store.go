@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/errors"
+	"context"
+	"sync"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// CaptchaStore keeps CAPTCHA challenges in process. A challenge made by one
+// instance cannot be answered on another; use the Postgres store there.
+type CaptchaStore struct {
+	mu         sync.Mutex
+	challenges map[uuidHelper.UUID]*captcha.Challenge
+}
+
+func NewCaptchaStore() *CaptchaStore {
+	return &CaptchaStore{challenges: make(map[uuidHelper.UUID]*captcha.Challenge)}
+}
+
+func (s *CaptchaStore) CreateChallenge(ctx context.Context, c *captcha.Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.challenges[c.ID] = c
+	return nil
+}
+
+func (s *CaptchaStore) GetChallenge(ctx context.Context, id uuidHelper.UUID, now time.Time) (*captcha.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.challenges[id]
+	if !ok || c.IsExpired(now) {
+		return nil, errors.ErrCaptchaNotFound
+	}
+	return c, nil
+}
+
+func (s *CaptchaStore) TakeChallenge(ctx context.Context, id uuidHelper.UUID) (*captcha.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.challenges[id]
+	if !ok {
+		return nil, errors.ErrCaptchaNotFound
+	}
+	delete(s.challenges, id)
+	return c, nil
+}
+
+func (s *CaptchaStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.challenges {
+		if c.IsExpired(now) {
+			delete(s.challenges, id)
+		}
+	}
+	return nil
+}
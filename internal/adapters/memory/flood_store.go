@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"sync"
+	"time"
+)
+
+// FloodStore keeps flood claims in process. It suits a single instance;
+// several instances behind a balancer need the Postgres store.
+type FloodStore struct {
+	mu     sync.Mutex
+	claims map[string]time.Time
+}
+
+func NewFloodStore() *FloodStore {
+	return &FloodStore{claims: make(map[string]time.Time)}
+}
+
+func (s *FloodStore) Acquire(ctx context.Context, claims []flood.Claim, now time.Time) (string, time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return "", time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var held string
+	var until time.Time
+	for _, c := range claims {
+		if expires, ok := s.claims[c.Key]; ok && expires.After(now) && expires.After(until) {
+			held, until = c.Key, expires
+		}
+	}
+	if held != "" {
+		return held, until, nil
+	}
+
+	for _, c := range claims {
+		s.claims[c.Key] = now.Add(c.TTL)
+	}
+	return "", time.Time{}, nil
+}
+
+func (s *FloodStore) DeleteExpired(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, expires := range s.claims {
+		if !expires.After(now) {
+			delete(s.claims, key)
+		}
+	}
+	return nil
+}
@@ -3,45 +3,229 @@ package postgres
 // thread repo
 const (
 	GetThreadByID = `
-		SELECT id, title, content, image_url, session_id, 
-		       created_at, last_commented, is_deleted
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
 		FROM threads
 		WHERE id = $1`
 
 	CreateThread = `
 		INSERT INTO threads (
 			id, title, content, image_url, session_id, 
-			created_at, last_commented, is_deleted
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+			created_at, last_commented, state, board_id, bumped_at,
+			is_pinned, archived_at, tripcode
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING post_number`
 
+	// bumped_at and the counters belong to trg_bump_thread and are left alone.
 	UpdateThread = `
 		UPDATE threads
 		SET title = $2, content = $3, image_url = $4, session_id = $5, 
-		    created_at = $6, last_commented = $7, is_deleted = $8
+		    created_at = $6, last_commented = $7, state = $8,
+		    is_pinned = $9, archived_at = $10, edited_at = $11
 		WHERE id = $1`
 
 	ListActiveThreads = `
-		SELECT id, title, content, image_url, session_id, 
-		       created_at, last_commented, is_deleted
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
 		FROM threads
-		WHERE is_deleted = FALSE`
+		WHERE state <> 'archived'
+		ORDER BY is_pinned DESC, bumped_at DESC, id DESC`
+
+	ListPinnedThreads = `
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
+		FROM threads
+		WHERE state <> 'archived' AND is_pinned = TRUE
+		  AND ($1::uuid IS NULL OR board_id = $1::uuid)
+		ORDER BY bumped_at DESC, id DESC`
 
 	ListAllThreads = `
-		SELECT id, title, content, image_url, session_id, 
-		       created_at, last_commented, is_deleted
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
 		FROM threads`
+
+	ListArchivedThreadsBefore = `
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
+		FROM threads
+		WHERE state = 'archived' AND archived_at < $1`
+
+	// DeleteThread removes a thread with its comments (by cascade) and
+	// returns the image URLs of those comments so their media can go too.
+	DeleteThread = `
+		WITH media AS (
+			SELECT unnest(image_url) AS url FROM comments WHERE thread_id = $1
+		), removed AS (
+			DELETE FROM threads WHERE id = $1 RETURNING id
+		)
+		SELECT url FROM media WHERE EXISTS (SELECT 1 FROM removed)`
+
+	// $1/$2 are the keyset cursor (NULL for the first page), $3 is the limit,
+	// $4 the board (NULL for every board). Active threads are keyed on
+	// bumped_at, the archive on created_at. Pinned threads are served apart
+	// by ListPinnedThreads.
+	ListActiveThreadsPage = `
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
+		FROM threads
+		WHERE state <> 'archived' AND is_pinned = FALSE
+		  AND ($4::uuid IS NULL OR board_id = $4::uuid)
+		  AND ($1::timestamp IS NULL OR (bumped_at, id) < ($1::timestamp, $2::uuid))
+		ORDER BY bumped_at DESC, id DESC
+		LIMIT $3`
+
+	ListAllThreadsPage = `
+		SELECT id, board_id, title, content, image_url, session_id, 
+		       created_at, last_commented, state, archived_at,
+		       is_pinned, bumped_at, bump_count, reply_count, edited_at, post_number, tripcode
+		FROM threads
+		WHERE ($4::uuid IS NULL OR board_id = $4::uuid)
+		  AND ($1::timestamp IS NULL OR (created_at, id) < ($1::timestamp, $2::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+
+	// GetPostByNumber finds the thread or the comment numbered $2 on board $1.
+	GetPostByNumber = `
+		SELECT post_number, id, NULL::uuid
+		FROM threads
+		WHERE board_id = $1 AND post_number = $2
+		UNION ALL
+		SELECT c.post_number, c.thread_id, c.id
+		FROM comments c
+		JOIN threads t ON t.id = c.thread_id
+		WHERE t.board_id = $1 AND c.post_number = $2
+		LIMIT 1`
+)
+
+// board repo
+const (
+	GetBoardBySlug = `
+		SELECT id, slug, title, description, max_threads, max_images, bump_limit, poster_ids, created_at
+		FROM boards
+		WHERE slug = $1`
+
+	GetBoardByID = `
+		SELECT id, slug, title, description, max_threads, max_images, bump_limit, poster_ids, created_at
+		FROM boards
+		WHERE id = $1`
+
+	ListBoards = `
+		SELECT id, slug, title, description, max_threads, max_images, bump_limit, poster_ids, created_at
+		FROM boards
+		ORDER BY slug`
 )
 
 // comment repo
 const (
 	CreateComment = `
-		INSERT INTO comments (id, thread_id, parent_comment_id, content, image_url, session_id, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		INSERT INTO comments (id, thread_id, parent_comment_id, content, image_url, session_id, created_at, is_sage, tripcode)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING post_number`
 
 	GetCommentsByThreadID = `
-		SELECT id, thread_id, parent_comment_id, content, image_url, session_id, created_at
+		SELECT id, thread_id, parent_comment_id, content, image_url, session_id, created_at, is_sage,
+		       is_deleted, edited_at, post_number, tripcode
 		FROM comments
-		WHERE thread_id = $1`
+		WHERE thread_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	GetCommentIDsBySession = `
+		SELECT id
+		FROM comments
+		WHERE thread_id = $1 AND session_id = $2`
+
+	GetCommentByID = `
+		SELECT id, thread_id, parent_comment_id, content, image_url, session_id, created_at, is_sage,
+		       is_deleted, edited_at, post_number, tripcode
+		FROM comments
+		WHERE id = $1`
+
+	UpdateComment = `
+		UPDATE comments
+		SET content = $2, is_deleted = $3, edited_at = $4
+		WHERE id = $1`
+
+	// GetCommentTree walks the replies down from the roots of a thread (or
+	// from one comment) in a single recursive query. child_count counts all
+	// direct replies, also those below the depth limit.
+	GetCommentTree = `
+		WITH RECURSIVE tree AS (
+			SELECT c.id, 0 AS depth
+			FROM comments c
+			WHERE c.thread_id = $1
+			  AND (($2::uuid IS NULL AND c.parent_comment_id IS NULL) OR c.id = $2::uuid)
+			UNION ALL
+			SELECT c.id, t.depth + 1
+			FROM tree t
+			JOIN comments c ON c.parent_comment_id = t.id AND c.thread_id = $1
+			WHERE t.depth < $3
+		)
+		SELECT c.id, c.thread_id, c.parent_comment_id, c.content, c.image_url, c.session_id, c.created_at, c.is_sage,
+		       c.is_deleted, c.edited_at, c.post_number, c.tripcode,
+		       t.depth,
+		       (SELECT COUNT(*) FROM comments ch WHERE ch.parent_comment_id = c.id) AS child_count
+		FROM tree t
+		JOIN comments c ON c.id = t.id
+		ORDER BY c.created_at ASC, c.id ASC`
+
+	GetCommentsByThreadIDPage = `
+		SELECT id, thread_id, parent_comment_id, content, image_url, session_id, created_at, is_sage,
+		       is_deleted, edited_at, post_number, tripcode
+		FROM comments
+		WHERE thread_id = $1
+		  AND ($2::timestamp IS NULL OR (created_at, id) > ($2::timestamp, $3::uuid))
+		ORDER BY created_at ASC, id ASC
+		LIMIT $4`
+)
+
+// reference repo
+const (
+	// SaveReferences replaces the links of a comment: links that are gone
+	// are removed and new ones added.
+	SaveReferences = `
+		WITH removed AS (
+			DELETE FROM comment_references
+			WHERE comment_id = $1::uuid AND NOT (target_id = ANY($3::uuid[]))
+		)
+		INSERT INTO comment_references (comment_id, thread_id, target_id)
+		SELECT $1::uuid, $2::uuid, unnest($3::uuid[])
+		ON CONFLICT DO NOTHING`
+
+	ListReferences = `
+		SELECT r.comment_id, r.target_id
+		FROM comment_references r
+		JOIN comments c ON c.id = r.comment_id
+		WHERE r.thread_id = $1
+		ORDER BY c.created_at, c.id`
+)
+
+// revision repo
+const (
+	CreateThreadRevision = `
+		INSERT INTO thread_revisions (id, thread_id, title, content, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	CreateCommentRevision = `
+		INSERT INTO comment_revisions (id, comment_id, content, created_at)
+		VALUES ($1, $2, $3, $4)`
+
+	ListThreadRevisions = `
+		SELECT id, thread_id, title, content, created_at
+		FROM thread_revisions
+		WHERE thread_id = $1
+		ORDER BY created_at, id`
+
+	ListCommentRevisions = `
+		SELECT id, comment_id, '', content, created_at
+		FROM comment_revisions
+		WHERE comment_id = $1
+		ORDER BY created_at, id`
 )
 
 // session repo
@@ -51,7 +235,7 @@ const (
 		VALUES ($1, $2, $3, $4, $5)`
 
 	GetSessionByID = `
-		SELECT id, avatar_url, display_name, created_at, expires_at
+		SELECT id, avatar_url, display_name, tripcode, created_at, expires_at, captchas_solved, ip_hash
 		FROM sessions
 		WHERE id = $1`
 
@@ -60,8 +244,261 @@ const (
 		WHERE expires_at < $1`
 
 	ListActiveSessions = `
-		SELECT id, avatar_url, display_name, created_at, expires_at
+		SELECT id, avatar_url, display_name, tripcode, created_at, expires_at, captchas_solved, ip_hash
 		FROM sessions`
 
-	UpdateDisplayName = `UPDATE sessions SET display_name = $1 WHERE id = $2`
+	UpdateDisplayName = `UPDATE sessions SET display_name = $1, tripcode = $2 WHERE id = $3`
+
+	// LockDisplayName serializes renames to the same name until the end of
+	// the transaction.
+	LockDisplayName = `SELECT pg_advisory_xact_lock(hashtext('display_name:' || lower($1)))`
+
+	// DisplayNameTaken tells whether another unexpired session has the
+	// name without a tripcode.
+	DisplayNameTaken = `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE lower(display_name) = lower($1) AND tripcode = ''
+			  AND id <> $2 AND expires_at > $3
+		)`
+
+	AddSolvedCaptcha = `UPDATE sessions SET captchas_solved = captchas_solved + 1 WHERE id = $1`
+
+	UpdateSessionIPHash = `UPDATE sessions SET ip_hash = $1 WHERE id = $2`
+)
+
+// flood repo
+const (
+	// LockFloodKeys serializes posts that share a key until the end of the
+	// transaction. The keys come sorted, so two posts cannot deadlock.
+	LockFloodKeys = `
+		SELECT pg_advisory_xact_lock(hashtext(key))
+		FROM unnest($1::text[]) WITH ORDINALITY AS k(key, n)
+		ORDER BY n`
+
+	// GetHeldFloodClaim returns the held claim that frees up last.
+	GetHeldFloodClaim = `
+		SELECT key, expires_at
+		FROM flood_claims
+		WHERE key = ANY($1) AND expires_at > $2
+		ORDER BY expires_at DESC
+		LIMIT 1`
+
+	PutFloodClaim = `
+		INSERT INTO flood_claims (key, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at`
+
+	DeleteExpiredFloodClaims = `
+		DELETE FROM flood_claims
+		WHERE expires_at <= $1`
+)
+
+// captcha repo
+const (
+	CreateCaptchaChallenge = `
+		INSERT INTO captcha_challenges (id, answer, expires_at)
+		VALUES ($1, $2, $3)`
+
+	GetCaptchaChallenge = `
+		SELECT id, answer, expires_at
+		FROM captcha_challenges
+		WHERE id = $1 AND expires_at > $2`
+
+	// TakeCaptchaChallenge deletes the challenge as it reads it, so two
+	// requests cannot both use one answer.
+	TakeCaptchaChallenge = `
+		DELETE FROM captcha_challenges
+		WHERE id = $1
+		RETURNING id, answer, expires_at`
+
+	DeleteExpiredCaptchaChallenges = `
+		DELETE FROM captcha_challenges
+		WHERE expires_at <= $1`
+)
+
+// moderator repo
+const (
+	CreateModerator = `
+		INSERT INTO moderators (id, username, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username) DO NOTHING`
+
+	GetModeratorByID = `
+		SELECT id, username, password_hash, role, created_at
+		FROM moderators
+		WHERE id = $1`
+
+	GetModeratorByUsername = `
+		SELECT id, username, password_hash, role, created_at
+		FROM moderators
+		WHERE username = $1`
+
+	ListModerators = `
+		SELECT id, username, password_hash, role, created_at
+		FROM moderators
+		ORDER BY username`
+
+	CountModerators = `
+		SELECT COUNT(*) FROM moderators`
+
+	CreateModeratorLogin = `
+		INSERT INTO moderator_logins (token_hash, moderator_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)`
+
+	GetModeratorLogin = `
+		SELECT token_hash, moderator_id, created_at, expires_at
+		FROM moderator_logins
+		WHERE token_hash = $1 AND expires_at > $2`
+
+	DeleteModeratorLogin = `
+		DELETE FROM moderator_logins
+		WHERE token_hash = $1`
+
+	DeleteExpiredModeratorLogins = `
+		DELETE FROM moderator_logins
+		WHERE expires_at <= $1`
+)
+
+// report repo
+const (
+	// LockReportedPost serializes the reports on one post until the end of
+	// the transaction, so they all land in the same open case.
+	LockReportedPost = `
+		SELECT pg_advisory_xact_lock(hashtext('report:' || $1))`
+
+	GetOpenReportCase = `
+		SELECT c.id, c.thread_id, c.comment_id, c.post_number, c.author_session_id,
+		       c.score, c.report_count,
+		       (SELECT COALESCE(json_object_agg(reason, n), '{}')
+		        FROM (SELECT reason, COUNT(*) AS n FROM reports WHERE case_id = c.id GROUP BY reason) r),
+		       c.opened_at, c.last_reported_at, c.claimed_by, c.claimed_at,
+		       c.resolution, c.resolved_by, c.resolved_at
+		FROM report_cases c
+		WHERE c.post_id = $1 AND c.resolution IS NULL`
+
+	CreateReportCase = `
+		INSERT INTO report_cases (id, post_id, thread_id, comment_id, post_number, author_session_id, opened_at, last_reported_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	// CreateReport leaves a second report of a session on a post out.
+	CreateReport = `
+		INSERT INTO reports (id, case_id, post_id, session_id, reason, text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (post_id, session_id) DO NOTHING`
+
+	UpdateReportCaseScore = `
+		UPDATE report_cases
+		SET score = $2, report_count = $3, last_reported_at = $4
+		WHERE id = $1`
+
+	ListOpenReportCases = `
+		SELECT c.id, c.thread_id, c.comment_id, c.post_number, c.author_session_id,
+		       c.score, c.report_count,
+		       (SELECT COALESCE(json_object_agg(reason, n), '{}')
+		        FROM (SELECT reason, COUNT(*) AS n FROM reports WHERE case_id = c.id GROUP BY reason) r),
+		       c.opened_at, c.last_reported_at, c.claimed_by, c.claimed_at,
+		       c.resolution, c.resolved_by, c.resolved_at
+		FROM report_cases c
+		WHERE c.resolution IS NULL
+		ORDER BY c.score DESC, c.last_reported_at DESC
+		LIMIT $1`
+
+	GetReportCase = `
+		SELECT c.id, c.thread_id, c.comment_id, c.post_number, c.author_session_id,
+		       c.score, c.report_count,
+		       (SELECT COALESCE(json_object_agg(reason, n), '{}')
+		        FROM (SELECT reason, COUNT(*) AS n FROM reports WHERE case_id = c.id GROUP BY reason) r),
+		       c.opened_at, c.last_reported_at, c.claimed_by, c.claimed_at,
+		       c.resolution, c.resolved_by, c.resolved_at
+		FROM report_cases c
+		WHERE c.id = $1`
+
+	ListReports = `
+		SELECT id, case_id, post_id, session_id, reason, text, created_at
+		FROM reports
+		WHERE case_id = $1
+		ORDER BY created_at, id`
+
+	// ClaimReportCase takes over claims older than $4.
+	ClaimReportCase = `
+		UPDATE report_cases
+		SET claimed_by = $2, claimed_at = $3
+		WHERE id = $1 AND resolution IS NULL
+		  AND (claimed_by IS NULL OR claimed_by = $2 OR claimed_at < $4)`
+
+	ResolveReportCase = `
+		UPDATE report_cases
+		SET resolution = $2, resolved_by = $3, resolved_at = $4
+		WHERE id = $1 AND resolution IS NULL AND claimed_by = $3`
+)
+
+// ban repo
+const (
+	CreateBan = `
+		INSERT INTO bans (id, session_id, ip_hash, board_id, reason, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	// FindBans matches an empty address to no ban.
+	FindBans = `
+		SELECT b.id, b.session_id, b.ip_hash, b.board_id, COALESCE(bo.slug, ''),
+		       b.reason, b.created_by, b.created_at, b.expires_at
+		FROM bans b
+		LEFT JOIN boards bo ON bo.id = b.board_id
+		WHERE (b.session_id = $1 OR ($2 <> '' AND b.ip_hash = $2))
+		  AND (b.expires_at IS NULL OR b.expires_at > $3)
+		ORDER BY b.expires_at DESC NULLS FIRST`
+
+	ListActiveBans = `
+		SELECT b.id, b.session_id, b.ip_hash, b.board_id, COALESCE(bo.slug, ''),
+		       b.reason, b.created_by, b.created_at, b.expires_at
+		FROM bans b
+		LEFT JOIN boards bo ON bo.id = b.board_id
+		WHERE b.expires_at IS NULL OR b.expires_at > $1
+		ORDER BY b.created_at DESC`
+
+	DeleteBan = `DELETE FROM bans WHERE id = $1`
+
+	DeleteExpiredBans = `DELETE FROM bans WHERE expires_at <= $1`
+)
+
+// search repo
+const (
+	// $1 is the websearch-style query, $2 the status filter (all, active,
+	// archived), $3/$4 the limit and offset, $5 the ts_headline options.
+	// Headlines are only built for the rows of the requested page, from
+	// text without the highlight delimiters (chr 2 and 3), so that only
+	// ts_headline's own mark anything.
+	Search = `
+		WITH q AS (
+			SELECT websearch_to_tsquery('simple', $1) AS query
+		),
+		hits AS (
+			SELECT 'thread' AS kind, t.id AS thread_id, NULL::uuid AS comment_id, t.title,
+			       t.content AS body, ts_rank(t.search_vector, q.query) AS rank,
+			       t.created_at, t.state = 'archived' AS is_archived
+			FROM threads t CROSS JOIN q
+			WHERE t.search_vector @@ q.query
+			  AND ($2::text = 'all' OR (t.state = 'archived') = ($2::text = 'archived'))
+			UNION ALL
+			SELECT 'comment', c.thread_id, c.id, t.title,
+			       c.content, ts_rank(c.search_vector, q.query),
+			       c.created_at, t.state = 'archived'
+			FROM comments c
+			JOIN threads t ON t.id = c.thread_id
+			CROSS JOIN q
+			WHERE c.search_vector @@ q.query
+			  AND c.is_deleted = FALSE
+			  AND ($2::text = 'all' OR (t.state = 'archived') = ($2::text = 'archived'))
+		),
+		page AS (
+			SELECT * FROM hits
+			ORDER BY rank DESC, created_at DESC, COALESCE(comment_id, thread_id) DESC
+			LIMIT $3 OFFSET $4
+		)
+		SELECT p.kind, p.thread_id, p.comment_id, p.title,
+		       ts_headline('simple', translate(p.body, chr(2) || chr(3), ''), q.query, $5),
+		       p.rank, p.created_at, p.is_archived
+		FROM page p CROSS JOIN q
+		ORDER BY p.rank DESC, p.created_at DESC, COALESCE(p.comment_id, p.thread_id) DESC`
 )
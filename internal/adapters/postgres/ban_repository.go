@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/errors"
+	"context"
+	"database/sql"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type BanRepository struct {
+	db *sql.DB
+}
+
+func NewBanRepository(db *sql.DB) *BanRepository {
+	return &BanRepository{db: db}
+}
+
+func (r *BanRepository) CreateBan(ctx context.Context, b *ban.Ban) error {
+	var expiresAt any
+	if b.ExpiresAt != nil {
+		expiresAt = *b.ExpiresAt
+	}
+	_, err := r.db.ExecContext(ctx, CreateBan,
+		b.ID.String(), nilIfNilUUID(b.SessionID), b.IPHash, nilIfNilUUID(b.BoardID),
+		b.Reason, nilIfNilUUID(b.CreatedBy), b.CreatedAt, expiresAt,
+	)
+	if err != nil {
+		logger.Error("failed to insert ban", "error", err, "ban_id", b.ID)
+	}
+	return err
+}
+
+func (r *BanRepository) FindBans(ctx context.Context, sessionID uuidHelper.UUID, ipHash string, now time.Time) ([]*ban.Ban, error) {
+	return r.list(ctx, FindBans, sessionID.String(), ipHash, now)
+}
+
+func (r *BanRepository) ListActiveBans(ctx context.Context, now time.Time) ([]*ban.Ban, error) {
+	return r.list(ctx, ListActiveBans, now)
+}
+
+func (r *BanRepository) DeleteBan(ctx context.Context, id uuidHelper.UUID) error {
+	res, err := r.db.ExecContext(ctx, DeleteBan, id.String())
+	if err != nil {
+		logger.Error("failed to delete ban", "error", err, "ban_id", id)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrBanNotFound
+	}
+	return nil
+}
+
+func (r *BanRepository) DeleteExpiredBans(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, DeleteExpiredBans, now)
+	if err != nil {
+		logger.Error("failed to delete expired bans", "error", err)
+	}
+	return err
+}
+
+func (r *BanRepository) list(ctx context.Context, query string, args ...any) ([]*ban.Ban, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("failed to list bans", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []*ban.Ban
+	for rows.Next() {
+		var b ban.Ban
+		var id string
+		var sessionID, boardID, createdBy sql.NullString
+		var expiresAt sql.NullTime
+		err := rows.Scan(&id, &sessionID, &b.IPHash, &boardID, &b.BoardSlug,
+			&b.Reason, &createdBy, &b.CreatedAt, &expiresAt,
+		)
+		if err != nil {
+			logger.Error("failed to scan ban", "error", err)
+			return nil, err
+		}
+
+		if b.ID, err = uuidHelper.ParseUUID(id); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			b.ExpiresAt = &expiresAt.Time
+		}
+		for _, f := range []struct {
+			dst **uuidHelper.UUID
+			src sql.NullString
+		}{{&b.SessionID, sessionID}, {&b.BoardID, boardID}, {&b.CreatedBy, createdBy}} {
+			if !f.src.Valid {
+				continue
+			}
+			u, err := uuidHelper.ParseUUID(f.src.String)
+			if err != nil {
+				logger.Error("invalid UUID format for ban", "value", f.src.String, "error", err)
+				return nil, err
+			}
+			*f.dst = &u
+		}
+		bans = append(bans, &b)
+	}
+	return bans, rows.Err()
+}
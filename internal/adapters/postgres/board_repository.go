@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/errors"
+	"context"
+	"database/sql"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type BoardRepository struct {
+	db *sql.DB
+}
+
+func NewBoardRepository(db *sql.DB) *BoardRepository {
+	return &BoardRepository{db: db}
+}
+
+func (r *BoardRepository) GetBoardBySlug(ctx context.Context, slug string) (*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting board by slug", "error", err, "slug", slug)
+		return nil, err
+	}
+
+	b, err := scanBoard(r.db.QueryRowContext(ctx, GetBoardBySlug, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrBoardNotFound
+	}
+	if err != nil {
+		logger.Error("failed to scan board row", "error", err, "slug", slug)
+	}
+	return b, err
+}
+
+func (r *BoardRepository) GetBoardByID(ctx context.Context, id uuidHelper.UUID) (*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting board by ID", "error", err, "board_id", id)
+		return nil, err
+	}
+
+	b, err := scanBoard(r.db.QueryRowContext(ctx, GetBoardByID, id.String()))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrBoardNotFound
+	}
+	if err != nil {
+		logger.Error("failed to scan board row", "error", err, "board_id", id)
+	}
+	return b, err
+}
+
+func (r *BoardRepository) ListBoards(ctx context.Context) ([]*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing boards", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, ListBoards)
+	if err != nil {
+		logger.Error("failed to execute list boards query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var boards []*board.Board
+	for rows.Next() {
+		b, err := scanBoard(rows)
+		if err != nil {
+			logger.Error("failed to scan board", "error", err)
+			return nil, err
+		}
+		boards = append(boards, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for boards", "error", err)
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+func scanBoard(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*board.Board, error) {
+	b := &board.Board{}
+	var idStr string
+
+	err := scanner.Scan(
+		&idStr,
+		&b.Slug,
+		&b.Title,
+		&b.Description,
+		&b.MaxThreads,
+		&b.MaxImages,
+		&b.BumpLimit,
+		&b.PosterIDs,
+		&b.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	b.ID, err = uuidHelper.ParseUUID(idStr)
+	if err != nil {
+		logger.Error("invalid UUID format for board ID", "value", idStr, "error", err)
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/errors"
+	"context"
+	"database/sql"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type CaptchaRepository struct {
+	db *sql.DB
+}
+
+func NewCaptchaRepository(db *sql.DB) *CaptchaRepository {
+	return &CaptchaRepository{db: db}
+}
+
+func (r *CaptchaRepository) CreateChallenge(ctx context.Context, c *captcha.Challenge) error {
+	_, err := r.db.ExecContext(ctx, CreateCaptchaChallenge, c.ID.String(), c.Answer, c.ExpiresAt)
+	if err != nil {
+		logger.Error("failed to create captcha challenge", "error", err, "id", c.ID)
+	}
+	return err
+}
+
+func (r *CaptchaRepository) GetChallenge(ctx context.Context, id uuidHelper.UUID, now time.Time) (*captcha.Challenge, error) {
+	return r.scanChallenge(r.db.QueryRowContext(ctx, GetCaptchaChallenge, id.String(), now), id)
+}
+
+func (r *CaptchaRepository) TakeChallenge(ctx context.Context, id uuidHelper.UUID) (*captcha.Challenge, error) {
+	return r.scanChallenge(r.db.QueryRowContext(ctx, TakeCaptchaChallenge, id.String()), id)
+}
+
+func (r *CaptchaRepository) scanChallenge(row *sql.Row, id uuidHelper.UUID) (*captcha.Challenge, error) {
+	var c captcha.Challenge
+	var idStr string
+	if err := row.Scan(&idStr, &c.Answer, &c.ExpiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrCaptchaNotFound
+		}
+		logger.Error("failed to scan captcha challenge", "error", err, "id", id)
+		return nil, err
+	}
+
+	var err error
+	if c.ID, err = uuidHelper.ParseUUID(idStr); err != nil {
+		logger.Error("invalid UUID format for captcha challenge", "value", idStr, "error", err)
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *CaptchaRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, DeleteExpiredCaptchaChallenges, now)
+	if err != nil {
+		logger.Error("failed to delete expired captcha challenges", "error", err)
+	}
+	return err
+}
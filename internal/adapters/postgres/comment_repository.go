@@ -2,8 +2,10 @@ package postgres
 
 import (
 	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
 	"1337b04rd/internal/app/common/utils"
 	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/errors"
 	"context"
 	"database/sql"
 
@@ -26,7 +28,7 @@ func (r *CommentRepository) CreateComment(ctx context.Context, c *comment.Commen
 		return err
 	}
 
-	_, err := r.db.ExecContext(ctx, CreateComment,
+	err := r.db.QueryRowContext(ctx, CreateComment,
 		c.ID.String(),
 		c.ThreadID.String(),
 		nilIfNilUUID(c.ParentCommentID),
@@ -34,7 +36,9 @@ func (r *CommentRepository) CreateComment(ctx context.Context, c *comment.Commen
 		pq.Array(c.ImageURLs),
 		c.SessionID.String(),
 		c.CreatedAt,
-	)
+		c.IsSage,
+		c.Tripcode,
+	).Scan(&c.PostNumber)
 	if err != nil {
 		logger.Error("failed to create comment", "error", err, "comment_id", c.ID)
 		return err
@@ -72,15 +76,145 @@ func (r *CommentRepository) GetCommentsByThreadID(ctx context.Context, threadID
 	return comments, nil
 }
 
+func (r *CommentRepository) GetCommentIDsBySession(ctx context.Context, threadID, sessionID utils.UUID) ([]utils.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting own comments", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, GetCommentIDsBySession, threadID.String(), sessionID.String())
+	if err != nil {
+		logger.Error("failed to query own comments", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []utils.UUID
+	for rows.Next() {
+		var idStr string
+		if err := rows.Scan(&idStr); err != nil {
+			logger.Error("failed to scan comment id", "error", err, "thread_id", threadID.String())
+			return nil, err
+		}
+		id, err := utils.ParseUUID(idStr)
+		if err != nil {
+			logger.Error("invalid UUID format for comment ID", "value", idStr, "error", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error in own comment rows", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id utils.UUID) (*comment.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting comment", "error", err, "comment_id", id.String())
+		return nil, err
+	}
+
+	c, err := scanComment(r.db.QueryRowContext(ctx, GetCommentByID, id.String()))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrCommentNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get comment", "error", err, "comment_id", id.String())
+	}
+	return c, err
+}
+
+func (r *CommentRepository) UpdateComment(ctx context.Context, c *comment.Comment) error {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while updating comment", "error", err, "comment_id", c.ID)
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, UpdateComment, c.ID.String(), c.Content, c.IsDeleted, c.EditedAt)
+	if err != nil {
+		logger.Error("failed to update comment", "error", err, "comment_id", c.ID)
+	}
+	return err
+}
+
+func (r *CommentRepository) GetCommentTree(ctx context.Context, threadID utils.UUID, rootID *utils.UUID, maxDepth int) ([]*comment.Node, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting comment tree", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, GetCommentTree, threadID.String(), nilIfNilUUID(rootID), maxDepth)
+	if err != nil {
+		logger.Error("failed to query comment tree", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*comment.Node
+	for rows.Next() {
+		n := &comment.Node{}
+		c, err := scanComment(rows, &n.Depth, &n.ChildCount)
+		if err != nil {
+			logger.Error("failed to scan comment tree node", "error", err, "thread_id", threadID.String())
+			return nil, err
+		}
+		n.Comment = c
+		nodes = append(nodes, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error in comment tree rows", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (r *CommentRepository) GetCommentsByThreadIDPage(ctx context.Context, threadID utils.UUID, after *pagination.Cursor, limit int) ([]*comment.Comment, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting comments page", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+
+	afterTime, afterID := cursorArgs(after)
+	rows, err := r.db.QueryContext(ctx, GetCommentsByThreadIDPage, threadID.String(), afterTime, afterID, limit)
+	if err != nil {
+		logger.Error("failed to query comments page", "error", err, "thread_id", threadID.String())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*comment.Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			logger.Error("failed to scan comment", "error", err, "thread_id", threadID.String())
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error in comment rows", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	return comments, nil
+}
+
+// scanComment reads the comment columns of a row, followed by any extra
+// columns the query selects.
 func scanComment(scanner interface {
 	Scan(dest ...interface{}) error
-}) (*comment.Comment, error) {
+}, extra ...interface{}) (*comment.Comment, error) {
 	c := &comment.Comment{}
 	var idStr, threadIDStr, sessionIDStr string
 	var parentID sql.NullString
 	var imageURLs pq.StringArray
+	var editedAt sql.NullTime
 
-	err := scanner.Scan(
+	dest := []interface{}{
 		&idStr,
 		&threadIDStr,
 		&parentID,
@@ -88,7 +222,13 @@ func scanComment(scanner interface {
 		&imageURLs,
 		&sessionIDStr,
 		&c.CreatedAt,
-	)
+		&c.IsSage,
+		&c.IsDeleted,
+		&editedAt,
+		&c.PostNumber,
+		&c.Tripcode,
+	}
+	err := scanner.Scan(append(dest, extra...)...)
 	if err != nil {
 		logger.Error("failed to scan comment row", "error", err)
 		return nil, err
@@ -121,6 +261,10 @@ func scanComment(scanner interface {
 		c.ParentCommentID = &parsedID
 	}
 
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+
 	c.ImageURLs = []string(imageURLs)
 	return c, nil
 }
@@ -131,3 +275,12 @@ func nilIfNilUUID(u *utils.UUID) interface{} {
 	}
 	return u.String()
 }
+
+// cursorArgs turns a keyset cursor into query arguments; a nil cursor
+// becomes two NULLs so the "first page" branch of the query applies.
+func cursorArgs(after *pagination.Cursor) (interface{}, interface{}) {
+	if after == nil {
+		return nil, nil
+	}
+	return after.Time, after.ID.String()
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// FloodRepository keeps flood claims in Postgres, so every instance of the
+// app sees the same posting history.
+type FloodRepository struct {
+	db *sql.DB
+}
+
+func NewFloodRepository(db *sql.DB) *FloodRepository {
+	return &FloodRepository{db: db}
+}
+
+func (r *FloodRepository) Acquire(ctx context.Context, claims []flood.Claim, now time.Time) (string, time.Time, error) {
+	keys := make([]string, len(claims))
+	for i, c := range claims {
+		keys[i] = c.Key
+	}
+	sort.Strings(keys)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin flood transaction", "error", err)
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, LockFloodKeys, pq.Array(keys)); err != nil {
+		logger.Error("failed to lock flood keys", "error", err)
+		return "", time.Time{}, err
+	}
+
+	var held string
+	var until time.Time
+	err = tx.QueryRowContext(ctx, GetHeldFloodClaim, pq.Array(keys), now).Scan(&held, &until)
+	switch {
+	case err == nil:
+		return held, until, nil
+	case err != sql.ErrNoRows:
+		logger.Error("failed to read flood claims", "error", err)
+		return "", time.Time{}, err
+	}
+
+	for _, c := range claims {
+		if _, err := tx.ExecContext(ctx, PutFloodClaim, c.Key, now.Add(c.TTL)); err != nil {
+			logger.Error("failed to save flood claim", "error", err, "key", c.Key)
+			return "", time.Time{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit flood claims", "error", err)
+		return "", time.Time{}, err
+	}
+	return "", time.Time{}, nil
+}
+
+func (r *FloodRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, DeleteExpiredFloodClaims, now)
+	if err != nil {
+		logger.Error("failed to delete expired flood claims", "error", err)
+	}
+	return err
+}
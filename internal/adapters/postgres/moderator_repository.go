@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"database/sql"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type ModeratorRepository struct {
+	db *sql.DB
+}
+
+func NewModeratorRepository(db *sql.DB) *ModeratorRepository {
+	return &ModeratorRepository{db: db}
+}
+
+func (r *ModeratorRepository) CreateModerator(ctx context.Context, m *moderator.Moderator) error {
+	res, err := r.db.ExecContext(ctx, CreateModerator, m.ID.String(), m.Username, m.PasswordHash, string(m.Role), m.CreatedAt)
+	if err != nil {
+		logger.Error("failed to insert moderator", "error", err, "username", m.Username)
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.ErrModeratorExists
+	}
+	return nil
+}
+
+func (r *ModeratorRepository) GetModeratorByID(ctx context.Context, id uuidHelper.UUID) (*moderator.Moderator, error) {
+	return r.scanModerator(r.db.QueryRowContext(ctx, GetModeratorByID, id.String()))
+}
+
+func (r *ModeratorRepository) GetModeratorByUsername(ctx context.Context, username string) (*moderator.Moderator, error) {
+	return r.scanModerator(r.db.QueryRowContext(ctx, GetModeratorByUsername, username))
+}
+
+func (r *ModeratorRepository) ListModerators(ctx context.Context) ([]*moderator.Moderator, error) {
+	rows, err := r.db.QueryContext(ctx, ListModerators)
+	if err != nil {
+		logger.Error("failed to list moderators", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []*moderator.Moderator
+	for rows.Next() {
+		m, err := r.scanModerator(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+func (r *ModeratorRepository) CountModerators(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, CountModerators).Scan(&n); err != nil {
+		logger.Error("failed to count moderators", "error", err)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *ModeratorRepository) scanModerator(row interface{ Scan(...any) error }) (*moderator.Moderator, error) {
+	var m moderator.Moderator
+	var idStr, role string
+	if err := row.Scan(&idStr, &m.Username, &m.PasswordHash, &role, &m.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrModeratorNotFound
+		}
+		logger.Error("failed to scan moderator", "error", err)
+		return nil, err
+	}
+	m.Role = moderator.Role(role)
+
+	var err error
+	if m.ID, err = uuidHelper.ParseUUID(idStr); err != nil {
+		logger.Error("invalid UUID format for moderator", "value", idStr, "error", err)
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *ModeratorRepository) CreateLogin(ctx context.Context, l *moderator.Login) error {
+	_, err := r.db.ExecContext(ctx, CreateModeratorLogin, l.TokenHash, l.ModeratorID.String(), l.CreatedAt, l.ExpiresAt)
+	if err != nil {
+		logger.Error("failed to insert moderator login", "error", err, "moderator_id", l.ModeratorID)
+	}
+	return err
+}
+
+func (r *ModeratorRepository) GetLogin(ctx context.Context, tokenHash string, now time.Time) (*moderator.Login, error) {
+	var l moderator.Login
+	var idStr string
+	err := r.db.QueryRowContext(ctx, GetModeratorLogin, tokenHash, now).Scan(&l.TokenHash, &idStr, &l.CreatedAt, &l.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrLoginRequired
+		}
+		logger.Error("failed to scan moderator login", "error", err)
+		return nil, err
+	}
+
+	if l.ModeratorID, err = uuidHelper.ParseUUID(idStr); err != nil {
+		logger.Error("invalid UUID format for moderator login", "value", idStr, "error", err)
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *ModeratorRepository) DeleteLogin(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, DeleteModeratorLogin, tokenHash)
+	if err != nil {
+		logger.Error("failed to delete moderator login", "error", err)
+	}
+	return err
+}
+
+func (r *ModeratorRepository) DeleteExpiredLogins(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, DeleteExpiredModeratorLogins, now)
+	if err != nil {
+		logger.Error("failed to delete expired moderator logins", "error", err)
+	}
+	return err
+}
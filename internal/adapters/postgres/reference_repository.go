@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/comment"
+	"context"
+	"database/sql"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+
+	"github.com/lib/pq"
+)
+
+type ReferenceRepository struct {
+	db *sql.DB
+}
+
+func NewReferenceRepository(db *sql.DB) *ReferenceRepository {
+	return &ReferenceRepository{db: db}
+}
+
+func (r *ReferenceRepository) SaveReferences(ctx context.Context, c *comment.Comment) error {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while saving references", "error", err, "comment_id", c.ID)
+		return err
+	}
+
+	targets := make([]string, 0, len(c.References))
+	for _, id := range c.References {
+		targets = append(targets, id.String())
+	}
+
+	_, err := r.db.ExecContext(ctx, SaveReferences, c.ID.String(), c.ThreadID.String(), pq.Array(targets))
+	if err != nil {
+		logger.Error("failed to save references", "error", err, "comment_id", c.ID)
+	}
+	return err
+}
+
+func (r *ReferenceRepository) ListReferences(ctx context.Context, threadID uuidHelper.UUID) ([]*comment.Reference, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing references", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, ListReferences, threadID.String())
+	if err != nil {
+		logger.Error("failed to execute list references query", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []*comment.Reference
+	for rows.Next() {
+		var commentIDStr, targetIDStr string
+		if err := rows.Scan(&commentIDStr, &targetIDStr); err != nil {
+			logger.Error("failed to scan reference", "error", err, "thread_id", threadID)
+			return nil, err
+		}
+		ref := &comment.Reference{}
+		if ref.CommentID, err = uuidHelper.ParseUUID(commentIDStr); err != nil {
+			logger.Error("invalid UUID format for reference comment id", "value", commentIDStr, "error", err)
+			return nil, err
+		}
+		if ref.TargetID, err = uuidHelper.ParseUUID(targetIDStr); err != nil {
+			logger.Error("invalid UUID format for reference target id", "value", targetIDStr, "error", err)
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for references", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+	return refs, nil
+}
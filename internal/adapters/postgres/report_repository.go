@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/report"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) AddReport(ctx context.Context, c *report.Case, rep *report.Report) (*report.Case, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("failed to begin report transaction", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	postID := rep.PostID.String()
+	if _, err := tx.ExecContext(ctx, LockReportedPost, postID); err != nil {
+		logger.Error("failed to lock reported post", "error", err, "post_id", postID)
+		return nil, err
+	}
+
+	open, err := scanReportCase(tx.QueryRowContext(ctx, GetOpenReportCase, postID))
+	switch {
+	case err == nil:
+		c = open
+	case err == errors.ErrReportNotFound:
+		_, err = tx.ExecContext(ctx, CreateReportCase,
+			c.ID.String(), postID, c.Post.ThreadID.String(), nilIfNilUUID(c.Post.CommentID),
+			c.Post.Number, c.AuthorSessionID.String(), c.OpenedAt, c.LastReportedAt,
+		)
+		if err != nil {
+			logger.Error("failed to open report case", "error", err, "post_id", postID)
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	c.Add(rep)
+	res, err := tx.ExecContext(ctx, CreateReport,
+		rep.ID.String(), rep.CaseID.String(), postID, rep.SessionID.String(), string(rep.Reason), rep.Text, rep.CreatedAt,
+	)
+	if err != nil {
+		logger.Error("failed to insert report", "error", err, "post_id", postID)
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, errors.ErrAlreadyReported
+	}
+
+	if _, err := tx.ExecContext(ctx, UpdateReportCaseScore, c.ID.String(), c.Score, c.ReportCount, c.LastReportedAt); err != nil {
+		logger.Error("failed to update report case", "error", err, "case_id", c.ID)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("failed to commit report", "error", err)
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *ReportRepository) ListOpenCases(ctx context.Context, limit int) ([]*report.Case, error) {
+	rows, err := r.db.QueryContext(ctx, ListOpenReportCases, limit)
+	if err != nil {
+		logger.Error("failed to list report cases", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cases []*report.Case
+	for rows.Next() {
+		c, err := scanReportCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+func (r *ReportRepository) GetCase(ctx context.Context, id uuidHelper.UUID) (*report.Case, error) {
+	return scanReportCase(r.db.QueryRowContext(ctx, GetReportCase, id.String()))
+}
+
+func (r *ReportRepository) ListReports(ctx context.Context, caseID uuidHelper.UUID) ([]*report.Report, error) {
+	rows, err := r.db.QueryContext(ctx, ListReports, caseID.String())
+	if err != nil {
+		logger.Error("failed to list reports", "error", err, "case_id", caseID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*report.Report
+	for rows.Next() {
+		var rep report.Report
+		var id, caseIDStr, postID, sessionID, reason string
+		if err := rows.Scan(&id, &caseIDStr, &postID, &sessionID, &reason, &rep.Text, &rep.CreatedAt); err != nil {
+			logger.Error("failed to scan report", "error", err)
+			return nil, err
+		}
+		rep.Reason = report.Reason(reason)
+		for _, f := range []struct {
+			dst *uuidHelper.UUID
+			src string
+		}{{&rep.ID, id}, {&rep.CaseID, caseIDStr}, {&rep.PostID, postID}, {&rep.SessionID, sessionID}} {
+			if *f.dst, err = uuidHelper.ParseUUID(f.src); err != nil {
+				logger.Error("invalid UUID format for report", "value", f.src, "error", err)
+				return nil, err
+			}
+		}
+		reports = append(reports, &rep)
+	}
+	return reports, rows.Err()
+}
+
+func (r *ReportRepository) ClaimCase(ctx context.Context, id, moderatorID uuidHelper.UUID, now, staleBefore time.Time) error {
+	res, err := r.db.ExecContext(ctx, ClaimReportCase, id.String(), moderatorID.String(), now, staleBefore)
+	if err != nil {
+		logger.Error("failed to claim report case", "error", err, "case_id", id)
+		return err
+	}
+	return r.checkUpdated(ctx, res, id)
+}
+
+func (r *ReportRepository) ResolveCase(ctx context.Context, c *report.Case) error {
+	res, err := r.db.ExecContext(ctx, ResolveReportCase, c.ID.String(), string(c.Resolution), c.ResolvedBy.String(), *c.ResolvedAt)
+	if err != nil {
+		logger.Error("failed to resolve report case", "error", err, "case_id", c.ID)
+		return err
+	}
+	return r.checkUpdated(ctx, res, c.ID)
+}
+
+// checkUpdated explains why a claim or resolution changed no row: the case
+// is gone, already resolved, or claimed by someone else.
+func (r *ReportRepository) checkUpdated(ctx context.Context, res sql.Result, id uuidHelper.UUID) error {
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	c, err := r.GetCase(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.IsOpen() {
+		return errors.ErrReportResolved
+	}
+	return errors.ErrReportClaimed
+}
+
+func scanReportCase(row interface{ Scan(...any) error }) (*report.Case, error) {
+	var c report.Case
+	var id, threadID, authorID string
+	var commentID, claimedBy, resolution, resolvedBy sql.NullString
+	var claimedAt, resolvedAt sql.NullTime
+	var reasons []byte
+	err := row.Scan(&id, &threadID, &commentID, &c.Post.Number, &authorID,
+		&c.Score, &c.ReportCount, &reasons,
+		&c.OpenedAt, &c.LastReportedAt, &claimedBy, &claimedAt,
+		&resolution, &resolvedBy, &resolvedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrReportNotFound
+		}
+		logger.Error("failed to scan report case", "error", err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal(reasons, &c.Reasons); err != nil {
+		logger.Error("invalid reasons of report case", "error", err, "case_id", id)
+		return nil, err
+	}
+	c.Resolution = report.Resolution(resolution.String)
+	if claimedAt.Valid {
+		c.ClaimedAt = &claimedAt.Time
+	}
+	if resolvedAt.Valid {
+		c.ResolvedAt = &resolvedAt.Time
+	}
+
+	if c.ID, err = uuidHelper.ParseUUID(id); err != nil {
+		return nil, err
+	}
+	if c.Post.ThreadID, err = uuidHelper.ParseUUID(threadID); err != nil {
+		return nil, err
+	}
+	if c.AuthorSessionID, err = uuidHelper.ParseUUID(authorID); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		dst **uuidHelper.UUID
+		src sql.NullString
+	}{{&c.Post.CommentID, commentID}, {&c.ClaimedBy, claimedBy}, {&c.ResolvedBy, resolvedBy}} {
+		if !f.src.Valid {
+			continue
+		}
+		u, err := uuidHelper.ParseUUID(f.src.String)
+		if err != nil {
+			logger.Error("invalid UUID format for report case", "value", f.src.String, "error", err)
+			return nil, err
+		}
+		*f.dst = &u
+	}
+	return &c, nil
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/revision"
+	"context"
+	"database/sql"
+	"fmt"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type RevisionRepository struct {
+	db *sql.DB
+}
+
+func NewRevisionRepository(db *sql.DB) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+func (r *RevisionRepository) CreateRevision(ctx context.Context, rev *revision.Revision) error {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while creating revision", "error", err, "post_id", rev.PostID)
+		return err
+	}
+
+	var err error
+	switch rev.Kind {
+	case revision.KindThread:
+		_, err = r.db.ExecContext(ctx, CreateThreadRevision,
+			rev.ID.String(), rev.PostID.String(), rev.Title, rev.Content, rev.CreatedAt)
+	case revision.KindComment:
+		_, err = r.db.ExecContext(ctx, CreateCommentRevision,
+			rev.ID.String(), rev.PostID.String(), rev.Content, rev.CreatedAt)
+	default:
+		err = fmt.Errorf("unknown revision kind %q", rev.Kind)
+	}
+	if err != nil {
+		logger.Error("failed to create revision", "error", err, "post_id", rev.PostID)
+	}
+	return err
+}
+
+func (r *RevisionRepository) ListRevisions(ctx context.Context, kind revision.Kind, postID uuidHelper.UUID) ([]*revision.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing revisions", "error", err, "post_id", postID)
+		return nil, err
+	}
+
+	query := ListThreadRevisions
+	if kind == revision.KindComment {
+		query = ListCommentRevisions
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, postID.String())
+	if err != nil {
+		logger.Error("failed to execute list revisions query", "error", err, "post_id", postID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*revision.Revision
+	for rows.Next() {
+		rev := &revision.Revision{Kind: kind}
+		var idStr, postIDStr string
+		if err := rows.Scan(&idStr, &postIDStr, &rev.Title, &rev.Content, &rev.CreatedAt); err != nil {
+			logger.Error("failed to scan revision", "error", err, "post_id", postID)
+			return nil, err
+		}
+		if rev.ID, err = uuidHelper.ParseUUID(idStr); err != nil {
+			logger.Error("invalid UUID format for revision id", "value", idStr, "error", err)
+			return nil, err
+		}
+		if rev.PostID, err = uuidHelper.ParseUUID(postIDStr); err != nil {
+			logger.Error("invalid UUID format for revision post id", "value", postIDStr, "error", err)
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for revisions", "error", err, "post_id", postID)
+		return nil, err
+	}
+	return revisions, nil
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/domain/search"
+	"context"
+	"database/sql"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// headlineOptions wraps matched terms in the domain's highlight delimiters
+// and keeps snippets short enough for a result list.
+var headlineOptions = "StartSel=" + search.HighlightStart +
+	", StopSel=" + search.HighlightStop +
+	", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+
+type SearchRepository struct {
+	db *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+func (r *SearchRepository) Search(ctx context.Context, q *search.Query, offset, limit int) ([]*search.Result, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while searching", "error", err)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, Search, q.Text, string(q.Status), limit, offset, headlineOptions)
+	if err != nil {
+		logger.Error("failed to execute search query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*search.Result
+	for rows.Next() {
+		res, err := scanSearchResult(rows)
+		if err != nil {
+			logger.Error("failed to scan search result", "error", err)
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for search", "error", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func scanSearchResult(rows *sql.Rows) (*search.Result, error) {
+	res := &search.Result{}
+	var (
+		kind        string
+		threadIDStr string
+		commentID   sql.NullString
+	)
+
+	err := rows.Scan(
+		&kind,
+		&threadIDStr,
+		&commentID,
+		&res.ThreadTitle,
+		&res.Snippet,
+		&res.Rank,
+		&res.CreatedAt,
+		&res.IsArchived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	res.Kind = search.Kind(kind)
+
+	res.ThreadID, err = uuidHelper.ParseUUID(threadIDStr)
+	if err != nil {
+		logger.Error("invalid UUID format for thread_id", "value", threadIDStr, "error", err)
+		return nil, err
+	}
+
+	if commentID.Valid {
+		id, err := uuidHelper.ParseUUID(commentID.String)
+		if err != nil {
+			logger.Error("invalid UUID format for comment_id", "value", commentID.String, "error", err)
+			return nil, err
+		}
+		res.CommentID = &id
+	}
+
+	return res, nil
+}
@@ -37,7 +37,7 @@ func (r *SessionRepository) GetSessionByID(ctx context.Context, id string) (*ses
 
 	var s session.Session
 	var uuidStr string
-	err := row.Scan(&uuidStr, &s.AvatarURL, &s.DisplayName, &s.CreatedAt, &s.ExpiresAt)
+	err := row.Scan(&uuidStr, &s.AvatarURL, &s.DisplayName, &s.Tripcode, &s.CreatedAt, &s.ExpiresAt, &s.CaptchasSolved, &s.IPHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			logger.Error("session not found", "id", id)
@@ -76,7 +76,7 @@ func (r *SessionRepository) ListActiveSessions(ctx context.Context) ([]*session.
 	for rows.Next() {
 		var s session.Session
 		var uuidStr string
-		if err := rows.Scan(&uuidStr, &s.AvatarURL, &s.DisplayName, &s.CreatedAt, &s.ExpiresAt); err != nil {
+		if err := rows.Scan(&uuidStr, &s.AvatarURL, &s.DisplayName, &s.Tripcode, &s.CreatedAt, &s.ExpiresAt, &s.CaptchasSolved, &s.IPHash); err != nil {
 			logger.Error("failed to scan session row", "error", err)
 			return nil, err
 		}
@@ -97,10 +97,53 @@ func (r *SessionRepository) ListActiveSessions(ctx context.Context) ([]*session.
 	return sessions, nil
 }
 
-func (r *SessionRepository) UpdateDisplayName(ctx context.Context, id string, name string) error {
-	_, err := r.db.ExecContext(ctx, UpdateDisplayName, name, id)
+func (r *SessionRepository) UpdateDisplayName(ctx context.Context, id string, name, tripcode string) error {
+	if tripcode != "" {
+		_, err := r.db.ExecContext(ctx, UpdateDisplayName, name, tripcode, id)
+		if err != nil {
+			logger.Error("failed to update display name", "id", id, "error", err)
+		}
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		logger.Error("failed to begin rename transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, LockDisplayName, name); err != nil {
+		logger.Error("failed to lock display name", "error", err)
+		return err
+	}
+	var taken bool
+	if err := tx.QueryRowContext(ctx, DisplayNameTaken, name, id, time.Now()).Scan(&taken); err != nil {
+		logger.Error("failed to check display name", "id", id, "error", err)
+		return err
+	}
+	if taken {
+		return errors.ErrDisplayNameConflict
+	}
+	if _, err := tx.ExecContext(ctx, UpdateDisplayName, name, tripcode, id); err != nil {
 		logger.Error("failed to update display name", "id", id, "error", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SessionRepository) AddSolvedCaptcha(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, AddSolvedCaptcha, id)
+	if err != nil {
+		logger.Error("failed to count solved captcha", "id", id, "error", err)
+	}
+	return err
+}
+
+func (r *SessionRepository) UpdateIPHash(ctx context.Context, id string, ipHash string) error {
+	_, err := r.db.ExecContext(ctx, UpdateSessionIPHash, ipHash, id)
+	if err != nil {
+		logger.Error("failed to update session address", "id", id, "error", err)
 	}
 	return err
 }
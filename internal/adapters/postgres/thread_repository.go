@@ -2,10 +2,14 @@ package postgres
 
 import (
 	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
 	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
 	"1337b04rd/internal/domain/thread"
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	uuidHelper "1337b04rd/internal/app/common/utils"
 
@@ -26,7 +30,7 @@ func (r *ThreadRepository) CreateThread(ctx context.Context, t *thread.Thread) e
 		return err
 	}
 
-	_, err := r.db.ExecContext(ctx, CreateThread,
+	err := r.db.QueryRowContext(ctx, CreateThread,
 		t.ID.String(),
 		t.Title,
 		t.Content,
@@ -34,8 +38,13 @@ func (r *ThreadRepository) CreateThread(ctx context.Context, t *thread.Thread) e
 		t.SessionID.String(),
 		t.CreatedAt,
 		t.LastCommented,
-		t.IsDeleted,
-	)
+		string(t.State),
+		t.BoardID.String(),
+		t.BumpedAt,
+		t.IsPinned,
+		t.ArchivedAt,
+		t.Tripcode,
+	).Scan(&t.PostNumber)
 	if err != nil {
 		logger.Error("failed to execute create thread query", "error", err, "thread_id", t.ID)
 	}
@@ -73,7 +82,10 @@ func (r *ThreadRepository) UpdateThread(ctx context.Context, t *thread.Thread) e
 		t.SessionID.String(),
 		t.CreatedAt,
 		t.LastCommented,
-		t.IsDeleted,
+		string(t.State),
+		t.IsPinned,
+		t.ArchivedAt,
+		t.EditedAt,
 	)
 	if err != nil {
 		logger.Error("failed to execute update thread query", "error", err, "thread_id", t.ID)
@@ -143,6 +155,136 @@ func (r *ThreadRepository) ListAllThreads(ctx context.Context) ([]*thread.Thread
 	return threads, nil
 }
 
+func (r *ThreadRepository) ListPinnedThreads(ctx context.Context, boardID *uuidHelper.UUID) ([]*thread.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing pinned threads", "error", err)
+		return nil, err
+	}
+
+	return r.queryThreads(ctx, ListPinnedThreads, nilIfNilUUID(boardID))
+}
+
+func (r *ThreadRepository) ListArchivedThreadsBefore(ctx context.Context, before time.Time) ([]*thread.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing archived threads", "error", err)
+		return nil, err
+	}
+
+	return r.queryThreads(ctx, ListArchivedThreadsBefore, before)
+}
+
+func (r *ThreadRepository) DeleteThread(ctx context.Context, id uuidHelper.UUID) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while deleting thread", "error", err, "thread_id", id)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, DeleteThread, id.String())
+	if err != nil {
+		logger.Error("failed to execute delete thread query", "error", err, "thread_id", id)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imageURLs []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			logger.Error("failed to scan comment image url", "error", err, "thread_id", id)
+			return nil, err
+		}
+		imageURLs = append(imageURLs, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for deleted thread", "error", err, "thread_id", id)
+		return nil, err
+	}
+	return imageURLs, nil
+}
+
+func (r *ThreadRepository) ListActiveThreadsPage(ctx context.Context, boardID *uuidHelper.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing active threads page", "error", err)
+		return nil, err
+	}
+
+	afterTime, afterID := cursorArgs(after)
+	return r.queryThreads(ctx, ListActiveThreadsPage, afterTime, afterID, limit, nilIfNilUUID(boardID))
+}
+
+func (r *ThreadRepository) ListAllThreadsPage(ctx context.Context, boardID *uuidHelper.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while listing all threads page", "error", err)
+		return nil, err
+	}
+
+	afterTime, afterID := cursorArgs(after)
+	return r.queryThreads(ctx, ListAllThreadsPage, afterTime, afterID, limit, nilIfNilUUID(boardID))
+}
+
+func (r *ThreadRepository) GetPostByNumber(ctx context.Context, boardID uuidHelper.UUID, number int64) (*post.Locator, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Error("context error while getting post by number", "error", err, "number", number)
+		return nil, err
+	}
+
+	var (
+		l           post.Locator
+		threadIDStr string
+		commentID   sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, GetPostByNumber, boardID.String(), number).Scan(&l.Number, &threadIDStr, &commentID)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPostNotFound
+	}
+	if err != nil {
+		logger.Error("failed to get post by number", "error", err, "board_id", boardID, "number", number)
+		return nil, err
+	}
+
+	l.ThreadID, err = uuidHelper.ParseUUID(threadIDStr)
+	if err != nil {
+		logger.Error("invalid UUID format for thread_id", "value", threadIDStr, "error", err)
+		return nil, err
+	}
+	if commentID.Valid {
+		id, err := uuidHelper.ParseUUID(commentID.String)
+		if err != nil {
+			logger.Error("invalid UUID format for comment_id", "value", commentID.String, "error", err)
+			return nil, err
+		}
+		l.CommentID = &id
+	}
+	return &l, nil
+}
+
+func (r *ThreadRepository) queryThreads(ctx context.Context, query string, args ...interface{}) ([]*thread.Thread, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("failed to execute threads query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []*thread.Thread
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			logger.Error("failed to scan thread", "error", err)
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("error occurred during rows iteration for threads", "error", err)
+		return nil, err
+	}
+
+	return threads, nil
+}
+
 func scanThread(scanner interface {
 	Scan(dest ...interface{}) error
 }) (*thread.Thread, error) {
@@ -150,19 +292,32 @@ func scanThread(scanner interface {
 	var (
 		imageURLs     pq.StringArray
 		lastCommented sql.NullTime
+		archivedAt    sql.NullTime
+		editedAt      sql.NullTime
+		state         string
 		idStr         string
+		boardIDStr    string
 		sessionIDStr  string
 	)
 
 	err := scanner.Scan(
 		&idStr,
+		&boardIDStr,
 		&t.Title,
 		&t.Content,
 		&imageURLs,
 		&sessionIDStr,
 		&t.CreatedAt,
 		&lastCommented,
-		&t.IsDeleted,
+		&state,
+		&archivedAt,
+		&t.IsPinned,
+		&t.BumpedAt,
+		&t.BumpCount,
+		&t.ReplyCount,
+		&editedAt,
+		&t.PostNumber,
+		&t.Tripcode,
 	)
 	if err != nil {
 		logger.Error("failed to scan thread row", "error", err)
@@ -175,6 +330,12 @@ func scanThread(scanner interface {
 		return nil, err
 	}
 
+	t.BoardID, err = uuidHelper.ParseUUID(boardIDStr)
+	if err != nil {
+		logger.Error("invalid UUID format for board_id", "value", boardIDStr, "error", err)
+		return nil, err
+	}
+
 	t.SessionID, err = uuidHelper.ParseUUID(sessionIDStr)
 	if err != nil {
 		logger.Error("invalid UUID format for session_id", "value", sessionIDStr, "error", err)
@@ -184,6 +345,18 @@ func scanThread(scanner interface {
 	if lastCommented.Valid {
 		t.LastCommented = &lastCommented.Time
 	}
+	if archivedAt.Valid {
+		t.ArchivedAt = &archivedAt.Time
+	}
+	if editedAt.Valid {
+		t.EditedAt = &editedAt.Time
+	}
+
+	t.State = thread.State(state)
+	if !thread.ValidState(t.State) {
+		logger.Error("unknown thread state", "value", state, "thread_id", idStr)
+		return nil, fmt.Errorf("unknown thread state %q", state)
+	}
 
 	t.ImageURLs = []string(imageURLs)
 	return t, nil
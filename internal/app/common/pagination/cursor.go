@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/errors"
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor is a keyset position: the sort timestamp and ID of the last row a
+// client has seen. It is opaque to clients, who only pass it back.
+type Cursor struct {
+	Time time.Time
+	ID   utils.UUID
+}
+
+// Page is one slice of a keyset-ordered list. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+func (c Cursor) Encode() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode. An empty string means "from
+// the start" and yields a nil cursor.
+func Decode(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	id, err := utils.ParseUUID(parts[1])
+	if err != nil {
+		return nil, errors.ErrInvalidCursor
+	}
+
+	return &Cursor{Time: t, ID: id}, nil
+}
+
+// ClampLimit maps a requested page size onto [1, MaxLimit], using
+// DefaultLimit when none was given.
+func ClampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+// NewPage trims rows fetched with limit+1 down to limit and derives the next
+// cursor from the last row kept. key returns the keyset position of a row.
+func NewPage[T any](rows []T, limit int, key func(T) Cursor) *Page[T] {
+	page := &Page[T]{Items: rows}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		page.NextCursor = key(page.Items[limit-1]).Encode()
+	}
+	return page
+}
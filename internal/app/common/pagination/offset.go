@@ -0,0 +1,33 @@
+package pagination
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"encoding/base64"
+	"strconv"
+)
+
+// Ranked lists such as search results have no stable keyset, so they page by
+// offset. The offset is wrapped in the same opaque form as a keyset cursor.
+
+func EncodeOffset(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o|" + strconv.Itoa(offset)))
+}
+
+// DecodeOffset parses a cursor produced by EncodeOffset. An empty string
+// means the first page.
+func DecodeOffset(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(raw) < 3 || string(raw[:2]) != "o|" {
+		return 0, errors.ErrInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(string(raw[2:]))
+	if err != nil || offset < 0 {
+		return 0, errors.ErrInvalidCursor
+	}
+	return offset, nil
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/ban"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type BanPort interface {
+	CreateBan(ctx context.Context, b *ban.Ban) error
+	// FindBans returns the bans in force at now that name the session or
+	// the address, the ones that last longest first.
+	FindBans(ctx context.Context, sessionID uuidHelper.UUID, ipHash string, now time.Time) ([]*ban.Ban, error)
+	// ListActiveBans returns every ban in force at now, newest first.
+	ListActiveBans(ctx context.Context, now time.Time) ([]*ban.Ban, error)
+	DeleteBan(ctx context.Context, id uuidHelper.UUID) error
+	DeleteExpiredBans(ctx context.Context, now time.Time) error
+}
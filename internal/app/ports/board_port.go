@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/board"
+	"context"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type BoardPort interface {
+	GetBoardBySlug(ctx context.Context, slug string) (*board.Board, error)
+	GetBoardByID(ctx context.Context, id uuidHelper.UUID) (*board.Board, error)
+	ListBoards(ctx context.Context) ([]*board.Board, error)
+}
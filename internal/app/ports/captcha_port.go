@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/captcha"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type CaptchaPort interface {
+	CreateChallenge(ctx context.Context, c *captcha.Challenge) error
+	// GetChallenge returns a challenge that has not expired by now, or
+	// ErrCaptchaNotFound.
+	GetChallenge(ctx context.Context, id uuidHelper.UUID, now time.Time) (*captcha.Challenge, error)
+	// TakeChallenge removes a challenge and returns it, expired or not, so
+	// that it is answered at most once. ErrCaptchaNotFound when it is gone.
+	TakeChallenge(ctx context.Context, id uuidHelper.UUID) (*captcha.Challenge, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
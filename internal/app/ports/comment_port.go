@@ -1,6 +1,7 @@
 package ports
 
 import (
+	"1337b04rd/internal/app/common/pagination"
 	"1337b04rd/internal/domain/comment"
 	"context"
 
@@ -9,5 +10,18 @@ import (
 
 type CommentPort interface {
 	CreateComment(ctx context.Context, c *comment.Comment) error
+	GetCommentByID(ctx context.Context, id uuidHelper.UUID) (*comment.Comment, error)
+	// UpdateComment stores the content, deletion mark and edit time.
+	UpdateComment(ctx context.Context, c *comment.Comment) error
 	GetCommentsByThreadID(ctx context.Context, threadID uuidHelper.UUID) ([]*comment.Comment, error)
+	// GetCommentIDsBySession lists the comments a session posted in a thread.
+	GetCommentIDsBySession(ctx context.Context, threadID, sessionID uuidHelper.UUID) ([]uuidHelper.UUID, error)
+
+	// GetCommentTree returns the comments under rootID, or under the roots
+	// of the thread when rootID is nil, down to maxDepth levels below them,
+	// oldest first.
+	GetCommentTree(ctx context.Context, threadID uuidHelper.UUID, rootID *uuidHelper.UUID, maxDepth int) ([]*comment.Node, error)
+
+	// Keyset-paginated, oldest first. after is nil for the first page.
+	GetCommentsByThreadIDPage(ctx context.Context, threadID uuidHelper.UUID, after *pagination.Cursor, limit int) ([]*comment.Comment, error)
 }
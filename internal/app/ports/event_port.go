@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/event"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type EventPort interface {
+	// Publish must not block: slow subscribers lose events rather than
+	// stalling the request that produced them.
+	Publish(e event.Event)
+	// Subscribe returns a channel of events for one thread and a function
+	// that unsubscribes and closes the channel.
+	Subscribe(threadID uuidHelper.UUID) (<-chan event.Event, func())
+}
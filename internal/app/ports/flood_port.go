@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"time"
+)
+
+type FloodPort interface {
+	// Acquire holds every claim from now on unless one of them is still
+	// held. Then it holds none and returns the held key that frees up last,
+	// with the time it does. Acquire must be atomic across concurrent posts.
+	Acquire(ctx context.Context, claims []flood.Claim, now time.Time) (held string, until time.Time, err error)
+	// DeleteExpired forgets the claims that expired before now.
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
@@ -0,0 +1,25 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type ModeratorPort interface {
+	// CreateModerator fails with ErrModeratorExists for a taken username.
+	CreateModerator(ctx context.Context, m *moderator.Moderator) error
+	GetModeratorByID(ctx context.Context, id uuidHelper.UUID) (*moderator.Moderator, error)
+	GetModeratorByUsername(ctx context.Context, username string) (*moderator.Moderator, error)
+	ListModerators(ctx context.Context) ([]*moderator.Moderator, error)
+	CountModerators(ctx context.Context) (int, error)
+
+	CreateLogin(ctx context.Context, l *moderator.Login) error
+	// GetLogin returns the login with tokenHash if it has not expired by
+	// now, or ErrLoginRequired.
+	GetLogin(ctx context.Context, tokenHash string, now time.Time) (*moderator.Login, error)
+	DeleteLogin(ctx context.Context, tokenHash string) error
+	DeleteExpiredLogins(ctx context.Context, now time.Time) error
+}
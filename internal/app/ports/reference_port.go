@@ -0,0 +1,16 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/comment"
+	"context"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type ReferencePort interface {
+	// SaveReferences replaces the >> links of a comment with c.References.
+	SaveReferences(ctx context.Context, c *comment.Comment) error
+	// ListReferences returns the links made in a thread, in the order of
+	// the comments making them.
+	ListReferences(ctx context.Context, threadID uuidHelper.UUID) ([]*comment.Reference, error)
+}
@@ -0,0 +1,25 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/report"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type ReportPort interface {
+	// AddReport files r under the open case of its post, or opens c for it
+	// when there is none, and returns the case it went to.
+	// ErrAlreadyReported when the session reported the post before.
+	AddReport(ctx context.Context, c *report.Case, r *report.Report) (*report.Case, error)
+	// ListOpenCases returns the open cases, highest score first.
+	ListOpenCases(ctx context.Context, limit int) ([]*report.Case, error)
+	GetCase(ctx context.Context, id uuidHelper.UUID) (*report.Case, error)
+	ListReports(ctx context.Context, caseID uuidHelper.UUID) ([]*report.Report, error)
+	// ClaimCase gives an open case to moderatorID unless another
+	// moderator claimed it after staleBefore; ErrReportClaimed then.
+	ClaimCase(ctx context.Context, id, moderatorID uuidHelper.UUID, now, staleBefore time.Time) error
+	// ResolveCase stores the resolution of a case its resolver claimed.
+	ResolveCase(ctx context.Context, c *report.Case) error
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/revision"
+	"context"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type RevisionPort interface {
+	CreateRevision(ctx context.Context, r *revision.Revision) error
+	// ListRevisions returns the revisions of a post, oldest first.
+	ListRevisions(ctx context.Context, kind revision.Kind, postID uuidHelper.UUID) ([]*revision.Revision, error)
+}
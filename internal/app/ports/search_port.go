@@ -0,0 +1,11 @@
+package ports
+
+import (
+	"1337b04rd/internal/domain/search"
+	"context"
+)
+
+type SearchPort interface {
+	// Search returns matches ordered by rank, best first, skipping offset rows.
+	Search(ctx context.Context, q *search.Query, offset, limit int) ([]*search.Result, error)
+}
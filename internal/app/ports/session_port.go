@@ -10,5 +10,12 @@ type SessionPort interface {
 	CreateSession(ctx context.Context, s *session.Session) error
 	DeleteExpired(ctx context.Context) error
 	ListActiveSessions(ctx context.Context) ([]*session.Session, error)
-	UpdateDisplayName(ctx context.Context, id string, name string) error
+	// UpdateDisplayName renames a session. A name without a tripcode that
+	// another unexpired session has without one too gives
+	// ErrDisplayNameConflict; the check and the rename are atomic.
+	UpdateDisplayName(ctx context.Context, id string, name, tripcode string) error
+	// AddSolvedCaptcha counts one more CAPTCHA solved by the session.
+	AddSolvedCaptcha(ctx context.Context, id string) error
+	// UpdateIPHash remembers the address the session last wrote from.
+	UpdateIPHash(ctx context.Context, id string, ipHash string) error
 }
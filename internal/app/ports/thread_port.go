@@ -1,8 +1,11 @@
 package ports
 
 import (
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/domain/post"
 	"1337b04rd/internal/domain/thread"
 	"context"
+	"time"
 
 	uuidHelper "1337b04rd/internal/app/common/utils"
 )
@@ -13,4 +16,24 @@ type ThreadPort interface {
 	UpdateThread(ctx context.Context, t *thread.Thread) error
 	ListActiveThreads(ctx context.Context) ([]*thread.Thread, error)
 	ListAllThreads(ctx context.Context) ([]*thread.Thread, error)
+
+	// ListArchivedThreadsBefore returns archived threads that were archived
+	// before the given time.
+	ListArchivedThreadsBefore(ctx context.Context, before time.Time) ([]*thread.Thread, error)
+	// DeleteThread removes a thread and its comments, returning the image
+	// URLs of the removed comments.
+	DeleteThread(ctx context.Context, id uuidHelper.UUID) ([]string, error)
+
+	// ListPinnedThreads returns the active pinned threads, which the keyset
+	// pages of active threads leave out. boardID is nil for every board.
+	ListPinnedThreads(ctx context.Context, boardID *uuidHelper.UUID) ([]*thread.Thread, error)
+
+	// Keyset-paginated variants, newest first. after is nil for the first page;
+	// boardID is nil to list threads of every board.
+	ListActiveThreadsPage(ctx context.Context, boardID *uuidHelper.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error)
+	ListAllThreadsPage(ctx context.Context, boardID *uuidHelper.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error)
+
+	// GetPostByNumber finds the thread or comment with the given number on
+	// a board, archived threads included.
+	GetPostByNumber(ctx context.Context, boardID uuidHelper.UUID, number int64) (*post.Locator, error)
 }
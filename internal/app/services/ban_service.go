@@ -0,0 +1,198 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"1337b04rd/internal/domain/session"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// BanService bans the authors of posts and checks writes against the bans.
+type BanService struct {
+	banRepo     ports.BanPort
+	sessionRepo ports.SessionPort
+	boardRepo   ports.BoardPort
+	threadRepo  ports.ThreadPort
+	commentRepo ports.CommentPort
+	// ipSalt keys the address hashes; with a new salt the bans by address
+	// no longer match.
+	ipSalt          []byte
+	defaultDuration time.Duration
+}
+
+func NewBanService(banRepo ports.BanPort, sessionRepo ports.SessionPort, boardRepo ports.BoardPort, threadRepo ports.ThreadPort, commentRepo ports.CommentPort, ipSalt string, defaultDuration time.Duration) *BanService {
+	return &BanService{
+		banRepo:         banRepo,
+		sessionRepo:     sessionRepo,
+		boardRepo:       boardRepo,
+		threadRepo:      threadRepo,
+		commentRepo:     commentRepo,
+		ipSalt:          []byte(ipSalt),
+		defaultDuration: defaultDuration,
+	}
+}
+
+// DefaultDuration is how long a ban lasts when the moderator does not say.
+func (s *BanService) DefaultDuration() time.Duration {
+	return s.defaultDuration
+}
+
+// Check returns the ban that keeps the session, writing from ip, from
+// writing, or nil. board tells which board the write goes to; it is only
+// called when a ban limited to one board matches, and may be nil for a
+// write that belongs to no board. A session let through remembers its
+// address, for the bans of its posts.
+func (s *BanService) Check(ctx context.Context, sess *session.Session, ip string, board func() (*uuidHelper.UUID, error)) (*ban.Ban, error) {
+	ipHash := ban.HashIP(s.ipSalt, ip)
+	bans, err := s.banRepo.FindBans(ctx, sess.ID, ipHash, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var boardID *uuidHelper.UUID
+	resolved := board == nil
+	for _, b := range bans {
+		if b.BoardID != nil && !resolved {
+			if boardID, err = board(); err != nil {
+				return nil, err
+			}
+			resolved = true
+		}
+		if b.Covers(boardID) {
+			logger.Warn("write refused by ban", "session_id", sess.ID, "ban_id", b.ID)
+			return b, nil
+		}
+	}
+
+	if ipHash != "" && ipHash != sess.IPHash {
+		if err := s.sessionRepo.UpdateIPHash(ctx, sess.ID.String(), ipHash); err != nil {
+			return nil, err
+		}
+		sess.IPHash = ipHash
+	}
+	return nil, nil
+}
+
+// BanAuthor bans the session that wrote a post, and the address it last
+// wrote from, on the post's board or, unless boardOnly, on every board. A
+// zero duration never ends.
+func (s *BanService) BanAuthor(ctx context.Context, mod *moderator.Moderator, postID uuidHelper.UUID, boardOnly bool, reason string, duration time.Duration) (*ban.Ban, error) {
+	author, boardID, err := s.author(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ipHash string
+	sess, err := s.sessionRepo.GetSessionByID(ctx, author.String())
+	switch err {
+	case nil:
+		ipHash = sess.IPHash
+	case errors.ErrSessionNotFound:
+		// The session expired; the ban still stops its cookie.
+	default:
+		return nil, err
+	}
+
+	b, err := ban.NewBan(&author, ipHash, reason, duration, mod.ID)
+	if err != nil {
+		return nil, err
+	}
+	if boardOnly {
+		bd, err := s.boardRepo.GetBoardByID(ctx, boardID)
+		if err != nil {
+			return nil, err
+		}
+		b.Scope(bd.ID, bd.Slug)
+	}
+	if err := s.banRepo.CreateBan(ctx, b); err != nil {
+		return nil, err
+	}
+
+	logger.Info("author banned", "ban_id", b.ID, "post_id", postID, "by_address", ipHash != "", "board", b.BoardSlug, "moderator", mod.Username)
+	return b, nil
+}
+
+// author finds the session that wrote a thread or comment, deleted or not,
+// and the board it is on.
+func (s *BanService) author(ctx context.Context, postID uuidHelper.UUID) (uuidHelper.UUID, uuidHelper.UUID, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, postID)
+	if err == nil {
+		return t.SessionID, t.BoardID, nil
+	}
+	if err != errors.ErrThreadNotFound {
+		return uuidHelper.UUID{}, uuidHelper.UUID{}, err
+	}
+
+	c, err := s.commentRepo.GetCommentByID(ctx, postID)
+	if err == errors.ErrCommentNotFound {
+		return uuidHelper.UUID{}, uuidHelper.UUID{}, errors.ErrPostNotFound
+	}
+	if err != nil {
+		return uuidHelper.UUID{}, uuidHelper.UUID{}, err
+	}
+	if t, err = s.threadRepo.GetThreadByID(ctx, c.ThreadID); err != nil {
+		return uuidHelper.UUID{}, uuidHelper.UUID{}, err
+	}
+	return c.SessionID, t.BoardID, nil
+}
+
+// ListBans returns the bans in force, newest first.
+func (s *BanService) ListBans(ctx context.Context) ([]*ban.Ban, error) {
+	return s.banRepo.ListActiveBans(ctx, time.Now())
+}
+
+// Lift ends a ban early.
+func (s *BanService) Lift(ctx context.Context, id uuidHelper.UUID, mod *moderator.Moderator) error {
+	if err := s.banRepo.DeleteBan(ctx, id); err != nil {
+		return err
+	}
+	logger.Info("ban lifted", "ban_id", id, "moderator", mod.Username)
+	return nil
+}
+
+// Cleanup forgets expired bans.
+func (s *BanService) Cleanup(ctx context.Context) error {
+	return s.banRepo.DeleteExpiredBans(ctx, time.Now())
+}
+
+// BoardBySlug, BoardOfThread and BoardOfComment find the board a write goes
+// to. They return nil for a missing board or post, which the write handler
+// answers itself.
+func (s *BanService) BoardBySlug(ctx context.Context, slug string) (*uuidHelper.UUID, error) {
+	b, err := s.boardRepo.GetBoardBySlug(ctx, slug)
+	if err == errors.ErrBoardNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b.ID, nil
+}
+
+func (s *BanService) BoardOfThread(ctx context.Context, threadID uuidHelper.UUID) (*uuidHelper.UUID, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err == errors.ErrThreadNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t.BoardID, nil
+}
+
+func (s *BanService) BoardOfComment(ctx context.Context, commentID uuidHelper.UUID) (*uuidHelper.UUID, error) {
+	c, err := s.commentRepo.GetCommentByID(ctx, commentID)
+	if err == errors.ErrCommentNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.BoardOfThread(ctx, c.ThreadID)
+}
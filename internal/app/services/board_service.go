@@ -0,0 +1,60 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/board"
+	"context"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type BoardService struct {
+	boardRepo ports.BoardPort
+}
+
+func NewBoardService(boardRepo ports.BoardPort) *BoardService {
+	return &BoardService{boardRepo: boardRepo}
+}
+
+func (s *BoardService) ListBoards(ctx context.Context) ([]*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in ListBoards", "error", err)
+		return nil, err
+	}
+
+	boards, err := s.boardRepo.ListBoards(ctx)
+	if err != nil {
+		logger.Error("failed to list boards", "error", err)
+		return nil, err
+	}
+	return boards, nil
+}
+
+func (s *BoardService) GetBoardBySlug(ctx context.Context, slug string) (*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in GetBoardBySlug", "error", err)
+		return nil, err
+	}
+
+	b, err := s.boardRepo.GetBoardBySlug(ctx, slug)
+	if err != nil {
+		logger.Warn("failed to get board", "error", err, "slug", slug)
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *BoardService) GetBoardByID(ctx context.Context, id uuidHelper.UUID) (*board.Board, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in GetBoardByID", "error", err)
+		return nil, err
+	}
+
+	b, err := s.boardRepo.GetBoardByID(ctx, id)
+	if err != nil {
+		logger.Warn("failed to get board", "error", err, "board_id", id)
+		return nil, err
+	}
+	return b, nil
+}
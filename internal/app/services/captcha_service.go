@@ -0,0 +1,120 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/captcha"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/session"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// CaptchaSettings choose which posts need a solved CAPTCHA.
+type CaptchaSettings struct {
+	Actions map[post.Action]bool
+	// TTL is how long a challenge may be answered.
+	TTL time.Duration
+	// TrustAfter is how many CAPTCHAs a session solves before it is no
+	// longer asked. Zero always asks.
+	TrustAfter int
+}
+
+func DefaultCaptchaSettings() CaptchaSettings {
+	return CaptchaSettings{
+		Actions:    map[post.Action]bool{post.ActionThread: true},
+		TTL:        10 * time.Minute,
+		TrustAfter: 3,
+	}
+}
+
+// CaptchaService hands out CAPTCHA challenges and checks their answers.
+// A nil service asks for none.
+type CaptchaService struct {
+	store       ports.CaptchaPort
+	sessionRepo ports.SessionPort
+	settings    CaptchaSettings
+}
+
+func NewCaptchaService(store ports.CaptchaPort, sessionRepo ports.SessionPort, settings CaptchaSettings) *CaptchaService {
+	return &CaptchaService{store: store, sessionRepo: sessionRepo, settings: settings}
+}
+
+// Required reports whether sess must solve a CAPTCHA for action.
+func (s *CaptchaService) Required(sess *session.Session, action post.Action) bool {
+	if s == nil || !s.settings.Actions[action] {
+		return false
+	}
+	return !sess.Trusted(s.settings.TrustAfter)
+}
+
+func (s *CaptchaService) NewChallenge(ctx context.Context) (*captcha.Challenge, error) {
+	c, err := captcha.NewChallenge(s.settings.TTL)
+	if err != nil {
+		logger.Error("failed to generate captcha", "error", err)
+		return nil, err
+	}
+	if err := s.store.CreateChallenge(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Image returns the PNG of a challenge that can still be answered.
+func (s *CaptchaService) Image(ctx context.Context, id uuidHelper.UUID) ([]byte, error) {
+	c, err := s.store.GetChallenge(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	img, err := c.PNG()
+	if err != nil {
+		logger.Error("failed to render captcha", "error", err, "id", id)
+		return nil, err
+	}
+	return img, nil
+}
+
+// Verify checks the answer to a challenge when sess must solve one for
+// action. The challenge is used up either way. A right answer counts
+// towards the session's trust.
+func (s *CaptchaService) Verify(ctx context.Context, sess *session.Session, action post.Action, challengeID, answer string) error {
+	if !s.Required(sess, action) {
+		return nil
+	}
+	if challengeID == "" || answer == "" {
+		return errors.ErrCaptchaRequired
+	}
+
+	id, err := uuidHelper.ParseUUID(challengeID)
+	if err != nil {
+		return errors.ErrCaptchaInvalid
+	}
+	c, err := s.store.TakeChallenge(ctx, id)
+	if err == errors.ErrCaptchaNotFound {
+		return errors.ErrCaptchaInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if c.IsExpired(time.Now()) || !c.Check(answer) {
+		logger.Warn("captcha failed", "session_id", sess.ID, "action", action)
+		return errors.ErrCaptchaInvalid
+	}
+
+	if err := s.sessionRepo.AddSolvedCaptcha(ctx, sess.ID.String()); err != nil {
+		logger.Error("failed to count solved captcha", "error", err, "session_id", sess.ID)
+	}
+	sess.CaptchasSolved++
+	return nil
+}
+
+// Cleanup forgets expired challenges.
+func (s *CaptchaService) Cleanup(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	return s.store.DeleteExpired(ctx, time.Now())
+}
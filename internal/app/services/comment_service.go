@@ -2,55 +2,137 @@ package services
 
 import (
 	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
 	"1337b04rd/internal/app/common/utils"
 	"1337b04rd/internal/app/ports"
 	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/event"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/revision"
+	"1337b04rd/internal/domain/thread"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"strings"
+	"time"
 )
 
 type CommentService struct {
-	commentRepo ports.CommentPort
-	threadRepo  ports.ThreadPort
-	s3          ports.S3Port
-	sessionRepo ports.SessionPort // Добавляем
+	commentRepo   ports.CommentPort
+	threadRepo    ports.ThreadPort
+	boardRepo     ports.BoardPort
+	revisionRepo  ports.RevisionPort
+	referenceRepo ports.ReferencePort
+	s3            ports.S3Port
+	sessionRepo   ports.SessionPort // Добавляем
+	events        ports.EventPort
+	// editWindow is how long after posting the author may edit or delete.
+	editWindow time.Duration
+	// posterSecret keys the poster IDs, see thread.PosterID.
+	posterSecret []byte
+	limits       post.Limits
+	guard        *PostGuard
 }
 
 func NewCommentService(
 	commentRepo ports.CommentPort,
 	threadRepo ports.ThreadPort,
+	boardRepo ports.BoardPort,
+	revisionRepo ports.RevisionPort,
+	referenceRepo ports.ReferencePort,
 	s3 ports.S3Port,
 	sessionRepo ports.SessionPort, // Добавляем
+	events ports.EventPort,
+	editWindow time.Duration,
+	posterSecret string,
+	limits post.Limits,
+	guard *PostGuard,
 ) *CommentService {
 	return &CommentService{
-		commentRepo: commentRepo,
-		threadRepo:  threadRepo,
-		s3:          s3,
-		sessionRepo: sessionRepo,
+		commentRepo:   commentRepo,
+		threadRepo:    threadRepo,
+		boardRepo:     boardRepo,
+		revisionRepo:  revisionRepo,
+		referenceRepo: referenceRepo,
+		s3:            s3,
+		sessionRepo:   sessionRepo,
+		events:        events,
+		editWindow:    editWindow,
+		posterSecret:  []byte(posterSecret),
+		limits:        limits,
+		guard:         guard,
 	}
 }
 
+// Limits are the size limits every comment is checked against.
+func (s *CommentService) Limits() post.Limits {
+	return s.limits
+}
+
+// CreateComment posts a reply. A sage reply is counted but does not bump
+// the thread. Every >> reference must point into the same thread. ip is
+// the poster's address for the flood guard.
 func (s *CommentService) CreateComment(
 	ctx context.Context,
 	threadID utils.UUID,
 	parentID *utils.UUID,
 	content string,
+	sage bool,
 	files map[string]io.Reader,
 	contentTypes map[string]string,
 	sessionID utils.UUID,
 	displayName string,
 	avatarURL string,
+	tripcode string,
+	ip string,
 ) (*comment.Comment, error) {
 	if err := ctx.Err(); err != nil {
 		logger.Warn("context canceled in CreateComment", "error", err)
 		return nil, err
 	}
 
-	var imageURLs []string
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		logger.Error("cannot fetch thread", "error", err)
+		return nil, err
+	}
+	if err := t.CheckWritable(); err != nil {
+		return nil, err
+	}
+
+	b, err := s.boardRepo.GetBoardByID(ctx, t.BoardID)
+	if err != nil {
+		logger.Error("cannot fetch board of thread", "error", err, "thread_id", t.ID)
+		return nil, err
+	}
+	if err := b.CheckImages(len(files)); err != nil {
+		return nil, err
+	}
+	if err := s.limits.CheckAttachments(len(files)); err != nil {
+		return nil, err
+	}
+
+	// Checked before the upload, so a rejected comment leaves no images.
+	c, err := comment.NewComment(threadID, parentID, content, nil, sessionID, displayName, avatarURL, s.limits)
+	if err != nil {
+		logger.Error("cannot create new comment", "error", err)
+		return nil, err
+	}
+	c.IsSage = sage
+	c.Tripcode = tripcode
+	if err := s.checkParent(ctx, t, parentID); err != nil {
+		return nil, err
+	}
+	if err := s.checkReferences(ctx, t, c); err != nil {
+		return nil, err
+	}
+	if err := s.guard.Check(ctx, post.ActionComment, sessionID, ip, content); err != nil {
+		return nil, err
+	}
+
 	if len(files) > 0 {
 		urls, err := s.s3.UploadImages(files, contentTypes)
 		if err != nil {
@@ -59,32 +141,24 @@ func (s *CommentService) CreateComment(
 		}
 		for _, url := range urls {
 			updatedURL := strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
-			imageURLs = append(imageURLs, updatedURL)
+			c.ImageURLs = append(c.ImageURLs, updatedURL)
 		}
 	}
 
-	c, err := comment.NewComment(threadID, parentID, content, imageURLs, sessionID, displayName, avatarURL)
-	if err != nil {
-		logger.Error("cannot create new comment", "error", err)
-		return nil, err
-	}
-
 	if err := s.commentRepo.CreateComment(ctx, c); err != nil {
 		logger.Error("cannot save comment", "error", err)
 		return nil, err
 	}
-
-	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
-	if err != nil {
-		logger.Error("cannot fetch thread", "error", err)
+	if err := s.referenceRepo.SaveReferences(ctx, c); err != nil {
+		logger.Error("cannot save comment references", "error", err, "comment_id", c.ID)
 		return nil, err
 	}
-
-	if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
-		logger.Error("cannot update thread", "error", err)
+	if err := s.stampPosterIDs(ctx, t, c); err != nil {
 		return nil, err
 	}
 
+	s.events.Publish(event.NewCommentCreated(c))
+
 	logger.Info("comment created", "comment", c)
 	return c, nil
 }
@@ -98,17 +172,30 @@ func (s *CommentService) PrepareFilesFromMultipart(form *multipart.Form) (map[st
 		return files, contentTypes, nil
 	}
 
+	total := 0
+	for _, fileHeaders := range form.File {
+		total += len(fileHeaders)
+	}
+	if err := s.limits.CheckAttachments(total); err != nil {
+		return nil, nil, err
+	}
+
 	for _, fileHeaders := range form.File {
 		for _, fh := range fileHeaders {
+			if err := s.limits.CheckAttachmentSize(fh.Size); err != nil {
+				return nil, nil, err
+			}
 			file, err := fh.Open()
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to open file: %w", err)
+				logger.Error("failed to open uploaded file", "error", err, "filename", fh.Filename)
+				return nil, nil, errors.ErrImageUploadFailed
 			}
 			defer file.Close()
 
 			buf := new(bytes.Buffer)
 			if _, err := io.Copy(buf, file); err != nil {
-				return nil, nil, fmt.Errorf("failed to buffer file: %w", err)
+				logger.Error("failed to buffer uploaded file", "error", err, "filename", fh.Filename)
+				return nil, nil, errors.ErrImageUploadFailed
 			}
 
 			key := fmt.Sprintf("file_%d", counter)
@@ -127,12 +214,342 @@ func (s *CommentService) GetCommentsByThreadID(ctx context.Context, threadID uti
 		return nil, err
 	}
 
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
 	comments, err := s.commentRepo.GetCommentsByThreadID(ctx, threadID)
 	if err != nil {
 		logger.Error("failed to get comments", "error", err, "thread_id", threadID)
 		return nil, err
 	}
 
+	s.fillCommentDetails(ctx, comments)
+	if err := s.stampPosterIDs(ctx, t, comments...); err != nil {
+		return nil, err
+	}
+	if err := s.linkReplies(ctx, threadID, comments); err != nil {
+		return nil, err
+	}
+
+	logger.Info("comments retrieved", "thread_id", threadID, "count", len(comments))
+	return comments, nil
+}
+
+// GetCommentsByThreadIDPage returns one page of a thread's comments, oldest
+// first.
+func (s *CommentService) GetCommentsByThreadIDPage(ctx context.Context, threadID utils.UUID, cursor string, limit int) (*pagination.Page[*comment.Comment], error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in GetCommentsByThreadIDPage", "error", err)
+		return nil, err
+	}
+
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.ClampLimit(limit)
+
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.GetCommentsByThreadIDPage(ctx, threadID, after, limit+1)
+	if err != nil {
+		logger.Error("failed to get comments page", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+
+	page := pagination.NewPage(comments, limit, func(c *comment.Comment) pagination.Cursor {
+		return pagination.Cursor{Time: c.CreatedAt, ID: c.ID}
+	})
+	s.fillCommentDetails(ctx, page.Items)
+	if err := s.stampPosterIDs(ctx, t, page.Items...); err != nil {
+		return nil, err
+	}
+	if err := s.linkReplies(ctx, threadID, page.Items); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// GetCommentTree returns the replies of a thread nested under their
+// parents, or the subtree of one of its comments when rootID is set.
+// Replies deeper than maxDepth below the top are left out; a negative
+// maxDepth means the default.
+func (s *CommentService) GetCommentTree(ctx context.Context, threadID utils.UUID, rootID *utils.UUID, maxDepth int) ([]*comment.Node, error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in GetCommentTree", "error", err)
+		return nil, err
+	}
+
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if rootID != nil {
+		root, err := s.commentRepo.GetCommentByID(ctx, *rootID)
+		if err != nil {
+			return nil, err
+		}
+		if root.ThreadID != threadID {
+			return nil, errors.ErrCommentNotFound
+		}
+	}
+
+	nodes, err := s.commentRepo.GetCommentTree(ctx, threadID, rootID, comment.ClampTreeDepth(maxDepth))
+	if err != nil {
+		logger.Error("failed to get comment tree", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+
+	comments := make([]*comment.Comment, 0, len(nodes))
+	for _, n := range nodes {
+		comments = append(comments, n.Comment)
+	}
+	s.fillCommentDetails(ctx, comments)
+	if err := s.stampPosterIDs(ctx, t, comments...); err != nil {
+		return nil, err
+	}
+	if err := s.linkReplies(ctx, threadID, comments); err != nil {
+		return nil, err
+	}
+
+	return comment.BuildTree(nodes), nil
+}
+
+// EditComment replaces the content of a comment on behalf of its author.
+// The replaced version is kept as a revision.
+func (s *CommentService) EditComment(ctx context.Context, id, sessionID utils.UUID, content string) (*comment.Comment, error) {
+	c, t, err := s.authorComment(ctx, id, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := revision.NewRevision(revision.KindComment, c.ID, "", c.Content)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Edit(content, time.Now(), s.limits); err != nil {
+		return nil, err
+	}
+	if err := s.checkReferences(ctx, t, c); err != nil {
+		return nil, err
+	}
+
+	if err := s.revisionRepo.CreateRevision(ctx, rev); err != nil {
+		logger.Error("failed to save comment revision", "error", err, "comment_id", id)
+		return nil, err
+	}
+	if err := s.commentRepo.UpdateComment(ctx, c); err != nil {
+		logger.Error("failed to update edited comment", "error", err, "comment_id", id)
+		return nil, err
+	}
+	if err := s.referenceRepo.SaveReferences(ctx, c); err != nil {
+		logger.Error("failed to save edited comment references", "error", err, "comment_id", id)
+		return nil, err
+	}
+
+	s.fillCommentDetails(ctx, []*comment.Comment{c})
+	if err := s.stampPosterIDs(ctx, t, c); err != nil {
+		return nil, err
+	}
+	if err := s.linkReplies(ctx, c.ThreadID, []*comment.Comment{c}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteComment turns a comment into a tombstone on behalf of its author.
+func (s *CommentService) DeleteComment(ctx context.Context, id, sessionID utils.UUID) error {
+	c, _, err := s.authorComment(ctx, id, sessionID)
+	if err != nil {
+		return err
+	}
+	return s.tombstone(ctx, c)
+}
+
+// RemoveComment turns a comment into a tombstone for a moderator, whoever
+// wrote it and whatever the state of its thread. Its images are deleted
+// too, as they may be why it was removed.
+func (s *CommentService) RemoveComment(ctx context.Context, id utils.UUID) error {
+	c, err := s.commentRepo.GetCommentByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.IsDeleted {
+		return errors.ErrCommentNotFound
+	}
+	if err := s.tombstone(ctx, c); err != nil {
+		return err
+	}
+	deleteMedia(s.s3, c.ImageURLs)
+	return nil
+}
+
+func (s *CommentService) tombstone(ctx context.Context, c *comment.Comment) error {
+	c.MarkAsDeleted()
+	if err := s.commentRepo.UpdateComment(ctx, c); err != nil {
+		logger.Error("failed to delete comment", "error", err, "comment_id", c.ID)
+		return err
+	}
+	if err := s.referenceRepo.SaveReferences(ctx, c); err != nil {
+		logger.Error("failed to drop deleted comment references", "error", err, "comment_id", c.ID)
+		return err
+	}
+	return nil
+}
+
+// ListCommentRevisions returns the earlier versions of a comment, oldest
+// first. Deleted comments have none to show.
+func (s *CommentService) ListCommentRevisions(ctx context.Context, id utils.UUID) ([]*revision.Revision, error) {
+	c, err := s.commentRepo.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.IsDeleted {
+		return nil, errors.ErrCommentNotFound
+	}
+	return s.revisionRepo.ListRevisions(ctx, revision.KindComment, id)
+}
+
+// authorComment loads a live comment that sessionID may still change in a
+// thread that is still writable, together with that thread.
+func (s *CommentService) authorComment(ctx context.Context, id, sessionID utils.UUID) (*comment.Comment, *thread.Thread, error) {
+	c, err := s.commentRepo.GetCommentByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.IsDeleted {
+		return nil, nil, errors.ErrCommentNotFound
+	}
+	if err := c.CheckAuthor(sessionID, time.Now(), s.editWindow); err != nil {
+		return nil, nil, err
+	}
+
+	t, err := s.threadRepo.GetThreadByID(ctx, c.ThreadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := t.CheckWritable(); err != nil {
+		return nil, nil, err
+	}
+	return c, t, nil
+}
+
+// checkParent makes sure a reply goes under a comment of its own thread.
+// The thread itself is not a parent: top-level replies have none.
+func (s *CommentService) checkParent(ctx context.Context, t *thread.Thread, parentID *utils.UUID) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == t.ID {
+		return errors.ErrInvalidParentID
+	}
+	parent, err := s.commentRepo.GetCommentByID(ctx, *parentID)
+	if err == errors.ErrCommentNotFound {
+		return errors.ErrInvalidParentID
+	}
+	if err != nil {
+		logger.Error("cannot check parent comment", "error", err, "parent_id", *parentID)
+		return err
+	}
+	if parent.ThreadID != t.ID {
+		return errors.ErrInvalidParentID
+	}
+	return nil
+}
+
+// checkReferences makes sure every post c links to is the thread itself or
+// one of its comments, and adds the posts it quotes by number.
+func (s *CommentService) checkReferences(ctx context.Context, t *thread.Thread, c *comment.Comment) error {
+	for _, id := range c.References {
+		if id == t.ID {
+			continue
+		}
+		target, err := s.commentRepo.GetCommentByID(ctx, id)
+		if err == errors.ErrCommentNotFound {
+			return errors.ErrInvalidReference
+		}
+		if err != nil {
+			logger.Error("cannot check comment reference", "error", err, "target_id", id)
+			return err
+		}
+		if target.ThreadID != t.ID {
+			return errors.ErrInvalidReference
+		}
+	}
+
+	for _, n := range comment.ParsePostNumbers(c.Content) {
+		loc, err := s.threadRepo.GetPostByNumber(ctx, t.BoardID, n)
+		if err == errors.ErrPostNotFound {
+			return errors.ErrInvalidReference
+		}
+		if err != nil {
+			logger.Error("cannot resolve quoted post number", "error", err, "number", n)
+			return err
+		}
+		if loc.ThreadID != t.ID {
+			return errors.ErrInvalidReference
+		}
+		if err := c.AddReference(loc.ID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkReplies fills in the forward links and backlinks of comments of a
+// thread.
+func (s *CommentService) linkReplies(ctx context.Context, threadID utils.UUID, comments []*comment.Comment) error {
+	refs, err := s.referenceRepo.ListReferences(ctx, threadID)
+	if err != nil {
+		logger.Error("failed to list references", "error", err, "thread_id", threadID)
+		return err
+	}
+	comment.LinkReplies(threadID, comments, refs)
+	return nil
+}
+
+// PostsBy returns the posts sessionID wrote in a thread: the thread itself
+// when it is theirs, and their comments.
+func (s *CommentService) PostsBy(ctx context.Context, threadID, sessionID utils.UUID) (map[utils.UUID]bool, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.commentRepo.GetCommentIDsBySession(ctx, threadID, sessionID)
+	if err != nil {
+		logger.Error("failed to list own comments", "error", err, "thread_id", threadID)
+		return nil, err
+	}
+
+	own := make(map[utils.UUID]bool, len(ids)+1)
+	if t.SessionID == sessionID {
+		own[t.ID] = true
+	}
+	for _, id := range ids {
+		own[id] = true
+	}
+	return own, nil
+}
+
+// stampPosterIDs fills the poster IDs of comments on t when its board
+// shows them.
+func (s *CommentService) stampPosterIDs(ctx context.Context, t *thread.Thread, comments ...*comment.Comment) error {
+	b, err := s.boardRepo.GetBoardByID(ctx, t.BoardID)
+	if err != nil {
+		logger.Error("cannot fetch board of thread", "error", err, "thread_id", t.ID)
+		return err
+	}
+	newPosterIDs(s.posterSecret, b).stampComments(t, comments...)
+	return nil
+}
+
+// fillCommentDetails rewrites image URLs for the browser and fills in the
+// author's name and avatar from their session.
+func (s *CommentService) fillCommentDetails(ctx context.Context, comments []*comment.Comment) {
 	for _, c := range comments {
 		for i, url := range c.ImageURLs {
 			c.ImageURLs[i] = strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
@@ -150,7 +567,58 @@ func (s *CommentService) GetCommentsByThreadID(ctx context.Context, threadID uti
 			}
 		}
 	}
+}
 
-	logger.Info("comments retrieved", "thread_id", threadID, "count", len(comments))
-	return comments, nil
+// ThreadStream is a live subscription to one thread together with the
+// comments a reconnecting reader missed since their Last-Event-ID.
+type ThreadStream struct {
+	Thread *thread.Thread
+	Missed []*comment.Comment
+	Events <-chan event.Event
+	Close  func()
+}
+
+// SubscribeThread subscribes before loading missed comments so nothing
+// published in between is lost; callers should skip live comments that
+// are already in Missed. An unparseable lastEventID is treated as absent.
+func (s *CommentService) SubscribeThread(ctx context.Context, threadID utils.UUID, lastEventID string) (*ThreadStream, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, threadID)
+	if err != nil {
+		logger.Warn("cannot subscribe to thread", "thread_id", threadID, "error", err)
+		return nil, err
+	}
+
+	events, unsubscribe := s.events.Subscribe(threadID)
+	stream := &ThreadStream{Thread: t, Events: events, Close: unsubscribe}
+
+	if lastEventID == "" {
+		return stream, nil
+	}
+	if _, err := pagination.Decode(lastEventID); err != nil {
+		logger.Warn("ignoring invalid Last-Event-ID", "thread_id", threadID, "last_event_id", lastEventID)
+		return stream, nil
+	}
+
+	cursor := lastEventID
+	for {
+		page, err := s.GetCommentsByThreadIDPage(ctx, threadID, cursor, pagination.MaxLimit)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		stream.Missed = append(stream.Missed, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	return stream, nil
+}
+
+// CommentEventID is the SSE event ID of a comment. It is a comment cursor, so
+// a Last-Event-ID can be fed straight back into the keyset query. The time is
+// cut to microseconds to match what Postgres stores.
+func CommentEventID(c *comment.Comment) string {
+	return pagination.Cursor{Time: c.CreatedAt.Truncate(time.Microsecond), ID: c.ID}.Encode()
 }
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/thread"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// ExpirySettings chooses the thread.ExpiryPolicy of every board.
+type ExpirySettings struct {
+	// Policy is the default policy kind, one of the thread.Policy* values.
+	Policy string
+	// Boards overrides Policy for single boards, keyed by slug.
+	Boards map[string]string
+	TTL    thread.FixedTTL
+	// Capacity is the bump-order capacity of boards that set no MaxThreads.
+	Capacity int
+	// ArchiveRetention is how long archived threads are kept before they
+	// are purged with their media. Zero keeps them forever.
+	ArchiveRetention time.Duration
+}
+
+// DefaultExpirySettings is the historical rule: 10 minutes without replies,
+// 15 minutes after the last one, plus each board's own thread cap.
+func DefaultExpirySettings() ExpirySettings {
+	return ExpirySettings{
+		Policy: thread.PolicyTTLCapacity,
+		TTL: thread.FixedTTL{
+			NoReplies:  10 * time.Minute,
+			AfterReply: 15 * time.Minute,
+		},
+	}
+}
+
+func (e ExpirySettings) PolicyFor(b *board.Board) thread.ExpiryPolicy {
+	kind := e.Policy
+	if override, ok := e.Boards[b.Slug]; ok {
+		kind = override
+	}
+
+	capacity := e.Capacity
+	if b.MaxThreads > 0 {
+		capacity = b.MaxThreads
+	}
+	return thread.NewExpiryPolicy(kind, e.TTL, capacity)
+}
+
+// boardPolicies maps board IDs to their policies. Threads of a board that is
+// not in the map fall back to the default policy without a capacity.
+type boardPolicies struct {
+	byBoard  map[uuidHelper.UUID]thread.ExpiryPolicy
+	fallback thread.ExpiryPolicy
+	posters  posterIDs
+}
+
+func (e ExpirySettings) forBoards(boards []*board.Board) boardPolicies {
+	p := boardPolicies{
+		byBoard:  make(map[uuidHelper.UUID]thread.ExpiryPolicy, len(boards)),
+		fallback: thread.NewExpiryPolicy(e.Policy, e.TTL, 0),
+	}
+	for _, b := range boards {
+		p.byBoard[b.ID] = e.PolicyFor(b)
+	}
+	return p
+}
+
+func (p boardPolicies) of(boardID uuidHelper.UUID) thread.ExpiryPolicy {
+	if policy, ok := p.byBoard[boardID]; ok {
+		return policy
+	}
+	return p.fallback
+}
+
+// stamp fills ExpiresAt of the threads that can still expire: active or
+// locked, and not pinned. It fills the poster IDs too, so every loaded
+// thread gets both.
+func (p boardPolicies) stamp(threads ...*thread.Thread) {
+	for _, t := range threads {
+		t.ExpiresAt = nil
+		if expirable(t) {
+			t.ExpiresAt = p.of(t.BoardID).ExpiresAt(t)
+		}
+	}
+	p.posters.stampThreads(threads...)
+}
+
+func expirable(t *thread.Thread) bool {
+	return (t.State == thread.StateActive || t.State == thread.StateLocked) && !t.IsPinned
+}
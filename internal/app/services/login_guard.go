@@ -0,0 +1,74 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/flood"
+	"context"
+	"time"
+)
+
+// LoginGuard throttles moderator logins per address and caps how many
+// passwords are hashed at once, since every hash costs memory. A nil guard
+// lets every login through.
+type LoginGuard struct {
+	store  ports.FloodPort
+	rules  flood.LoginRules
+	hashes chan struct{}
+}
+
+// NewLoginGuard keeps the attempts in store, usually the one of the post
+// guard. maxHashes of 0 or less does not cap hashing.
+func NewLoginGuard(store ports.FloodPort, rules flood.LoginRules, maxHashes int) *LoginGuard {
+	g := &LoginGuard{store: store, rules: rules}
+	if maxHashes > 0 {
+		g.hashes = make(chan struct{}, maxHashes)
+	}
+	return g
+}
+
+// Check records a login attempt from ip and returns a *flood.LimitError
+// when the address has used up its attempts.
+func (g *LoginGuard) Check(ctx context.Context, ip string) error {
+	if g == nil {
+		return nil
+	}
+	claims := g.rules.LoginClaims(ip)
+	if len(claims) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var first time.Time
+	for _, c := range claims {
+		held, until, err := g.store.Acquire(ctx, []flood.Claim{c}, now)
+		if err != nil {
+			logger.Error("failed to check login attempts", "error", err)
+			return err
+		}
+		if held == "" {
+			return nil
+		}
+		if first.IsZero() || until.Before(first) {
+			first = until
+		}
+	}
+	logger.Warn("moderator login refused", "ip", ip, "reason", errors.ErrTooManyLoginAttempts)
+	return &flood.LimitError{Err: errors.ErrTooManyLoginAttempts, RetryAfter: first.Sub(now)}
+}
+
+// hash runs check once fewer than maxHashes others do, or gives up with
+// the context.
+func (g *LoginGuard) hash(ctx context.Context, check func() bool) (bool, error) {
+	if g == nil || g.hashes == nil {
+		return check(), nil
+	}
+	select {
+	case g.hashes <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	defer func() { <-g.hashes }()
+	return check(), nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"context"
+	"sync"
+	"time"
+)
+
+// ModeratorService logs moderators in and out and manages their accounts.
+type ModeratorService struct {
+	repo     ports.ModeratorPort
+	loginTTL time.Duration
+	guard    *LoginGuard
+
+	// decoyHash is checked for unknown usernames, so that they take as
+	// long to refuse as wrong passwords.
+	decoyOnce sync.Once
+	decoyHash string
+}
+
+func NewModeratorService(repo ports.ModeratorPort, loginTTL time.Duration, guard *LoginGuard) *ModeratorService {
+	return &ModeratorService{repo: repo, loginTTL: loginTTL, guard: guard}
+}
+
+// Bootstrap creates the first admin when there are no moderators yet, so
+// that the board can be administered from a fresh database. An empty
+// username skips it.
+func (s *ModeratorService) Bootstrap(ctx context.Context, username, password string) error {
+	if username == "" {
+		return nil
+	}
+	n, err := s.repo.CountModerators(ctx)
+	if err != nil || n > 0 {
+		return err
+	}
+	m, err := s.CreateModerator(ctx, username, password, moderator.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	logger.Info("first admin created", "username", m.Username)
+	return nil
+}
+
+func (s *ModeratorService) CreateModerator(ctx context.Context, username, password string, role moderator.Role) (*moderator.Moderator, error) {
+	m, err := moderator.NewModerator(username, password, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateModerator(ctx, m); err != nil {
+		return nil, err
+	}
+	logger.Info("moderator created", "username", m.Username, "role", m.Role)
+	return m, nil
+}
+
+func (s *ModeratorService) ListModerators(ctx context.Context) ([]*moderator.Moderator, error) {
+	return s.repo.ListModerators(ctx)
+}
+
+// Login checks a username and password and returns the moderator with a
+// new login and its token. Both a wrong username and a wrong password
+// give ErrInvalidCredentials. ip is the address the attempt comes from;
+// one that tried too often gets a *flood.LimitError.
+func (s *ModeratorService) Login(ctx context.Context, username, password, ip string) (*moderator.Moderator, *moderator.Login, string, error) {
+	if err := s.guard.Check(ctx, ip); err != nil {
+		return nil, nil, "", err
+	}
+
+	// An unknown username is checked against the decoy, so that it takes
+	// as long to refuse as a wrong password.
+	hash := s.decoy()
+	m, err := s.repo.GetModeratorByUsername(ctx, username)
+	switch {
+	case err == errors.ErrModeratorNotFound:
+		m = nil
+	case err != nil:
+		return nil, nil, "", err
+	default:
+		hash = m.PasswordHash
+	}
+	ok, err := s.guard.hash(ctx, func() bool { return moderator.CheckPassword(hash, password) })
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if m == nil || !ok {
+		logger.Warn("moderator login failed", "username", username)
+		return nil, nil, "", errors.ErrInvalidCredentials
+	}
+
+	login, token, err := moderator.NewLogin(m.ID, s.loginTTL)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := s.repo.CreateLogin(ctx, login); err != nil {
+		return nil, nil, "", err
+	}
+	logger.Info("moderator logged in", "username", m.Username, "role", m.Role)
+	return m, login, token, nil
+}
+
+// Authenticate returns the moderator logged in with token, or
+// ErrLoginRequired.
+func (s *ModeratorService) Authenticate(ctx context.Context, token string) (*moderator.Moderator, error) {
+	if token == "" {
+		return nil, errors.ErrLoginRequired
+	}
+	login, err := s.repo.GetLogin(ctx, moderator.HashToken(token), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.repo.GetModeratorByID(ctx, login.ModeratorID)
+	if err == errors.ErrModeratorNotFound {
+		return nil, errors.ErrLoginRequired
+	}
+	return m, err
+}
+
+func (s *ModeratorService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.repo.DeleteLogin(ctx, moderator.HashToken(token))
+}
+
+// Cleanup forgets expired logins.
+func (s *ModeratorService) Cleanup(ctx context.Context) error {
+	return s.repo.DeleteExpiredLogins(ctx, time.Now())
+}
+
+func (s *ModeratorService) decoy() string {
+	s.decoyOnce.Do(func() {
+		hash, err := moderator.HashPassword("not the password of anyone")
+		if err != nil {
+			logger.Error("failed to hash decoy password", "error", err)
+		}
+		s.decoyHash = hash
+	})
+	return s.decoyHash
+}
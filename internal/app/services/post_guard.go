@@ -0,0 +1,65 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/flood"
+	"1337b04rd/internal/domain/post"
+	"context"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// PostGuard throttles posting per session and address and refuses content
+// that was posted moments ago. A nil guard lets every post through.
+type PostGuard struct {
+	store ports.FloodPort
+	rules flood.Rules
+}
+
+func NewPostGuard(store ports.FloodPort, rules flood.Rules) *PostGuard {
+	return &PostGuard{store: store, rules: rules}
+}
+
+// Check records a post about to be made and returns a *flood.LimitError
+// when it comes too soon. Call it after the post is validated, so rejected
+// posts do not count.
+func (g *PostGuard) Check(ctx context.Context, action post.Action, sessionID uuidHelper.UUID, ip, content string) error {
+	if g == nil {
+		return nil
+	}
+
+	claims := g.rules.Claims(action, sessionID.String(), ip, content)
+	if len(claims) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	held, until, err := g.store.Acquire(ctx, claims, now)
+	if err != nil {
+		logger.Error("failed to check flood claims", "error", err, "session_id", sessionID)
+		return err
+	}
+	if held == "" {
+		return nil
+	}
+
+	reason := errors.ErrPostingTooFast
+	for _, c := range claims {
+		if c.Key == held {
+			reason = c.Err
+		}
+	}
+	logger.Warn("post refused by flood guard", "session_id", sessionID, "ip", ip, "reason", reason)
+	return &flood.LimitError{Err: reason, RetryAfter: until.Sub(now)}
+}
+
+// Cleanup forgets expired claims.
+func (g *PostGuard) Cleanup(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	return g.store.DeleteExpired(ctx, time.Now())
+}
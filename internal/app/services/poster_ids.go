@@ -0,0 +1,45 @@
+package services
+
+import (
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/thread"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// posterIDs fills the per-thread poster IDs of posts on the boards that
+// show them. Posts on other boards keep an empty PosterID.
+type posterIDs struct {
+	secret []byte
+	boards map[uuidHelper.UUID]bool
+}
+
+func newPosterIDs(secret []byte, boards ...*board.Board) posterIDs {
+	p := posterIDs{secret: secret, boards: make(map[uuidHelper.UUID]bool, len(boards))}
+	for _, b := range boards {
+		if b.PosterIDs {
+			p.boards[b.ID] = true
+		}
+	}
+	return p
+}
+
+func (p posterIDs) stampThreads(threads ...*thread.Thread) {
+	for _, t := range threads {
+		t.PosterID = ""
+		if p.boards[t.BoardID] {
+			t.PosterID = thread.PosterID(p.secret, t.SessionID, t.ID)
+		}
+	}
+}
+
+// stampComments fills the poster IDs of comments on t.
+func (p posterIDs) stampComments(t *thread.Thread, comments ...*comment.Comment) {
+	for _, c := range comments {
+		c.PosterID = ""
+		if p.boards[t.BoardID] {
+			c.PosterID = thread.PosterID(p.secret, c.SessionID, t.ID)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/report"
+	"context"
+	"fmt"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// ReportService takes reports from readers and lets moderators work
+// through them.
+type ReportService struct {
+	reportRepo  ports.ReportPort
+	threadRepo  ports.ThreadPort
+	commentRepo ports.CommentPort
+	threadSvc   *ThreadService
+	commentSvc  *CommentService
+	banSvc      *BanService
+}
+
+func NewReportService(reportRepo ports.ReportPort, threadRepo ports.ThreadPort, commentRepo ports.CommentPort, threadSvc *ThreadService, commentSvc *CommentService, banSvc *BanService) *ReportService {
+	return &ReportService{
+		reportRepo:  reportRepo,
+		threadRepo:  threadRepo,
+		commentRepo: commentRepo,
+		threadSvc:   threadSvc,
+		commentSvc:  commentSvc,
+		banSvc:      banSvc,
+	}
+}
+
+// Report files a session's report on a thread or comment.
+func (s *ReportService) Report(ctx context.Context, sessionID, postID uuidHelper.UUID, reason report.Reason, text string) (*report.Report, error) {
+	r, err := report.NewReport(postID, sessionID, reason, text)
+	if err != nil {
+		return nil, err
+	}
+
+	target, author, err := s.locate(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := report.NewCase(*target, author)
+	if err != nil {
+		return nil, err
+	}
+	if c, err = s.reportRepo.AddReport(ctx, c, r); err != nil {
+		return nil, err
+	}
+
+	logger.Info("post reported", "post_id", postID, "reason", reason, "case_id", c.ID, "score", c.Score)
+	return r, nil
+}
+
+// locate finds a live post by its ID and the session that wrote it.
+func (s *ReportService) locate(ctx context.Context, postID uuidHelper.UUID) (*post.Locator, uuidHelper.UUID, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, postID)
+	if err == nil {
+		return &post.Locator{Number: t.PostNumber, ThreadID: t.ID}, t.SessionID, nil
+	}
+	if err != errors.ErrThreadNotFound {
+		return nil, uuidHelper.UUID{}, err
+	}
+
+	c, err := s.commentRepo.GetCommentByID(ctx, postID)
+	if err == errors.ErrCommentNotFound || err == nil && c.IsDeleted {
+		return nil, uuidHelper.UUID{}, errors.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, uuidHelper.UUID{}, err
+	}
+	return &post.Locator{Number: c.PostNumber, ThreadID: c.ThreadID, CommentID: &c.ID}, c.SessionID, nil
+}
+
+// Queue returns the open cases, worst first.
+func (s *ReportService) Queue(ctx context.Context, limit int) ([]*report.Case, error) {
+	return s.reportRepo.ListOpenCases(ctx, pagination.ClampLimit(limit))
+}
+
+// Case returns a case with its reports, oldest first.
+func (s *ReportService) Case(ctx context.Context, id uuidHelper.UUID) (*report.Case, []*report.Report, error) {
+	c, err := s.reportRepo.GetCase(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	reports, err := s.reportRepo.ListReports(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, reports, nil
+}
+
+// Claim gives a case to the moderator, so that others leave it be for
+// report.ClaimTTL.
+func (s *ReportService) Claim(ctx context.Context, id uuidHelper.UUID, mod *moderator.Moderator) (*report.Case, error) {
+	c, err := s.reportRepo.GetCase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.claim(ctx, c, mod); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *ReportService) claim(ctx context.Context, c *report.Case, mod *moderator.Moderator) error {
+	now := time.Now()
+	if err := c.Claim(mod.ID, now); err != nil {
+		return err
+	}
+	return s.reportRepo.ClaimCase(ctx, c.ID, mod.ID, now, now.Add(-report.ClaimTTL))
+}
+
+// Resolve closes a case, claiming it first if need be. Deleting removes the
+// post. Banning also bans its author for banDuration, on the post's board
+// when boardOnly. Banning, and deleting a whole thread, need the moderator
+// role, as they do outside of reports. An empty banReason names the worst
+// reason the post was reported for.
+func (s *ReportService) Resolve(ctx context.Context, id uuidHelper.UUID, mod *moderator.Moderator, resolution report.Resolution, banReason string, banDuration time.Duration, boardOnly bool) (*report.Case, error) {
+	if !resolution.Valid() {
+		return nil, errors.ErrInvalidResolution
+	}
+	if resolution == report.ResolutionBan && !mod.Role.AtLeast(moderator.RoleModerator) {
+		return nil, errors.ErrForbidden
+	}
+
+	c, err := s.reportRepo.GetCase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Checked before the claim, so a refused janitor does not hold the case.
+	if resolution == report.ResolutionDelete && c.Post.IsThread() && !mod.Role.AtLeast(moderator.RoleModerator) {
+		return nil, errors.ErrForbidden
+	}
+	if err := s.claim(ctx, c, mod); err != nil {
+		return nil, err
+	}
+	if err := c.Resolve(mod.ID, resolution, time.Now()); err != nil {
+		return nil, err
+	}
+	// The author is banned while the post is still there to find them by.
+	var b *ban.Ban
+	if resolution == report.ResolutionBan {
+		if banReason == "" {
+			banReason = fmt.Sprintf("post No.%d: %s", c.Post.Number, c.TopReason())
+		}
+		if b, err = s.banSvc.BanAuthor(ctx, mod, c.Post.ID(), boardOnly, banReason, banDuration); err != nil {
+			return nil, err
+		}
+	}
+
+	// The post goes before the case is stored as resolved, so that a
+	// failure leaves the case open to retry. The ban is lifted again then,
+	// lest the retry ban twice; removing a removed post does nothing.
+	if resolution != report.ResolutionDismiss {
+		if err := s.removePost(ctx, c.Post); err != nil {
+			logger.Error("failed to remove reported post", "error", err, "case_id", c.ID, "post_id", c.Post.ID())
+			return nil, s.liftAfterFailure(ctx, b, mod, err)
+		}
+	}
+	// A removed thread takes its case along.
+	if resolution == report.ResolutionDismiss || !c.Post.IsThread() {
+		if err := s.reportRepo.ResolveCase(ctx, c); err != nil {
+			return nil, s.liftAfterFailure(ctx, b, mod, err)
+		}
+	}
+
+	logger.Info("report resolved", "case_id", c.ID, "post_id", c.Post.ID(), "resolution", resolution, "moderator", mod.Username)
+	return c, nil
+}
+
+// liftAfterFailure lifts b, if any, when resolving its case failed with
+// err, and returns err.
+func (s *ReportService) liftAfterFailure(ctx context.Context, b *ban.Ban, mod *moderator.Moderator, err error) error {
+	if b == nil {
+		return err
+	}
+	if liftErr := s.banSvc.Lift(ctx, b.ID, mod); liftErr != nil {
+		logger.Error("failed to lift the ban of an unresolved case", "error", liftErr, "ban_id", b.ID)
+	}
+	return err
+}
+
+// removePost removes a thread or comment unless it is gone already.
+func (s *ReportService) removePost(ctx context.Context, target post.Locator) error {
+	var err error
+	if target.IsThread() {
+		err = s.threadSvc.RemoveThread(ctx, target.ThreadID)
+	} else {
+		err = s.commentSvc.RemoveComment(ctx, *target.CommentID)
+	}
+	if err == errors.ErrThreadNotFound || err == errors.ErrCommentNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/search"
+	"context"
+)
+
+type SearchService struct {
+	searchRepo ports.SearchPort
+}
+
+func NewSearchService(searchRepo ports.SearchPort) *SearchService {
+	return &SearchService{searchRepo: searchRepo}
+}
+
+// Search runs a full-text query over threads and comments. status is one of
+// the search.Status values and defaults to all threads.
+func (s *SearchService) Search(ctx context.Context, text, status, cursor string, limit int) (*pagination.Page[*search.Result], error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in Search", "error", err)
+		return nil, err
+	}
+
+	q, err := search.NewQuery(text, status)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := pagination.DecodeOffset(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.ClampLimit(limit)
+
+	results, err := s.searchRepo.Search(ctx, q, offset, limit+1)
+	if err != nil {
+		logger.Error("failed to search", "error", err, "query", q.Text)
+		return nil, err
+	}
+
+	page := &pagination.Page[*search.Result]{Items: results}
+	if len(results) > limit {
+		page.Items = results[:limit]
+		page.NextCursor = pagination.EncodeOffset(offset + limit)
+	}
+	return page, nil
+}
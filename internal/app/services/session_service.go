@@ -4,7 +4,9 @@ import (
 	"1337b04rd/internal/app/common/logger"
 	"1337b04rd/internal/app/common/utils"
 	"1337b04rd/internal/app/ports"
+	domainErrors "1337b04rd/internal/domain/errors"
 	"1337b04rd/internal/domain/session"
+	"1337b04rd/internal/domain/tripcode"
 	"context"
 	"errors"
 	"time"
@@ -14,13 +16,16 @@ type SessionService struct {
 	repo       ports.SessionPort
 	avatarSvc  ports.AvatarPort
 	sessionTTL time.Duration
+	// tripcodePepper keys secure tripcodes; empty disables them.
+	tripcodePepper []byte
 }
 
-func NewSessionService(repo ports.SessionPort, avatarSvc ports.AvatarPort, ttl time.Duration) *SessionService {
+func NewSessionService(repo ports.SessionPort, avatarSvc ports.AvatarPort, ttl time.Duration, tripcodePepper string) *SessionService {
 	return &SessionService{
-		repo:       repo,
-		avatarSvc:  avatarSvc,
-		sessionTTL: ttl,
+		repo:           repo,
+		avatarSvc:      avatarSvc,
+		sessionTTL:     ttl,
+		tripcodePepper: []byte(tripcodePepper),
 	}
 }
 
@@ -89,10 +94,28 @@ func (s *SessionService) DeleteExpired(ctx context.Context) error {
 	return err
 }
 
-func (s *SessionService) UpdateDisplayName(ctx context.Context, id utils.UUID, newName string) error {
-	err := s.repo.UpdateDisplayName(ctx, id.String(), newName)
+// UpdateDisplayName renames a session. "name#secret" sets a tripcode as
+// well; a name without one must not be in use by another active session.
+func (s *SessionService) UpdateDisplayName(ctx context.Context, id utils.UUID, input string) (*session.Session, error) {
+	name, code, err := tripcode.Parse(input, s.tripcodePepper)
 	if err != nil {
-		logger.Error("failed to update display name", "id", id.String(), "error", err)
+		return nil, err
 	}
-	return err
+
+	sess, err := s.repo.GetSessionByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := sess.Rename(name, code); err != nil {
+		return nil, err
+	}
+
+	// Names with a tripcode may repeat; the code tells them apart.
+	if err := s.repo.UpdateDisplayName(ctx, id.String(), sess.DisplayName, sess.Tripcode); err != nil {
+		if err != domainErrors.ErrDisplayNameConflict {
+			logger.Error("failed to update display name", "id", id.String(), "error", err)
+		}
+		return nil, err
+	}
+	return sess, nil
 }
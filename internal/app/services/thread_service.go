@@ -2,13 +2,20 @@ package services
 
 import (
 	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
 	"1337b04rd/internal/app/ports"
+	"1337b04rd/internal/domain/comment"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/event"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/revision"
 	"1337b04rd/internal/domain/thread"
 	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"path"
 	"strings"
 	"time"
 
@@ -16,27 +23,97 @@ import (
 )
 
 type ThreadService struct {
-	threadRepo ports.ThreadPort
-	s3         ports.S3Port
+	threadRepo    ports.ThreadPort
+	boardRepo     ports.BoardPort
+	revisionRepo  ports.RevisionPort
+	referenceRepo ports.ReferencePort
+	s3            ports.S3Port
+	commentS3     ports.S3Port
+	events        ports.EventPort
+	expiry        ExpirySettings
+	// editWindow is how long after posting the author may edit or delete.
+	editWindow time.Duration
+	// posterSecret keys the poster IDs, see thread.PosterID.
+	posterSecret []byte
+	limits       post.Limits
+	guard        *PostGuard
 }
 
-func NewThreadService(threadRepo ports.ThreadPort, s3 ports.S3Port) *ThreadService {
-	return &ThreadService{threadRepo: threadRepo, s3: s3}
+func NewThreadService(
+	threadRepo ports.ThreadPort,
+	boardRepo ports.BoardPort,
+	revisionRepo ports.RevisionPort,
+	referenceRepo ports.ReferencePort,
+	s3 ports.S3Port,
+	commentS3 ports.S3Port,
+	events ports.EventPort,
+	expiry ExpirySettings,
+	editWindow time.Duration,
+	posterSecret string,
+	limits post.Limits,
+	guard *PostGuard,
+) *ThreadService {
+	return &ThreadService{
+		threadRepo:    threadRepo,
+		boardRepo:     boardRepo,
+		revisionRepo:  revisionRepo,
+		referenceRepo: referenceRepo,
+		s3:            s3,
+		commentS3:     commentS3,
+		events:        events,
+		expiry:        expiry,
+		editWindow:    editWindow,
+		posterSecret:  []byte(posterSecret),
+		limits:        limits,
+		guard:         guard,
+	}
+}
+
+// Limits are the size limits every thread is checked against.
+func (s *ThreadService) Limits() post.Limits {
+	return s.limits
 }
 
+// CreateThread posts a new thread on the board with the given slug,
+// signed with the session's tripcode when it has one. ip is the poster's
+// address for the flood guard.
 func (s *ThreadService) CreateThread(
 	ctx context.Context,
+	boardSlug string,
 	title, content string,
 	files map[string]io.Reader,
 	contentTypes map[string]string,
 	sessionID uuidHelper.UUID,
+	tripcode string,
+	ip string,
 ) (*thread.Thread, error) {
 	if err := ctx.Err(); err != nil {
 		logger.Warn("context canceled in CreateThread", "error", err)
 		return nil, err
 	}
 
-	var imageURLs []string
+	b, err := s.boardRepo.GetBoardBySlug(ctx, boardSlug)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.CheckImages(len(files)); err != nil {
+		return nil, err
+	}
+	if err := s.limits.CheckAttachments(len(files)); err != nil {
+		return nil, err
+	}
+
+	// Checked before the upload, so a rejected thread leaves no images.
+	t, err := thread.NewThread(b.ID, title, content, nil, sessionID, s.limits)
+	if err != nil {
+		return nil, err
+	}
+	t.Tripcode = tripcode
+
+	if err := s.guard.Check(ctx, post.ActionThread, sessionID, ip, content); err != nil {
+		return nil, err
+	}
+
 	if len(files) > 0 {
 		urls, err := s.s3.UploadImages(files, contentTypes)
 		if err != nil {
@@ -47,19 +124,16 @@ func (s *ThreadService) CreateThread(
 		// Заменяем minio:9000 на localhost:9000 в URL-адресах
 		for _, url := range urls {
 			updatedURL := strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
-			imageURLs = append(imageURLs, updatedURL)
+			t.ImageURLs = append(t.ImageURLs, updatedURL)
 		}
 	}
 
-	t, err := thread.NewThread(title, content, imageURLs, sessionID)
-	if err != nil {
-		return nil, err
-	}
-
 	if err := s.threadRepo.CreateThread(ctx, t); err != nil {
 		logger.Error("failed to create new thread", "error", err)
 		return nil, err
 	}
+	t.ExpiresAt = s.expiry.PolicyFor(b).ExpiresAt(t)
+	newPosterIDs(s.posterSecret, b).stampThreads(t)
 	return t, nil
 }
 
@@ -72,17 +146,30 @@ func (s *ThreadService) PrepareFilesFromMultipart(form *multipart.Form) (map[str
 		return files, contentTypes, nil
 	}
 
+	total := 0
+	for _, fileHeaders := range form.File {
+		total += len(fileHeaders)
+	}
+	if err := s.limits.CheckAttachments(total); err != nil {
+		return nil, nil, err
+	}
+
 	for _, fileHeaders := range form.File {
 		for _, fh := range fileHeaders {
+			if err := s.limits.CheckAttachmentSize(fh.Size); err != nil {
+				return nil, nil, err
+			}
 			file, err := fh.Open()
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to open file: %w", err)
+				logger.Error("failed to open uploaded file", "error", err, "filename", fh.Filename)
+				return nil, nil, errors.ErrImageUploadFailed
 			}
 			defer file.Close()
 
 			buf := new(bytes.Buffer)
 			if _, err := io.Copy(buf, file); err != nil {
-				return nil, nil, fmt.Errorf("failed to buffer file: %w", err)
+				logger.Error("failed to buffer uploaded file", "error", err, "filename", fh.Filename)
+				return nil, nil, errors.ErrImageUploadFailed
 			}
 
 			key := fmt.Sprintf("file_%d", counter)
@@ -112,6 +199,19 @@ func (s *ThreadService) GetThreadByID(ctx context.Context, id uuidHelper.UUID) (
 		t.ImageURLs[i] = strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
 	}
 
+	refs, err := s.referenceRepo.ListReferences(ctx, t.ID)
+	if err != nil {
+		logger.Error("failed to list thread references", "error", err, "thread_id", id)
+		return nil, err
+	}
+	t.RepliedBy = comment.LinkReplies(t.ID, nil, refs)
+
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(t)
+
 	return t, nil
 }
 
@@ -126,10 +226,17 @@ func (s *ThreadService) ListActiveThreads(ctx context.Context) ([]*thread.Thread
 		logger.Error("couldn't get a list of active threads", "error", err)
 		return nil, err
 	}
+
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(threads...)
+
 	now := time.Now()
 	var activeThreads []*thread.Thread
 	for _, t := range threads {
-		if !t.ShouldDelete(now) {
+		if !t.IsExpired(now) {
 			// Заменяем minio:9000 на localhost:9000 в ImageURLs
 			for i, url := range t.ImageURLs {
 				t.ImageURLs[i] = strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
@@ -152,6 +259,12 @@ func (s *ThreadService) ListAllThreads(ctx context.Context) ([]*thread.Thread, e
 		return nil, err
 	}
 
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(threads...)
+
 	// Заменяем minio:9000 на localhost:9000 в ImageURLs для всех тредов
 	for _, t := range threads {
 		for i, url := range t.ImageURLs {
@@ -162,6 +275,145 @@ func (s *ThreadService) ListAllThreads(ctx context.Context) ([]*thread.Thread, e
 	return threads, nil
 }
 
+// ListActiveThreadsPage returns one page of active threads, most recently
+// bumped first. An empty boardSlug lists every board.
+// Pinned threads are put in front of the first page and are not counted
+// against limit.
+// Threads that expired but were not cleaned up yet are dropped from the page,
+// so a page may hold fewer than limit items while NextCursor is still set.
+func (s *ThreadService) ListActiveThreadsPage(ctx context.Context, boardSlug, cursor string, limit int) (*pagination.Page[*thread.Thread], error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in ListActiveThreadsPage", "error", err)
+		return nil, err
+	}
+
+	boardID, err := s.boardFilter(ctx, boardSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.ClampLimit(limit)
+
+	threads, err := s.threadRepo.ListActiveThreadsPage(ctx, boardID, after, limit+1)
+	if err != nil {
+		logger.Error("couldn't get a page of active threads", "error", err)
+		return nil, err
+	}
+
+	page := pagination.NewPage(threads, limit, bumpCursor)
+
+	if after == nil {
+		pinned, err := s.threadRepo.ListPinnedThreads(ctx, boardID)
+		if err != nil {
+			logger.Error("couldn't get pinned threads", "error", err)
+			return nil, err
+		}
+		page.Items = append(pinned, page.Items...)
+	}
+
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(page.Items...)
+
+	now := time.Now()
+	activeThreads := make([]*thread.Thread, 0, len(page.Items))
+	for _, t := range page.Items {
+		if t.IsExpired(now) {
+			continue
+		}
+		for i, url := range t.ImageURLs {
+			t.ImageURLs[i] = strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
+		}
+		activeThreads = append(activeThreads, t)
+	}
+	page.Items = activeThreads
+
+	return page, nil
+}
+
+// ListAllThreadsPage returns one page of all threads, archived included,
+// newest first. An empty boardSlug lists every board.
+func (s *ThreadService) ListAllThreadsPage(ctx context.Context, boardSlug, cursor string, limit int) (*pagination.Page[*thread.Thread], error) {
+	if err := ctx.Err(); err != nil {
+		logger.Warn("context canceled in ListAllThreadsPage", "error", err)
+		return nil, err
+	}
+
+	boardID, err := s.boardFilter(ctx, boardSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	limit = pagination.ClampLimit(limit)
+
+	threads, err := s.threadRepo.ListAllThreadsPage(ctx, boardID, after, limit+1)
+	if err != nil {
+		logger.Error("failed to get a page of all threads", "error", err)
+		return nil, err
+	}
+
+	page := pagination.NewPage(threads, limit, threadCursor)
+
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(page.Items...)
+
+	for _, t := range page.Items {
+		for i, url := range t.ImageURLs {
+			t.ImageURLs[i] = strings.Replace(url, "http://minio:9000", "http://localhost:9000", 1)
+		}
+	}
+
+	return page, nil
+}
+
+func threadCursor(t *thread.Thread) pagination.Cursor {
+	return pagination.Cursor{Time: t.CreatedAt, ID: t.ID}
+}
+
+func bumpCursor(t *thread.Thread) pagination.Cursor {
+	return pagination.Cursor{Time: t.BumpedAt, ID: t.ID}
+}
+
+func (s *ThreadService) policies(ctx context.Context) (boardPolicies, error) {
+	boards, err := s.boardRepo.ListBoards(ctx)
+	if err != nil {
+		logger.Error("cannot get a list of boards", "error", err)
+		return boardPolicies{}, err
+	}
+	p := s.expiry.forBoards(boards)
+	p.posters = newPosterIDs(s.posterSecret, boards...)
+	return p, nil
+}
+
+// boardFilter resolves a board slug to the ID used to filter thread lists.
+// An empty slug means no filter.
+func (s *ThreadService) boardFilter(ctx context.Context, slug string) (*uuidHelper.UUID, error) {
+	if slug == "" {
+		return nil, nil
+	}
+	b, err := s.boardRepo.GetBoardBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return &b.ID, nil
+}
+
+// CleanupExpiredThreads archives the active threads that the expiry policy
+// of their board lets go, then purges archived threads older than the
+// archive retention.
 func (s *ThreadService) CleanupExpiredThreads(ctx context.Context) error {
 	threads, err := s.threadRepo.ListActiveThreads(ctx)
 	if err != nil {
@@ -169,32 +421,257 @@ func (s *ThreadService) CleanupExpiredThreads(ctx context.Context) error {
 		return err
 	}
 
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return err
+	}
+
+	byBoard := make(map[uuidHelper.UUID][]*thread.Thread)
+	for _, t := range threads {
+		if !expirable(t) {
+			continue
+		}
+		byBoard[t.BoardID] = append(byBoard[t.BoardID], t)
+	}
+
 	now := time.Now()
 	var lastErr error
+	for boardID, active := range byBoard {
+		for _, t := range policies.of(boardID).Expired(active, now) {
+			logger.Info("deleting expired thread", "thread_id", t.ID, "board_id", boardID)
+			if err := s.archiveThread(ctx, t, now); err != nil {
+				lastErr = err
+			}
+		}
+	}
 
+	if s.expiry.ArchiveRetention > 0 {
+		if err := s.purgeArchivedThreads(ctx, now.Add(-s.expiry.ArchiveRetention)); err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (s *ThreadService) purgeArchivedThreads(ctx context.Context, before time.Time) error {
+	threads, err := s.threadRepo.ListArchivedThreadsBefore(ctx, before)
+	if err != nil {
+		logger.Error("cannot get a list of archived threads", "error", err)
+		return err
+	}
+
+	var lastErr error
 	for _, t := range threads {
-		createdAgo := now.Sub(t.CreatedAt)
+		logger.Info("purging archived thread", "thread_id", t.ID)
+		if err := s.purgeThread(ctx, t); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// PurgeThread removes an archived thread, its comments and all their images.
+func (s *ThreadService) PurgeThread(ctx context.Context, id uuidHelper.UUID) error {
+	t, err := s.threadRepo.GetThreadByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.purgeThread(ctx, t)
+}
 
-		switch {
-		// 1. Удалить, если прошло >10 минут и комментариев не было
-		case t.LastCommented == nil && createdAgo > 10*time.Minute:
-			t.MarkAsDeleted()
-			logger.Info("deleting thread with no comments after 10 minutes", "thread_id", t.ID)
+func (s *ThreadService) purgeThread(ctx context.Context, t *thread.Thread) error {
+	if err := t.Purge(); err != nil {
+		return err
+	}
 
-		// 2. Удалить, если после последнего комментария прошло >15 минут
-		case t.LastCommented != nil && now.Sub(*t.LastCommented) > 15*time.Minute:
-			t.MarkAsDeleted()
-			logger.Info("deleting thread after 15 minutes of inactivity", "thread_id", t.ID)
+	commentImages, err := s.threadRepo.DeleteThread(ctx, t.ID)
+	if err != nil {
+		logger.Error("failed to delete thread", "error", err, "thread_id", t.ID)
+		return err
+	}
 
-		default:
-			continue
+	deleteMedia(s.s3, t.ImageURLs)
+	deleteMedia(s.commentS3, commentImages)
+	return nil
+}
+
+// deleteMedia removes stored images by the file name at the end of their
+// URL. Failures are logged only: the posts referencing them are gone.
+func deleteMedia(store ports.S3Port, urls []string) {
+	for _, url := range urls {
+		if err := store.DeleteFile(path.Base(url)); err != nil {
+			logger.Warn("failed to delete image", "error", err, "url", url)
 		}
+	}
+}
 
-		if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
-			logger.Error("failed to update (delete) thread", "error", err, "thread_id", t.ID)
-			lastErr = err
+// EditThread changes the title and content of a thread on behalf of its
+// author; a nil field is left as is. The replaced version is kept as a
+// revision.
+func (s *ThreadService) EditThread(ctx context.Context, id, sessionID uuidHelper.UUID, title, content *string) (*thread.Thread, error) {
+	t, err := s.authorThread(ctx, id, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := revision.NewRevision(revision.KindThread, t.ID, t.Title, t.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	newTitle, newContent := t.Title, t.Content
+	if title != nil {
+		newTitle = *title
+	}
+	if content != nil {
+		newContent = *content
+	}
+	if err := t.Edit(newTitle, newContent, time.Now(), s.limits); err != nil {
+		return nil, err
+	}
+
+	if err := s.revisionRepo.CreateRevision(ctx, rev); err != nil {
+		logger.Error("failed to save thread revision", "error", err, "thread_id", id)
+		return nil, err
+	}
+	if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
+		logger.Error("failed to update edited thread", "error", err, "thread_id", id)
+		return nil, err
+	}
+
+	policies, err := s.policies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policies.stamp(t)
+	return t, nil
+}
+
+// DeleteThread removes a thread on behalf of its author. It goes through
+// the archive and is purged right away, with its replies and images.
+func (s *ThreadService) DeleteThread(ctx context.Context, id, sessionID uuidHelper.UUID) error {
+	t, err := s.authorThread(ctx, id, sessionID)
+	if err != nil {
+		return err
+	}
+	return s.removeThread(ctx, t)
+}
+
+// RemoveThread removes a thread for a moderator, like DeleteThread but
+// whoever wrote it and whatever its state.
+func (s *ThreadService) RemoveThread(ctx context.Context, id uuidHelper.UUID) error {
+	t, err := s.threadRepo.GetThreadByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.removeThread(ctx, t)
+}
+
+func (s *ThreadService) removeThread(ctx context.Context, t *thread.Thread) error {
+	now := time.Now()
+	if !t.IsArchived() {
+		if err := t.Archive(now); err != nil {
+			return err
 		}
 	}
+	if err := s.purgeThread(ctx, t); err != nil {
+		return err
+	}
 
-	return lastErr
+	s.events.Publish(event.NewThreadExpired(t.ID, now))
+	return nil
+}
+
+// ResolvePost finds where a post number of the board with the given slug
+// leads. Numbers of purged posts are not found.
+func (s *ThreadService) ResolvePost(ctx context.Context, boardSlug string, number int64) (*post.Locator, error) {
+	if number <= 0 {
+		return nil, errors.ErrInvalidPostNumber
+	}
+	b, err := s.boardRepo.GetBoardBySlug(ctx, boardSlug)
+	if err != nil {
+		return nil, err
+	}
+	return s.threadRepo.GetPostByNumber(ctx, b.ID, number)
+}
+
+// ListThreadRevisions returns the earlier versions of a thread, oldest
+// first.
+func (s *ThreadService) ListThreadRevisions(ctx context.Context, id uuidHelper.UUID) ([]*revision.Revision, error) {
+	if _, err := s.threadRepo.GetThreadByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.ListRevisions(ctx, revision.KindThread, id)
+}
+
+// authorThread loads a thread that sessionID may still change.
+func (s *ThreadService) authorThread(ctx context.Context, id, sessionID uuidHelper.UUID) (*thread.Thread, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.CheckAuthor(sessionID, time.Now(), s.editWindow); err != nil {
+		return nil, err
+	}
+	if err := t.CheckWritable(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// PinThread pins or unpins a thread.
+func (s *ThreadService) PinThread(ctx context.Context, id uuidHelper.UUID, pinned bool) (*thread.Thread, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if pinned {
+		if err := t.Pin(); err != nil {
+			return nil, err
+		}
+	} else {
+		t.Unpin()
+	}
+	if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
+		logger.Error("failed to update thread pin", "error", err, "thread_id", id)
+		return nil, err
+	}
+	return t, nil
+}
+
+// LockThread locks or unlocks a thread for replies.
+func (s *ThreadService) LockThread(ctx context.Context, id uuidHelper.UUID, locked bool) (*thread.Thread, error) {
+	t, err := s.threadRepo.GetThreadByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked {
+		err = t.Lock()
+	} else {
+		err = t.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
+		logger.Error("failed to update thread lock", "error", err, "thread_id", id)
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *ThreadService) archiveThread(ctx context.Context, t *thread.Thread, now time.Time) error {
+	if err := t.Archive(now); err != nil {
+		return err
+	}
+	if err := s.threadRepo.UpdateThread(ctx, t); err != nil {
+		logger.Error("failed to update (delete) thread", "error", err, "thread_id", t.ID)
+		return err
+	}
+
+	s.events.Publish(event.NewThreadExpired(t.ID, now))
+	return nil
 }
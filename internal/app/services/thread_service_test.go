@@ -0,0 +1,500 @@
+package services_test
+
+import (
+	"1337b04rd/internal/app/common/logger"
+	"1337b04rd/internal/app/common/pagination"
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/app/services"
+	"1337b04rd/internal/domain/board"
+	"1337b04rd/internal/domain/comment"
+	domainErrors "1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/event"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/revision"
+	"1337b04rd/internal/domain/thread"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockThreadRepository struct {
+	threads map[utils.UUID]*thread.Thread
+	// commentImages are returned by DeleteThread, keyed by thread.
+	commentImages map[utils.UUID][]string
+}
+
+func NewMockThreadRepository() *MockThreadRepository {
+	return &MockThreadRepository{threads: make(map[utils.UUID]*thread.Thread), commentImages: make(map[utils.UUID][]string)}
+}
+
+func (m *MockThreadRepository) CreateThread(ctx context.Context, t *thread.Thread) error {
+	m.threads[t.ID] = t
+	return nil
+}
+
+func (m *MockThreadRepository) GetThreadByID(ctx context.Context, id utils.UUID) (*thread.Thread, error) {
+	t, ok := m.threads[id]
+	if !ok {
+		return nil, domainErrors.ErrThreadNotFound
+	}
+	return t, nil
+}
+
+func (m *MockThreadRepository) UpdateThread(ctx context.Context, t *thread.Thread) error {
+	m.threads[t.ID] = t
+	return nil
+}
+
+func (m *MockThreadRepository) ListActiveThreads(ctx context.Context) ([]*thread.Thread, error) {
+	var result []*thread.Thread
+	for _, t := range m.threads {
+		if !t.IsArchived() {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockThreadRepository) ListAllThreads(ctx context.Context) ([]*thread.Thread, error) {
+	var result []*thread.Thread
+	for _, t := range m.threads {
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+func (m *MockThreadRepository) ListArchivedThreadsBefore(ctx context.Context, before time.Time) ([]*thread.Thread, error) {
+	var result []*thread.Thread
+	for _, t := range m.threads {
+		if t.State == thread.StateArchived && t.ArchivedAt != nil && t.ArchivedAt.Before(before) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockThreadRepository) DeleteThread(ctx context.Context, id utils.UUID) ([]string, error) {
+	delete(m.threads, id)
+	return m.commentImages[id], nil
+}
+
+func (m *MockThreadRepository) ListPinnedThreads(ctx context.Context, boardID *utils.UUID) ([]*thread.Thread, error) {
+	var result []*thread.Thread
+	for _, t := range m.threads {
+		if t.IsPinned && !t.IsArchived() && (boardID == nil || t.BoardID == *boardID) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockThreadRepository) ListActiveThreadsPage(ctx context.Context, boardID *utils.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	return nil, nil
+}
+
+func (m *MockThreadRepository) ListAllThreadsPage(ctx context.Context, boardID *utils.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	return nil, nil
+}
+
+func (m *MockThreadRepository) GetPostByNumber(ctx context.Context, boardID utils.UUID, number int64) (*post.Locator, error) {
+	return nil, domainErrors.ErrPostNotFound
+}
+
+type MockBoardRepository struct {
+	boards []*board.Board
+}
+
+func (m *MockBoardRepository) GetBoardBySlug(ctx context.Context, slug string) (*board.Board, error) {
+	for _, b := range m.boards {
+		if b.Slug == slug {
+			return b, nil
+		}
+	}
+	return nil, domainErrors.ErrBoardNotFound
+}
+
+func (m *MockBoardRepository) GetBoardByID(ctx context.Context, id utils.UUID) (*board.Board, error) {
+	for _, b := range m.boards {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return nil, domainErrors.ErrBoardNotFound
+}
+
+func (m *MockBoardRepository) ListBoards(ctx context.Context) ([]*board.Board, error) {
+	return m.boards, nil
+}
+
+// MockS3 records the files it is asked to delete.
+type MockS3 struct {
+	deleted []string
+}
+
+func (m *MockS3) UploadImages(files map[string]io.Reader, contentTypes map[string]string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m *MockS3) UploadImage(file io.Reader, size int64, contentType string) (string, error) {
+	return "", nil
+}
+
+func (m *MockS3) DeleteFile(fileName string) error {
+	m.deleted = append(m.deleted, fileName)
+	return nil
+}
+
+// MockRevisionRepository keeps the revisions it is given.
+type MockRevisionRepository struct {
+	revisions []*revision.Revision
+}
+
+func (m *MockRevisionRepository) CreateRevision(ctx context.Context, r *revision.Revision) error {
+	m.revisions = append(m.revisions, r)
+	return nil
+}
+
+func (m *MockRevisionRepository) ListRevisions(ctx context.Context, kind revision.Kind, postID utils.UUID) ([]*revision.Revision, error) {
+	var result []*revision.Revision
+	for _, r := range m.revisions {
+		if r.Kind == kind && r.PostID == postID {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+type MockReferenceRepository struct{}
+
+func (m *MockReferenceRepository) SaveReferences(ctx context.Context, c *comment.Comment) error {
+	return nil
+}
+
+func (m *MockReferenceRepository) ListReferences(ctx context.Context, threadID utils.UUID) ([]*comment.Reference, error) {
+	return nil, nil
+}
+
+type MockEvents struct {
+	published []event.Event
+}
+
+func (m *MockEvents) Publish(e event.Event) {
+	m.published = append(m.published, e)
+}
+
+func (m *MockEvents) Subscribe(threadID utils.UUID) (<-chan event.Event, func()) {
+	return make(chan event.Event), func() {}
+}
+
+func mustBoard(t *testing.T, slug string, maxThreads, maxImages int) *board.Board {
+	t.Helper()
+	b, err := board.NewBoard(slug, "Board "+slug, "", maxThreads, maxImages, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCreateThread_BoardLimits(t *testing.T) {
+	logger.Init("test")
+	boards := &MockBoardRepository{boards: []*board.Board{mustBoard(t, "g", 0, 1)}}
+	svc := services.NewThreadService(NewMockThreadRepository(), boards, &MockRevisionRepository{}, &MockReferenceRepository{}, nil, nil, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+	sessionID, _ := utils.NewUUID()
+
+	files := map[string]io.Reader{"a": strings.NewReader("a"), "b": strings.NewReader("b")}
+	if _, err := svc.CreateThread(context.Background(), "g", "title", "content", files, nil, sessionID, "", ""); err != domainErrors.ErrTooManyImages {
+		t.Errorf("expected ErrTooManyImages, got %v", err)
+	}
+
+	if _, err := svc.CreateThread(context.Background(), "nope", "title", "content", nil, nil, sessionID, "", ""); err != domainErrors.ErrBoardNotFound {
+		t.Errorf("expected ErrBoardNotFound, got %v", err)
+	}
+
+	th, err := svc.CreateThread(context.Background(), "g", "title", "content", nil, nil, sessionID, "", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if th.BoardID != boards.boards[0].ID {
+		t.Errorf("thread was created on board %v, want %v", th.BoardID, boards.boards[0].ID)
+	}
+}
+
+func TestCleanupExpiredThreads_ArchivesOverflowPerBoard(t *testing.T) {
+	logger.Init("test")
+	small := mustBoard(t, "s", 2, 4)
+	unlimited := mustBoard(t, "u", 0, 4)
+	repo := NewMockThreadRepository()
+	events := &MockEvents{}
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{small, unlimited}}, &MockRevisionRepository{}, &MockReferenceRepository{}, nil, nil, events, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	now := time.Now()
+	add := func(b *board.Board, sinceBump time.Duration) *thread.Thread {
+		th, err := thread.NewThread(b.ID, "title", "content", nil, sessionID, post.DefaultLimits())
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.CreatedAt = now.Add(-sinceBump)
+		th.BumpedAt = th.CreatedAt
+		repo.threads[th.ID] = th
+		return th
+	}
+
+	oldest := add(small, 3*time.Minute)
+	middle := add(small, 2*time.Minute)
+	newest := add(small, time.Minute)
+	other := add(unlimited, 3*time.Minute)
+
+	if err := svc.CleanupExpiredThreads(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !oldest.IsArchived() {
+		t.Error("expected the least recently bumped thread of a full board to be archived")
+	}
+	if middle.IsArchived() || newest.IsArchived() {
+		t.Error("expected the most recent threads to stay active")
+	}
+	if other.IsArchived() {
+		t.Error("expected a board without a cap to keep its threads")
+	}
+	if len(events.published) != 1 || events.published[0].ThreadID != oldest.ID {
+		t.Errorf("expected one expiry event for the archived thread, got %+v", events.published)
+	}
+}
+
+func TestCleanupExpiredThreads_SkipsPinned(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "s", 1, 4)
+	repo := NewMockThreadRepository()
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, &MockRevisionRepository{}, &MockReferenceRepository{}, nil, nil, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	pinned, _ := thread.NewThread(b.ID, "rules", "content", nil, sessionID, post.DefaultLimits())
+	pinned.CreatedAt = time.Now().Add(-time.Hour)
+	pinned.BumpedAt = pinned.CreatedAt
+	pinned.Pin()
+	fresh, _ := thread.NewThread(b.ID, "fresh", "content", nil, sessionID, post.DefaultLimits())
+	repo.threads[pinned.ID] = pinned
+	repo.threads[fresh.ID] = fresh
+
+	if err := svc.CleanupExpiredThreads(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pinned.IsArchived() {
+		t.Error("expected a pinned thread to outlive its TTL")
+	}
+	if fresh.IsArchived() {
+		t.Error("expected a pinned thread not to take a slot of the board's capacity")
+	}
+}
+
+func TestCleanupExpiredThreads_ArchivesLocked(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "s", 1, 4)
+	repo := NewMockThreadRepository()
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, &MockRevisionRepository{}, &MockReferenceRepository{}, nil, nil, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	locked, _ := thread.NewThread(b.ID, "locked", "content", nil, sessionID, post.DefaultLimits())
+	locked.CreatedAt = time.Now().Add(-time.Hour)
+	locked.BumpedAt = locked.CreatedAt
+	if err := locked.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	fresh, _ := thread.NewThread(b.ID, "fresh", "content", nil, sessionID, post.DefaultLimits())
+	repo.threads[locked.ID] = locked
+	repo.threads[fresh.ID] = fresh
+
+	if err := svc.CleanupExpiredThreads(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !locked.IsArchived() || locked.ArchivedAt == nil {
+		t.Error("expected a locked thread to give up its slot of the board's capacity")
+	}
+	if fresh.IsArchived() {
+		t.Error("expected the fresh thread to stay active")
+	}
+}
+
+func TestCleanupExpiredThreads_PurgesAfterRetention(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "b", 0, 4)
+	repo := NewMockThreadRepository()
+	threadS3, commentS3 := &MockS3{}, &MockS3{}
+	settings := services.DefaultExpirySettings()
+	settings.ArchiveRetention = time.Hour
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, &MockRevisionRepository{}, &MockReferenceRepository{}, threadS3, commentS3, &MockEvents{}, settings, time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	old, _ := thread.NewThread(b.ID, "old", "content", []string{"http://localhost:9000/threads/a.png"}, sessionID, post.DefaultLimits())
+	if err := old.Archive(time.Now().Add(-2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	recent, _ := thread.NewThread(b.ID, "recent", "content", nil, sessionID, post.DefaultLimits())
+	if err := recent.Archive(time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	repo.threads[old.ID] = old
+	repo.threads[recent.ID] = recent
+	repo.commentImages[old.ID] = []string{"http://localhost:9000/comments/b.png"}
+
+	if err := svc.CleanupExpiredThreads(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := repo.threads[old.ID]; ok {
+		t.Error("expected the thread archived before the retention to be purged")
+	}
+	if _, ok := repo.threads[recent.ID]; !ok {
+		t.Error("expected a recently archived thread to be kept")
+	}
+	if len(threadS3.deleted) != 1 || threadS3.deleted[0] != "a.png" {
+		t.Errorf("expected the thread image to be deleted, got %v", threadS3.deleted)
+	}
+	if len(commentS3.deleted) != 1 || commentS3.deleted[0] != "b.png" {
+		t.Errorf("expected the comment image to be deleted, got %v", commentS3.deleted)
+	}
+}
+
+func TestThreadLifecycle_RejectsInvalidTransitions(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "b", 0, 4)
+	repo := NewMockThreadRepository()
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, &MockRevisionRepository{}, &MockReferenceRepository{}, &MockS3{}, &MockS3{}, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	th, _ := thread.NewThread(b.ID, "title", "content", nil, sessionID, post.DefaultLimits())
+	repo.threads[th.ID] = th
+
+	if err := svc.PurgeThread(context.Background(), th.ID); err != domainErrors.ErrInvalidThreadTransition {
+		t.Errorf("expected an active thread not to be purged, got %v", err)
+	}
+
+	if _, err := svc.LockThread(context.Background(), th.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.Archive(time.Now()); err != nil {
+		t.Fatalf("expected a locked thread to be archived, got %v", err)
+	}
+	if _, err := svc.LockThread(context.Background(), th.ID, false); err != domainErrors.ErrInvalidThreadTransition {
+		t.Errorf("expected an archived thread not to be unlocked, got %v", err)
+	}
+
+	if _, err := svc.LockThread(context.Background(), th.ID, true); err != domainErrors.ErrInvalidThreadTransition {
+		t.Errorf("expected an archived thread not to be locked, got %v", err)
+	}
+	if _, err := svc.PinThread(context.Background(), th.ID, true); err != domainErrors.ErrThreadArchived {
+		t.Errorf("expected an archived thread not to be pinned, got %v", err)
+	}
+	if th.CheckWritable() != domainErrors.ErrThreadArchived {
+		t.Error("expected an archived thread to reject replies")
+	}
+}
+
+func TestExpirySettings_PolicyFor(t *testing.T) {
+	settings := services.DefaultExpirySettings()
+	settings.Boards = map[string]string{"q": thread.PolicyCapacity}
+	ttlBoard := mustBoard(t, "b", 0, 4)
+	capacityBoard := mustBoard(t, "q", 0, 4)
+
+	sessionID, _ := utils.NewUUID()
+	th, err := thread.NewThread(ttlBoard.ID, "title", "content", nil, sessionID, post.DefaultLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := settings.PolicyFor(ttlBoard).ExpiresAt(th)
+	if at == nil || !at.Equal(th.CreatedAt.Add(10*time.Minute)) {
+		t.Errorf("expected expiry 10 minutes after creation, got %v", at)
+	}
+
+	replied := th.CreatedAt.Add(5 * time.Minute)
+	th.LastCommented = &replied
+	at = settings.PolicyFor(ttlBoard).ExpiresAt(th)
+	if at == nil || !at.Equal(replied.Add(15*time.Minute)) {
+		t.Errorf("expected expiry 15 minutes after the last reply, got %v", at)
+	}
+
+	if at := settings.PolicyFor(capacityBoard).ExpiresAt(th); at != nil {
+		t.Errorf("expected no deadline under a capacity-only policy, got %v", at)
+	}
+}
+
+func TestListActiveThreadsPage_HidesExpiredThreads(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "b", 0, 4)
+	repo := &pagedThreadRepository{MockThreadRepository: NewMockThreadRepository()}
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, &MockRevisionRepository{}, &MockReferenceRepository{}, nil, nil, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	sessionID, _ := utils.NewUUID()
+	fresh, _ := thread.NewThread(b.ID, "fresh", "content", nil, sessionID, post.DefaultLimits())
+	stale, _ := thread.NewThread(b.ID, "stale", "content", nil, sessionID, post.DefaultLimits())
+	stale.CreatedAt = time.Now().Add(-time.Hour)
+	repo.threads[fresh.ID] = fresh
+	repo.threads[stale.ID] = stale
+
+	page, err := svc.ListActiveThreadsPage(context.Background(), "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != fresh.ID {
+		t.Fatalf("expected only the fresh thread, got %d threads", len(page.Items))
+	}
+	if page.Items[0].ExpiresAt == nil {
+		t.Error("expected ExpiresAt to be filled in")
+	}
+}
+
+// pagedThreadRepository serves every active thread that is not pinned as a
+// single page.
+type pagedThreadRepository struct {
+	*MockThreadRepository
+}
+
+func (m *pagedThreadRepository) ListActiveThreadsPage(ctx context.Context, boardID *utils.UUID, after *pagination.Cursor, limit int) ([]*thread.Thread, error) {
+	active, _ := m.ListActiveThreads(ctx)
+	var result []*thread.Thread
+	for _, t := range active {
+		if !t.IsPinned {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+func TestEditThread_AuthorWithinWindow(t *testing.T) {
+	logger.Init("test")
+	b := mustBoard(t, "b", 0, 4)
+	repo := NewMockThreadRepository()
+	revisions := &MockRevisionRepository{}
+	svc := services.NewThreadService(repo, &MockBoardRepository{boards: []*board.Board{b}}, revisions, &MockReferenceRepository{}, &MockS3{}, &MockS3{}, &MockEvents{}, services.DefaultExpirySettings(), time.Hour, "secret", post.DefaultLimits(), nil)
+
+	author, _ := utils.NewUUID()
+	stranger, _ := utils.NewUUID()
+	th, _ := thread.NewThread(b.ID, "title", "content", nil, author, post.DefaultLimits())
+	repo.threads[th.ID] = th
+
+	title := "new title"
+	if _, err := svc.EditThread(context.Background(), th.ID, stranger, &title, nil); err != domainErrors.ErrNotPostAuthor {
+		t.Errorf("expected another session to be refused, got %v", err)
+	}
+
+	edited, err := svc.EditThread(context.Background(), th.ID, author, &title, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Title != "new title" || edited.Content != "content" || edited.EditedAt == nil {
+		t.Errorf("unexpected edited thread: %+v", edited)
+	}
+	if len(revisions.revisions) != 1 || revisions.revisions[0].Title != "title" {
+		t.Errorf("expected the previous title to be kept as a revision, got %v", revisions.revisions)
+	}
+
+	th.CreatedAt = time.Now().Add(-2 * time.Hour)
+	if _, err := svc.EditThread(context.Background(), th.ID, author, &title, nil); err != domainErrors.ErrEditWindowClosed {
+		t.Errorf("expected the edit window to be closed, got %v", err)
+	}
+}
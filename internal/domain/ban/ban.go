@@ -0,0 +1,91 @@
+// Package ban keeps people from writing to the board. A ban names a session,
+// an address or both, since a banned client that drops its cookie gets a
+// fresh session but usually keeps its address.
+package ban
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+const MaxReasonLength = 500
+
+type Ban struct {
+	ID uuidHelper.UUID
+	// SessionID and IPHash are what the ban matches; either may be unset,
+	// but not both.
+	SessionID *uuidHelper.UUID
+	IPHash    string
+	// BoardID limits the ban to one board; nil bans from every board.
+	BoardID   *uuidHelper.UUID
+	BoardSlug string
+	// Reason is shown to the banned poster.
+	Reason    string
+	CreatedBy *uuidHelper.UUID // nil once the moderator is gone
+	CreatedAt time.Time
+	ExpiresAt *time.Time // nil for a ban that never ends
+}
+
+// NewBan bans a session, an address or both. A zero duration never ends.
+func NewBan(sessionID *uuidHelper.UUID, ipHash, reason string, duration time.Duration, createdBy uuidHelper.UUID) (*Ban, error) {
+	if sessionID == nil && ipHash == "" {
+		return nil, errors.ErrBanTargetRequired
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > MaxReasonLength {
+		return nil, errors.ErrInvalidBanReason
+	}
+	if duration < 0 {
+		return nil, errors.ErrInvalidBanDuration
+	}
+
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	b := &Ban{
+		ID:        id,
+		SessionID: sessionID,
+		IPHash:    ipHash,
+		Reason:    reason,
+		CreatedBy: &createdBy,
+		CreatedAt: now,
+	}
+	if duration > 0 {
+		expires := now.Add(duration)
+		b.ExpiresAt = &expires
+	}
+	return b, nil
+}
+
+// Scope limits the ban to one board.
+func (b *Ban) Scope(boardID uuidHelper.UUID, slug string) {
+	b.BoardID = &boardID
+	b.BoardSlug = slug
+}
+
+// Covers reports whether the ban applies to a write on the board. A write
+// that belongs to no board, like a name change, only meets bans from every
+// board.
+func (b *Ban) Covers(boardID *uuidHelper.UUID) bool {
+	return b.BoardID == nil || boardID != nil && *b.BoardID == *boardID
+}
+
+// HashIP derives what is stored of an address. Without the salt the hash
+// cannot be matched against a list of addresses.
+func HashIP(salt []byte, ip string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
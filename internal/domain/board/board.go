@@ -0,0 +1,75 @@
+package board
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+	. "1337b04rd/internal/domain/errors"
+)
+
+// DefaultSlug is the board that receives threads created without one.
+const DefaultSlug = "b"
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]{1,16}$`)
+
+type Board struct {
+	ID          uuidHelper.UUID
+	Slug        string
+	Title       string
+	Description string
+	// MaxThreads caps the number of active threads; the least recently
+	// active ones are archived by cleanup. Zero means no cap.
+	MaxThreads int
+	// MaxImages is the number of images allowed per post.
+	MaxImages int
+	// BumpLimit is the number of bumps after which replies stop moving a
+	// thread up. Zero means no limit.
+	BumpLimit int
+	// PosterIDs shows each post with an ID derived from its session and
+	// thread, see thread.PosterID.
+	PosterIDs bool
+	CreatedAt time.Time
+}
+
+func NewBoard(slug, title, description string, maxThreads, maxImages, bumpLimit int) (*Board, error) {
+	if !ValidSlug(slug) {
+		return nil, ErrInvalidBoardSlug
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return nil, ErrEmptyBoardTitle
+	}
+	if maxThreads < 0 || maxImages < 0 || bumpLimit < 0 {
+		return nil, ErrInvalidBoardLimits
+	}
+
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Board{
+		ID:          id,
+		Slug:        slug,
+		Title:       title,
+		Description: strings.TrimSpace(description),
+		MaxThreads:  maxThreads,
+		MaxImages:   maxImages,
+		BumpLimit:   bumpLimit,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// CheckImages reports whether a post with n images fits the board limits.
+func (b *Board) CheckImages(n int) error {
+	if n > b.MaxImages {
+		return ErrTooManyImages
+	}
+	return nil
+}
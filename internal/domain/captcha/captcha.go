@@ -0,0 +1,58 @@
+// Package captcha holds the self-hosted CAPTCHA: a short code drawn as a
+// distorted image that a poster types back. A challenge is answered once,
+// right or wrong, so guessing needs a new image every time.
+package captcha
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"math/big"
+	"strings"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// Alphabet leaves out characters that are easy to confuse once distorted,
+// like O and 0 or I and 1.
+const Alphabet = "ACDEFHJKLMNPRTUVWXY34679"
+
+const AnswerLength = 5
+
+type Challenge struct {
+	ID        uuidHelper.UUID
+	Answer    string
+	ExpiresAt time.Time
+}
+
+func NewChallenge(ttl time.Duration) (*Challenge, error) {
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	answer := make([]byte, AnswerLength)
+	for i := range answer {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(Alphabet))))
+		if err != nil {
+			return nil, err
+		}
+		answer[i] = Alphabet[n.Int64()]
+	}
+
+	return &Challenge{
+		ID:        id,
+		Answer:    string(answer),
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+func (c *Challenge) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// Check compares a typed answer, ignoring case and surrounding spaces.
+func (c *Challenge) Check(answer string) bool {
+	typed := strings.ToUpper(strings.TrimSpace(answer))
+	return subtle.ConstantTimeCompare([]byte(typed), []byte(c.Answer)) == 1
+}
@@ -0,0 +1,157 @@
+package captcha
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand/v2"
+)
+
+const (
+	ImageWidth  = 200
+	ImageHeight = 70
+)
+
+// glyphs is a 5x7 bitmap font for Alphabet, one string per row.
+var glyphs = map[byte][7]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#", "#...#"},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "##.##", "#...#"},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'3': {"####.", "....#", "....#", ".###.", "....#", "....#", "####."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'6': {".###.", "#....", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "....#", ".###."},
+}
+
+// Image draws the answer with every character scaled, rotated and shifted
+// on its own, bends the whole text along a wave and covers it with noise.
+// The distortion is seeded with the challenge ID, so loading the image
+// again gives no new view of the text to average out the noise.
+func (c *Challenge) Image() image.Image {
+	rng := rand.New(rand.NewPCG(binary.BigEndian.Uint64(c.ID[:8]), binary.BigEndian.Uint64(c.ID[8:])))
+
+	text := image.NewRGBA(image.Rect(0, 0, ImageWidth, ImageHeight))
+	step := float64(ImageWidth-20) / float64(len(c.Answer))
+	for i := 0; i < len(c.Answer); i++ {
+		ink := color.RGBA{uint8(rng.IntN(100)), uint8(rng.IntN(100)), uint8(rng.IntN(120)), 255}
+		scale := 4.5 + rng.Float64()*1.5
+		angle := (rng.Float64() - 0.5) * 0.6
+		cx := 10 + step*(float64(i)+0.5) + (rng.Float64()-0.5)*6
+		cy := float64(ImageHeight)/2 + (rng.Float64()-0.5)*14
+		drawGlyph(text, glyphs[c.Answer[i]], cx, cy, scale, angle, ink)
+	}
+
+	img := image.NewRGBA(text.Bounds())
+	for y := 0; y < ImageHeight; y++ {
+		for x := 0; x < ImageWidth; x++ {
+			shade := uint8(215 + rng.IntN(40))
+			img.Set(x, y, color.RGBA{shade, shade, shade - uint8(rng.IntN(20)), 255})
+		}
+	}
+
+	amplitude := 3 + rng.Float64()*3
+	period := 40 + rng.Float64()*40
+	phase := rng.Float64() * 2 * math.Pi
+	for y := 0; y < ImageHeight; y++ {
+		for x := 0; x < ImageWidth; x++ {
+			sx := x + int(amplitude*math.Sin(2*math.Pi*float64(y)/period+phase))
+			sy := y + int(amplitude*math.Cos(2*math.Pi*float64(x)/period+phase))
+			if p := text.RGBAAt(sx, sy); p.A != 0 {
+				img.SetRGBA(x, y, p)
+			}
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		noise := color.RGBA{uint8(rng.IntN(160)), uint8(rng.IntN(160)), uint8(rng.IntN(160)), 255}
+		drawLine(img, rng.IntN(ImageWidth), rng.IntN(ImageHeight), rng.IntN(ImageWidth), rng.IntN(ImageHeight), noise)
+	}
+	for i := 0; i < ImageWidth*ImageHeight/25; i++ {
+		shade := uint8(rng.IntN(256))
+		img.SetRGBA(rng.IntN(ImageWidth), rng.IntN(ImageHeight), color.RGBA{shade, shade, shade, 255})
+	}
+	return img
+}
+
+// PNG encodes Image.
+func (c *Challenge) PNG() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawGlyph paints a glyph centred on (cx, cy), scale pixels per cell and
+// turned by angle radians, by mapping every pixel around it back into the
+// bitmap.
+func drawGlyph(img *image.RGBA, glyph [7]string, cx, cy, scale, angle float64, ink color.RGBA) {
+	sin, cos := math.Sincos(-angle)
+	radius := int(scale * 5)
+	for y := int(cy) - radius; y <= int(cy)+radius; y++ {
+		for x := int(cx) - radius; x <= int(cx)+radius; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			col := int(math.Floor((dx*cos-dy*sin)/scale + 2.5))
+			row := int(math.Floor((dx*sin+dy*cos)/scale + 3.5))
+			if row >= 0 && row < 7 && col >= 0 && col < 5 && glyph[row][col] == '#' {
+				img.SetRGBA(x, y, ink)
+			}
+		}
+	}
+}
+
+// drawLine is Bresenham's line, two pixels thick.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		img.SetRGBA(x0, y0+1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -5,28 +5,52 @@ import (
 
 	uuidHelper "1337b04rd/internal/app/common/utils"
 	. "1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
 )
 
 type Comment struct {
-	ID              uuidHelper.UUID  `json:"ID"`
-	ThreadID        uuidHelper.UUID  `json:"ThreadID"`
-	ParentCommentID *uuidHelper.UUID `json:"ParentCommentID"`
-	Content         string           `json:"Content"`
-	ImageURLs       []string         `json:"ImageURLs"`
-	SessionID       uuidHelper.UUID  `json:"SessionID"`
-	CreatedAt       time.Time        `json:"CreatedAt"`
-	IsDeleted       bool             `json:"IsDeleted"`
-	DisplayName     string           `json:"display_name"`
-	AvatarURL       string           `json:"avatar_url"`
+	ID              uuidHelper.UUID
+	ThreadID        uuidHelper.UUID
+	ParentCommentID *uuidHelper.UUID
+	Content         string
+	ImageURLs       []string
+	SessionID       uuidHelper.UUID
+	Tripcode        string // the author's at posting time, if any
+	CreatedAt       time.Time
+	EditedAt        *time.Time
+	// IsDeleted comments stay in place as tombstones so replies keep
+	// their parent.
+	IsDeleted bool
+	// IsSage replies do not bump the thread.
+	IsSage      bool
+	DisplayName string
+	AvatarURL   string
+	// References are the posts this comment links to with >>, its parent
+	// included; RepliedBy are the comments linking back to it.
+	References []uuidHelper.UUID
+	RepliedBy  []uuidHelper.UUID
+
+	// PostNumber shares its board's sequence with the threads.
+	PostNumber int64
+
+	// PosterID is its author's ID inside the thread, see thread.PosterID.
+	// Only set on boards that show poster IDs.
+	PosterID string
 }
 
-func NewComment(threadID uuidHelper.UUID, parentCommentID *uuidHelper.UUID, content string, imageURLs []string, sessionID uuidHelper.UUID, DisplayName string, AvatarURL string) (*Comment, error) {
+func NewComment(threadID uuidHelper.UUID, parentCommentID *uuidHelper.UUID, content string, imageURLs []string, sessionID uuidHelper.UUID, DisplayName string, AvatarURL string, limits post.Limits) (*Comment, error) {
 	if threadID.IsZero() {
 		return nil, ErrInvalidThreadID
 	}
 	if content == "" {
 		return nil, ErrEmptyContent
 	}
+	if err := limits.CheckContent(content); err != nil {
+		return nil, err
+	}
+	if err := limits.CheckAttachments(len(imageURLs)); err != nil {
+		return nil, err
+	}
 	if sessionID.IsZero() {
 		return nil, ErrInvalidSessionID
 	}
@@ -42,7 +66,7 @@ func NewComment(threadID uuidHelper.UUID, parentCommentID *uuidHelper.UUID, cont
 		return nil, err
 	}
 
-	return &Comment{
+	c := &Comment{
 		ID:              id,
 		ThreadID:        threadID,
 		ParentCommentID: parentCommentID,
@@ -53,9 +77,45 @@ func NewComment(threadID uuidHelper.UUID, parentCommentID *uuidHelper.UUID, cont
 		IsDeleted:       false,
 		DisplayName:     DisplayName,
 		AvatarURL:       AvatarURL,
-	}, nil
+	}
+	if err := c.setReferences(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
+// MarkAsDeleted makes the comment a tombstone; it no longer links
+// anywhere.
 func (c *Comment) MarkAsDeleted() {
 	c.IsDeleted = true
+	c.References = nil
+}
+
+// CheckAuthor allows changes by the session that posted the comment and
+// only within window after posting.
+func (c *Comment) CheckAuthor(sessionID uuidHelper.UUID, now time.Time, window time.Duration) error {
+	if c.SessionID != sessionID {
+		return ErrNotPostAuthor
+	}
+	if now.After(c.CreatedAt.Add(window)) {
+		return ErrEditWindowClosed
+	}
+	return nil
+}
+
+func (c *Comment) Edit(content string, now time.Time, limits post.Limits) error {
+	if content == "" {
+		return ErrEmptyContent
+	}
+	if err := limits.CheckContent(content); err != nil {
+		return err
+	}
+	prev := c.Content
+	c.Content = content
+	if err := c.setReferences(); err != nil {
+		c.Content = prev
+		return err
+	}
+	c.EditedAt = &now
+	return nil
 }
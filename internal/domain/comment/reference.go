@@ -0,0 +1,133 @@
+package comment
+
+import (
+	"regexp"
+	"strconv"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+	. "1337b04rd/internal/domain/errors"
+)
+
+// MaxReferences caps the >> links of a single comment.
+const MaxReferences = 16
+
+// Reference is a >> link from a comment to another post of its thread:
+// a comment or the thread itself.
+type Reference struct {
+	CommentID uuidHelper.UUID
+	TargetID  uuidHelper.UUID
+}
+
+var (
+	referencePattern = regexp.MustCompile(`>>([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+	numberPattern    = regexp.MustCompile(`>>([0-9]{1,18})`)
+)
+
+// ParseReferences returns the distinct post IDs quoted with >> in content,
+// in order of appearance.
+func ParseReferences(content string) []uuidHelper.UUID {
+	var ids []uuidHelper.UUID
+	for _, m := range referencePattern.FindAllStringSubmatch(content, -1) {
+		id, err := uuidHelper.ParseUUID(m[1])
+		if err != nil {
+			continue
+		}
+		ids = appendUnique(ids, id)
+	}
+	return ids
+}
+
+// ParsePostNumbers returns the distinct post numbers quoted with >> in
+// content, in order of appearance. Digits that start a UUID are not a
+// number.
+func ParsePostNumbers(content string) []int64 {
+	var numbers []int64
+	for _, m := range numberPattern.FindAllStringSubmatchIndex(content, -1) {
+		if m[1] < len(content) && isIDByte(content[m[1]]) {
+			continue
+		}
+		n, err := strconv.ParseInt(content[m[2]:m[3]], 10, 64)
+		if err != nil || n == 0 {
+			continue
+		}
+		if !containsNumber(numbers, n) {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// AddReference links the comment to one more post, such as one quoted by
+// its number.
+func (c *Comment) AddReference(id uuidHelper.UUID) error {
+	refs := appendUnique(c.References, id)
+	if len(refs) > MaxReferences {
+		return ErrTooManyReferences
+	}
+	c.References = refs
+	return nil
+}
+
+// setReferences links the comment to its parent and to every post quoted
+// in its content.
+func (c *Comment) setReferences() error {
+	var refs []uuidHelper.UUID
+	if c.ParentCommentID != nil {
+		refs = append(refs, *c.ParentCommentID)
+	}
+	for _, id := range ParseReferences(c.Content) {
+		refs = appendUnique(refs, id)
+	}
+	if len(refs) > MaxReferences {
+		return ErrTooManyReferences
+	}
+	c.References = refs
+	return nil
+}
+
+// LinkReplies fills in the links of comments, and the replies to the
+// thread's opening post, from the references of a thread.
+func LinkReplies(threadID uuidHelper.UUID, comments []*Comment, refs []*Reference) []uuidHelper.UUID {
+	byID := make(map[uuidHelper.UUID]*Comment, len(comments))
+	for _, c := range comments {
+		c.References = nil
+		c.RepliedBy = nil
+		byID[c.ID] = c
+	}
+
+	var threadReplies []uuidHelper.UUID
+	for _, r := range refs {
+		if c, ok := byID[r.CommentID]; ok {
+			c.References = append(c.References, r.TargetID)
+		}
+		if r.TargetID == threadID {
+			threadReplies = append(threadReplies, r.CommentID)
+		}
+		if c, ok := byID[r.TargetID]; ok {
+			c.RepliedBy = append(c.RepliedBy, r.CommentID)
+		}
+	}
+	return threadReplies
+}
+
+func appendUnique(ids []uuidHelper.UUID, id uuidHelper.UUID) []uuidHelper.UUID {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func containsNumber(numbers []int64, n int64) bool {
+	for _, existing := range numbers {
+		if existing == n {
+			return true
+		}
+	}
+	return false
+}
+
+func isIDByte(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,64 @@
+package comment
+
+import (
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+const (
+	// DefaultTreeDepth is how deep a reply tree goes unless asked otherwise.
+	DefaultTreeDepth = 8
+	// MaxTreeDepth caps the depth a client may ask for.
+	MaxTreeDepth = 32
+)
+
+// Node is a comment in a reply tree. Depth counts from the roots of the
+// tree at 0. ChildCount is the number of direct replies, including those
+// left out below the depth limit, so a node with replies but no Children
+// was collapsed.
+type Node struct {
+	Comment    *Comment
+	Depth      int
+	ChildCount int
+	Children   []*Node
+}
+
+// Collapsed reports whether the replies of the node were cut off by the
+// depth limit.
+func (n *Node) Collapsed() bool {
+	return n.ChildCount > 0 && len(n.Children) == 0
+}
+
+// ClampTreeDepth turns a requested depth into one in [0, MaxTreeDepth];
+// a negative depth means the default.
+func ClampTreeDepth(depth int) int {
+	switch {
+	case depth < 0:
+		return DefaultTreeDepth
+	case depth > MaxTreeDepth:
+		return MaxTreeDepth
+	}
+	return depth
+}
+
+// BuildTree nests nodes under their parents. nodes must be in chronological
+// order; children keep that order. A node whose parent is not among nodes
+// is a root.
+func BuildTree(nodes []*Node) []*Node {
+	byID := make(map[uuidHelper.UUID]*Node, len(nodes))
+	for _, n := range nodes {
+		n.Children = nil
+		byID[n.Comment.ID] = n
+	}
+
+	var roots []*Node
+	for _, n := range nodes {
+		if p := n.Comment.ParentCommentID; p != nil {
+			if parent, ok := byID[*p]; ok {
+				parent.Children = append(parent.Children, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+	return roots
+}
@@ -4,17 +4,31 @@ import "errors"
 
 var (
 	ErrCommentNotFound    = errors.New("comment not found")
+	ErrNotPostAuthor      = errors.New("post belongs to another session")
+	ErrEditWindowClosed   = errors.New("edit window has closed")
 	ErrInvalidCommentID   = errors.New("invalid comment ID")
 	ErrInvalidParentID    = errors.New("invalid parent comment ID")
+	ErrInvalidReference   = errors.New("reference to a post outside this thread")
+	ErrTooManyReferences  = errors.New("too many references")
 	ErrInvalidDisplayName = errors.New("invalid display name")
 
-	ErrThreadNotFound    = errors.New("thread not found")
-	ErrInvalidThreadID   = errors.New("invalid thread ID")
-	ErrEmptyTitle        = errors.New("thread title cannot be empty")
-	ErrEmptyContent      = errors.New("thread content cannot be empty")
-	ErrTooLongTitle      = errors.New("thread title is too long")
-	ErrTooLongContent    = errors.New("thread content is too long")
-	ErrImageUploadFailed = errors.New("failed to upload image for thread")
+	ErrThreadNotFound          = errors.New("thread not found")
+	ErrThreadLocked            = errors.New("thread is locked")
+	ErrThreadArchived          = errors.New("thread is archived")
+	ErrInvalidThreadTransition = errors.New("thread state transition not allowed")
+	ErrInvalidThreadID         = errors.New("invalid thread ID")
+	ErrPostNotFound            = errors.New("post not found")
+	ErrInvalidPostNumber       = errors.New("invalid post number")
+	ErrEmptyTitle              = errors.New("thread title cannot be empty")
+	ErrEmptyContent            = errors.New("thread content cannot be empty")
+	ErrTooLongTitle            = errors.New("title is too long")
+	ErrTooLongContent          = errors.New("content is too long")
+	ErrTooManyLines            = errors.New("content has too many lines")
+	ErrTooManyAttachments      = errors.New("too many attachments")
+	ErrAttachmentTooLarge      = errors.New("attachment is too large")
+	ErrImageUploadFailed       = errors.New("failed to read uploaded image")
+	ErrPostingTooFast          = errors.New("posting too fast")
+	ErrDuplicatePost           = errors.New("this content was posted recently")
 
 	ErrInvalidAvatar         = errors.New("avatar not found")
 	ErrInvalidUserName       = errors.New("username is invalid")
@@ -31,4 +45,50 @@ var (
 	ErrSessionExpired      = errors.New("session expired")
 	ErrAvatarAssignment    = errors.New("failed to assign avatar")
 	ErrDisplayNameConflict = errors.New("display name already in use")
+
+	ErrSecureTripcodeDisabled = errors.New("secure tripcodes are not enabled")
+
+	ErrCaptchaRequired = errors.New("captcha required")
+	ErrCaptchaInvalid  = errors.New("wrong or expired captcha")
+	ErrCaptchaNotFound = errors.New("captcha not found")
+
+	ErrProofOfWorkRequired = errors.New("proof of work required")
+	ErrProofOfWorkInvalid  = errors.New("invalid or expired proof of work")
+
+	ErrInvalidCredentials   = errors.New("wrong username or password")
+	ErrTooManyLoginAttempts = errors.New("too many login attempts")
+	ErrModeratorNotFound    = errors.New("moderator not found")
+	ErrModeratorExists      = errors.New("moderator already exists")
+	ErrInvalidModeratorName = errors.New("username must be 3 to 32 lowercase letters, digits or underscores")
+	ErrWeakPassword         = errors.New("password must be 10 to 256 characters")
+	ErrInvalidRole          = errors.New("role must be janitor, moderator or admin")
+	ErrLoginRequired        = errors.New("moderator login required")
+	ErrForbidden            = errors.New("not allowed for this role")
+
+	ErrInvalidReportReason = errors.New("reason must be illegal, abuse, spam, off_topic or other")
+	ErrReportTooLong       = errors.New("report text is too long")
+	ErrAlreadyReported     = errors.New("this post was already reported from this session")
+	ErrReportNotFound      = errors.New("report not found")
+	ErrReportClaimed       = errors.New("report is claimed by another moderator")
+	ErrReportResolved      = errors.New("report is already resolved")
+	ErrInvalidResolution   = errors.New("resolution must be dismiss, delete or ban")
+
+	ErrBanned             = errors.New("you are banned")
+	ErrBanTargetRequired  = errors.New("a ban needs a session or an address")
+	ErrInvalidBanReason   = errors.New("ban reason must be 1 to 500 characters")
+	ErrInvalidBanDuration = errors.New("ban duration cannot be negative")
+	ErrBanNotFound        = errors.New("ban not found")
+
+	ErrBoardNotFound      = errors.New("board not found")
+	ErrInvalidBoardSlug   = errors.New("invalid board slug")
+	ErrEmptyBoardTitle    = errors.New("board title cannot be empty")
+	ErrInvalidBoardLimits = errors.New("invalid board limits")
+	ErrTooManyImages      = errors.New("too many images for this board")
+
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	ErrInvalidLimit  = errors.New("invalid page limit")
+
+	ErrEmptySearchQuery    = errors.New("search query cannot be empty")
+	ErrTooLongSearchQuery  = errors.New("search query is too long")
+	ErrInvalidSearchStatus = errors.New("invalid search status filter")
 )
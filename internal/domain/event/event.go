@@ -0,0 +1,41 @@
+package event
+
+import (
+	"1337b04rd/internal/domain/comment"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type Type string
+
+const (
+	CommentCreated Type = "comment"
+	ThreadExpired  Type = "thread_expired"
+)
+
+// Event is something that happened to a thread that live readers care about.
+// Comment is set only for CommentCreated.
+type Event struct {
+	Type     Type
+	ThreadID uuidHelper.UUID
+	Comment  *comment.Comment
+	At       time.Time
+}
+
+func NewCommentCreated(c *comment.Comment) Event {
+	return Event{
+		Type:     CommentCreated,
+		ThreadID: c.ThreadID,
+		Comment:  c,
+		At:       c.CreatedAt,
+	}
+}
+
+func NewThreadExpired(threadID uuidHelper.UUID, at time.Time) Event {
+	return Event{
+		Type:     ThreadExpired,
+		ThreadID: threadID,
+		At:       at,
+	}
+}
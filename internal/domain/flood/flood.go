@@ -0,0 +1,114 @@
+package flood
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	. "1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+)
+
+// Rules say how often one session or address may post. A zero field turns
+// its check off.
+type Rules struct {
+	// PostInterval separates any two posts, ThreadInterval two new threads.
+	PostInterval   time.Duration
+	ThreadInterval time.Duration
+	// DuplicateWindow is how long the same session or address may not post
+	// the same content again, in any thread.
+	DuplicateWindow time.Duration
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		PostInterval:    10 * time.Second,
+		ThreadInterval:  time.Minute,
+		DuplicateWindow: 10 * time.Minute,
+	}
+}
+
+// Claim is a key a post holds for TTL; while it is held, posts that need
+// the same key fail with Err.
+type Claim struct {
+	Key string
+	TTL time.Duration
+	Err error
+}
+
+// Claims lists what a post by sessionID from ip must hold. An empty ip or
+// content skips the checks that need it.
+func (r Rules) Claims(action post.Action, sessionID, ip, content string) []Claim {
+	var claims []Claim
+	add := func(key string, ttl time.Duration, err error) {
+		if ttl > 0 {
+			claims = append(claims, Claim{Key: key, TTL: ttl, Err: err})
+		}
+	}
+
+	add("post:session:"+sessionID, r.PostInterval, ErrPostingTooFast)
+	if ip != "" {
+		add("post:ip:"+ip, r.PostInterval, ErrPostingTooFast)
+	}
+	if action == post.ActionThread {
+		add("thread:session:"+sessionID, r.ThreadInterval, ErrPostingTooFast)
+		if ip != "" {
+			add("thread:ip:"+ip, r.ThreadInterval, ErrPostingTooFast)
+		}
+	}
+	if hash := ContentHash(content); hash != "" {
+		add("content:session:"+sessionID+":"+hash, r.DuplicateWindow, ErrDuplicatePost)
+		if ip != "" {
+			add("content:ip:"+ip+":"+hash, r.DuplicateWindow, ErrDuplicatePost)
+		}
+	}
+	return claims
+}
+
+// LoginRules say how many moderator logins one address may try. Zero
+// Attempts turns the limit off.
+type LoginRules struct {
+	Attempts int
+	Window   time.Duration
+}
+
+func DefaultLoginRules() LoginRules {
+	return LoginRules{Attempts: 10, Window: 15 * time.Minute}
+}
+
+// LoginClaims are the slots of logins from ip. An attempt takes any one of
+// them, so an address gets Attempts tries per Window.
+func (r LoginRules) LoginClaims(ip string) []Claim {
+	if r.Attempts <= 0 || r.Window <= 0 || ip == "" {
+		return nil
+	}
+	claims := make([]Claim, r.Attempts)
+	for i := range claims {
+		claims[i] = Claim{Key: "login:ip:" + ip + ":" + strconv.Itoa(i), TTL: r.Window, Err: ErrTooManyLoginAttempts}
+	}
+	return claims
+}
+
+// ContentHash identifies content regardless of case and whitespace, so
+// trivial variations count as duplicates. It is empty for blank content.
+func ContentHash(content string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(content), " "))
+	if normalized == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// LimitError is a post refused by the guard, with the time until it would
+// be accepted.
+type LimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string { return e.Err.Error() }
+
+func (e *LimitError) Unwrap() error { return e.Err }
@@ -0,0 +1,173 @@
+// Package markup renders imageboard post markup to HTML.
+//
+//	>text                 greentext line
+//	>>id, >>123           link to a post of the thread, by ID or number
+//	[spoiler]…[/spoiler]  hidden until hovered, within a line
+//	`code`                inline code
+//	```                   fenced code block, on lines of their own
+//	http(s)://…           link
+//
+// Everything the poster wrote is escaped; the only tags in the output are
+// the ones produced for the markup above.
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+const (
+	fence        = "```"
+	spoilerOpen  = "[spoiler]"
+	spoilerClose = "[/spoiler]"
+)
+
+var (
+	quotePattern       = regexp.MustCompile(`^>>([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+	numberQuotePattern = regexp.MustCompile(`^>>([0-9]{1,18})`)
+	urlPattern         = regexp.MustCompile(`^https?://[^\s<>"'` + "`" + `]+`)
+)
+
+// Render turns content into HTML. Links to threadID point at the opening
+// post; a zero threadID treats every link as one to a comment.
+func Render(content string, threadID uuidHelper.UUID) string {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var b strings.Builder
+	prevBlock := true
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>")
+			prevBlock = true
+			continue
+		}
+
+		if !prevBlock {
+			b.WriteString("<br>")
+		}
+		renderLine(&b, lines[i], threadID)
+		prevBlock = false
+	}
+	return b.String()
+}
+
+func renderLine(b *strings.Builder, line string, threadID uuidHelper.UUID) {
+	if strings.HasPrefix(line, ">") && !quotePattern.MatchString(line) && numberQuote(line) == "" {
+		b.WriteString(`<span class="greentext">`)
+		renderInline(b, line, threadID)
+		b.WriteString("</span>")
+		return
+	}
+	renderInline(b, line, threadID)
+}
+
+func renderInline(b *strings.Builder, s string, threadID uuidHelper.UUID) {
+	openSpoilers := 0
+	plainStart := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[plainStart:end]))
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		var n int
+		switch {
+		case rest[0] == '`' && strings.IndexByte(rest[1:], '`') >= 0:
+			end := strings.IndexByte(rest[1:], '`') + 1
+			flush(i)
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(rest[1:end]))
+			b.WriteString("</code>")
+			n = end + 1
+		case strings.HasPrefix(rest, spoilerOpen):
+			flush(i)
+			b.WriteString(`<span class="spoiler">`)
+			openSpoilers++
+			n = len(spoilerOpen)
+		case strings.HasPrefix(rest, spoilerClose) && openSpoilers > 0:
+			flush(i)
+			b.WriteString("</span>")
+			openSpoilers--
+			n = len(spoilerClose)
+		case rest[0] == '>' && quotePattern.MatchString(rest):
+			m := quotePattern.FindStringSubmatch(rest)
+			flush(i)
+			writeQuote(b, m[1], threadID)
+			n = len(m[0])
+		case rest[0] == '>' && numberQuote(rest) != "":
+			number := numberQuote(rest)
+			flush(i)
+			b.WriteString(`<a href="#p` + number + `" class="quotelink">&gt;&gt;` + number + "</a>")
+			n = len(">>") + len(number)
+		case rest[0] == 'h' && (i == 0 || !isWordByte(s[i-1])) && urlPattern.MatchString(rest):
+			url := trimURL(urlPattern.FindString(rest))
+			flush(i)
+			escaped := html.EscapeString(url)
+			b.WriteString(`<a href="` + escaped + `" rel="nofollow noopener noreferrer" target="_blank">` + escaped + "</a>")
+			n = len(url)
+		default:
+			i++
+			continue
+		}
+		i += n
+		plainStart = i
+	}
+	flush(len(s))
+
+	for ; openSpoilers > 0; openSpoilers-- {
+		b.WriteString("</span>")
+	}
+}
+
+func writeQuote(b *strings.Builder, raw string, threadID uuidHelper.UUID) {
+	id, err := uuidHelper.ParseUUID(raw)
+	if err != nil {
+		b.WriteString(html.EscapeString(">>" + raw))
+		return
+	}
+	if !threadID.IsZero() && id == threadID {
+		b.WriteString(`<a href="#thread" class="quotelink">&gt;&gt;` + id.String() + " (OP)</a>")
+		return
+	}
+	b.WriteString(`<a href="#c-` + id.String() + `" class="quotelink">&gt;&gt;` + id.String() + "</a>")
+}
+
+// numberQuote returns the post number s starts quoting, or "". Digits
+// followed by more of an ID, like the start of a UUID, are no number.
+func numberQuote(s string) string {
+	m := numberQuotePattern.FindStringSubmatch(s)
+	if m == nil || len(m[0]) < len(s) && (s[len(m[0])] == '-' || isWordByte(s[len(m[0])])) {
+		return ""
+	}
+	return m[1]
+}
+
+// trimURL drops punctuation that more likely ends the sentence than the
+// link, and a closing parenthesis without an opening one.
+func trimURL(url string) string {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+			url = url[:len(url)-1]
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"):
+			url = url[:len(url)-1]
+		default:
+			return url
+		}
+	}
+	return url
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,117 @@
+// Package moderator holds the accounts of the people who look after the
+// board. They are unrelated to the anonymous sessions: a moderator logs in
+// with a username and password and gets a login of its own.
+package moderator
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// Role says what a moderator may do. Roles are ordered: each one may do
+// everything the ones before it may.
+type Role string
+
+const (
+	// RoleJanitor cleans up: handles reports and deletes posts.
+	RoleJanitor Role = "janitor"
+	// RoleModerator also bans and pins, locks or purges threads.
+	RoleModerator Role = "moderator"
+	// RoleAdmin also manages moderator accounts.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleJanitor: 1, RoleModerator: 2, RoleAdmin: 3}
+
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// AtLeast reports whether r may do what min may.
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[min]
+}
+
+const (
+	MinPasswordLength = 10
+	MaxPasswordLength = 256
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_]{3,32}$`)
+
+type Moderator struct {
+	ID           uuidHelper.UUID
+	Username     string
+	PasswordHash string
+	Role         Role
+	CreatedAt    time.Time
+}
+
+func NewModerator(username, password string, role Role) (*Moderator, error) {
+	if !usernamePattern.MatchString(username) {
+		return nil, errors.ErrInvalidModeratorName
+	}
+	if !role.Valid() {
+		return nil, errors.ErrInvalidRole
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Moderator{
+		ID:           id,
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// Login is a moderator's logged in browser. The token lives only in the
+// cookie; the server keeps its hash, so a leaked table logs nobody in.
+type Login struct {
+	TokenHash   string
+	ModeratorID uuidHelper.UUID
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// NewLogin returns a login for the moderator and the token to hand out.
+func NewLogin(moderatorID uuidHelper.UUID, ttl time.Duration) (*Login, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	now := time.Now()
+	return &Login{
+		TokenHash:   HashToken(token),
+		ModeratorID: moderatorID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, token, nil
+}
+
+// HashToken is what is stored of a login token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (l *Login) IsExpired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,10 @@
+package post
+
+// Action is a kind of post that checks like flood control and the CAPTCHA
+// can treat differently.
+type Action string
+
+const (
+	ActionThread  Action = "thread"
+	ActionComment Action = "comment"
+)
@@ -0,0 +1,72 @@
+package post
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	. "1337b04rd/internal/domain/errors"
+)
+
+// Limits bound what one thread or comment may hold. A zero field means no
+// limit.
+type Limits struct {
+	// MaxTitleLength and MaxContentLength count runes, not bytes.
+	MaxTitleLength   int
+	MaxContentLength int
+	MaxLines         int
+	// MaxAttachments is the cap on every board; a board may allow fewer.
+	MaxAttachments int
+	// MaxAttachmentSize is in bytes.
+	MaxAttachmentSize int64
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxTitleLength:    120,
+		MaxContentLength:  8000,
+		MaxLines:          150,
+		MaxAttachments:    4,
+		MaxAttachmentSize: 8 << 20,
+	}
+}
+
+func (l Limits) CheckTitle(title string) error {
+	if l.MaxTitleLength > 0 && utf8.RuneCountInString(title) > l.MaxTitleLength {
+		return ErrTooLongTitle
+	}
+	return nil
+}
+
+func (l Limits) CheckContent(content string) error {
+	if l.MaxContentLength > 0 && utf8.RuneCountInString(content) > l.MaxContentLength {
+		return ErrTooLongContent
+	}
+	if l.MaxLines > 0 && strings.Count(content, "\n")+1 > l.MaxLines {
+		return ErrTooManyLines
+	}
+	return nil
+}
+
+func (l Limits) CheckAttachments(n int) error {
+	if l.MaxAttachments > 0 && n > l.MaxAttachments {
+		return ErrTooManyAttachments
+	}
+	return nil
+}
+
+func (l Limits) CheckAttachmentSize(size int64) error {
+	if l.MaxAttachmentSize > 0 && size > l.MaxAttachmentSize {
+		return ErrAttachmentTooLarge
+	}
+	return nil
+}
+
+// MaxUploadSize bounds the request body of a post: every attachment at its
+// largest plus room for the text fields. Zero when attachments are not
+// limited.
+func (l Limits) MaxUploadSize() int64 {
+	if l.MaxAttachments == 0 || l.MaxAttachmentSize == 0 {
+		return 0
+	}
+	return int64(l.MaxAttachments)*l.MaxAttachmentSize + 1<<20
+}
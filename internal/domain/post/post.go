@@ -0,0 +1,28 @@
+// Package post addresses threads and comments alike by their number on a
+// board.
+package post
+
+import (
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+// Locator is where a post number leads: an opening post, or a comment of
+// ThreadID when CommentID is set.
+type Locator struct {
+	Number    int64
+	ThreadID  uuidHelper.UUID
+	CommentID *uuidHelper.UUID
+}
+
+// ID is the UUID of the numbered post itself.
+func (l *Locator) ID() uuidHelper.UUID {
+	if l.CommentID != nil {
+		return *l.CommentID
+	}
+	return l.ThreadID
+}
+
+// IsThread reports whether the number belongs to an opening post.
+func (l *Locator) IsThread() bool {
+	return l.CommentID == nil
+}
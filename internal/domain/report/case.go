@@ -0,0 +1,125 @@
+package report
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type Resolution string
+
+const (
+	// ResolutionDismiss leaves the post alone.
+	ResolutionDismiss Resolution = "dismiss"
+	// ResolutionDelete removes the post.
+	ResolutionDelete Resolution = "delete"
+	// ResolutionBan removes the post and bans its author.
+	ResolutionBan Resolution = "ban"
+)
+
+func (r Resolution) Valid() bool {
+	return r == ResolutionDismiss || r == ResolutionDelete || r == ResolutionBan
+}
+
+// ClaimTTL is how long a claim keeps other moderators off a case. A
+// moderator who walks away does not block it for good.
+const ClaimTTL = 30 * time.Minute
+
+// Case gathers the open reports on a post. Once resolved it is kept as a
+// record; later reports on the post open a new case.
+type Case struct {
+	ID   uuidHelper.UUID
+	Post post.Locator
+	// AuthorSessionID is the session that wrote the reported post.
+	AuthorSessionID uuidHelper.UUID
+	// Score is the sum of the weights of the reasons of its reports.
+	Score       int
+	ReportCount int
+	// Reasons counts the reports by reason.
+	Reasons        map[Reason]int
+	OpenedAt       time.Time
+	LastReportedAt time.Time
+
+	ClaimedBy *uuidHelper.UUID
+	ClaimedAt *time.Time
+
+	Resolution Resolution // empty while open
+	ResolvedBy *uuidHelper.UUID
+	ResolvedAt *time.Time
+}
+
+func NewCase(target post.Locator, authorSessionID uuidHelper.UUID) (*Case, error) {
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Case{
+		ID:              id,
+		Post:            target,
+		AuthorSessionID: authorSessionID,
+		Reasons:         make(map[Reason]int),
+		OpenedAt:        now,
+		LastReportedAt:  now,
+	}, nil
+}
+
+func (c *Case) IsOpen() bool {
+	return c.Resolution == ""
+}
+
+// Add counts a report towards the case.
+func (c *Case) Add(r *Report) {
+	r.CaseID = c.ID
+	c.Score += r.Reason.Weight()
+	c.ReportCount++
+	if c.Reasons == nil {
+		c.Reasons = make(map[Reason]int)
+	}
+	c.Reasons[r.Reason]++
+	c.LastReportedAt = r.CreatedAt
+}
+
+// TopReason is the reason of the case that weighs most.
+func (c *Case) TopReason() Reason {
+	var top Reason
+	for reason := range c.Reasons {
+		if top == "" || reason.Weight() > top.Weight() || reason.Weight() == top.Weight() && reason < top {
+			top = reason
+		}
+	}
+	return top
+}
+
+// Claim gives the case to a moderator. It fails while another moderator's
+// claim is fresh.
+func (c *Case) Claim(moderatorID uuidHelper.UUID, now time.Time) error {
+	if !c.IsOpen() {
+		return errors.ErrReportResolved
+	}
+	if c.ClaimedBy != nil && *c.ClaimedBy != moderatorID && now.Sub(*c.ClaimedAt) < ClaimTTL {
+		return errors.ErrReportClaimed
+	}
+	c.ClaimedBy = &moderatorID
+	c.ClaimedAt = &now
+	return nil
+}
+
+// Resolve closes a case the moderator has claimed.
+func (c *Case) Resolve(moderatorID uuidHelper.UUID, resolution Resolution, now time.Time) error {
+	if !resolution.Valid() {
+		return errors.ErrInvalidResolution
+	}
+	if !c.IsOpen() {
+		return errors.ErrReportResolved
+	}
+	if c.ClaimedBy == nil || *c.ClaimedBy != moderatorID {
+		return errors.ErrReportClaimed
+	}
+	c.Resolution = resolution
+	c.ResolvedBy = &moderatorID
+	c.ResolvedAt = &now
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package report holds what readers flag for the moderators. Reports on
+// one post are gathered into a case, which a moderator claims and resolves
+// as a whole; the case's score puts the worst posts first in the queue.
+package report
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+type Reason string
+
+const (
+	ReasonIllegal  Reason = "illegal"
+	ReasonAbuse    Reason = "abuse"
+	ReasonSpam     Reason = "spam"
+	ReasonOffTopic Reason = "off_topic"
+	ReasonOther    Reason = "other"
+)
+
+// reasonWeights are what a report adds to the score of its case: a single
+// report of illegal content outranks a handful of off-topic ones.
+var reasonWeights = map[Reason]int{
+	ReasonIllegal:  10,
+	ReasonAbuse:    4,
+	ReasonSpam:     3,
+	ReasonOffTopic: 1,
+	ReasonOther:    1,
+}
+
+func (r Reason) Valid() bool {
+	return reasonWeights[r] > 0
+}
+
+func (r Reason) Weight() int {
+	return reasonWeights[r]
+}
+
+const MaxTextLength = 1000
+
+// Report is one session's complaint about a post. A session reports a
+// post at most once.
+type Report struct {
+	ID        uuidHelper.UUID
+	CaseID    uuidHelper.UUID
+	PostID    uuidHelper.UUID
+	SessionID uuidHelper.UUID
+	Reason    Reason
+	Text      string
+	CreatedAt time.Time
+}
+
+func NewReport(postID, sessionID uuidHelper.UUID, reason Reason, text string) (*Report, error) {
+	if !reason.Valid() {
+		return nil, errors.ErrInvalidReportReason
+	}
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > MaxTextLength {
+		return nil, errors.ErrReportTooLong
+	}
+
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		ID:        id,
+		PostID:    postID,
+		SessionID: sessionID,
+		Reason:    reason,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}, nil
+}
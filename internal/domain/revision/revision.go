@@ -0,0 +1,49 @@
+package revision
+
+import (
+	"time"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+	. "1337b04rd/internal/domain/errors"
+)
+
+type Kind string
+
+const (
+	KindThread  Kind = "thread"
+	KindComment Kind = "comment"
+)
+
+// Revision is the version of a post that an edit replaced. Title is only
+// set for threads.
+type Revision struct {
+	ID        uuidHelper.UUID
+	Kind      Kind
+	PostID    uuidHelper.UUID
+	Title     string
+	Content   string
+	CreatedAt time.Time
+}
+
+func NewRevision(kind Kind, postID uuidHelper.UUID, title, content string) (*Revision, error) {
+	if postID.IsZero() {
+		if kind == KindThread {
+			return nil, ErrInvalidThreadID
+		}
+		return nil, ErrInvalidCommentID
+	}
+
+	id, err := uuidHelper.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{
+		ID:        id,
+		Kind:      kind,
+		PostID:    postID,
+		Title:     title,
+		Content:   content,
+		CreatedAt: time.Now(),
+	}, nil
+}
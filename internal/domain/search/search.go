@@ -0,0 +1,74 @@
+package search
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+	. "1337b04rd/internal/domain/errors"
+)
+
+const MaxQueryLength = 200
+
+// Snippets mark matched terms with these delimiters. They are control
+// characters, which repositories remove from the post text before they
+// mark it, so only the matches carry them; adapters escape the snippet
+// first and only then turn the delimiters into markup.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+type Kind string
+
+const (
+	KindThread  Kind = "thread"
+	KindComment Kind = "comment"
+)
+
+// Status restricts results by the lifecycle of the thread they belong to.
+type Status string
+
+const (
+	StatusAll      Status = "all"
+	StatusActive   Status = "active"
+	StatusArchived Status = "archived"
+)
+
+type Query struct {
+	Text   string
+	Status Status
+}
+
+type Result struct {
+	Kind        Kind
+	ThreadID    uuidHelper.UUID
+	CommentID   *uuidHelper.UUID
+	ThreadTitle string
+	Snippet     string
+	Rank        float64
+	CreatedAt   time.Time
+	IsArchived  bool
+}
+
+func NewQuery(text, status string) (*Query, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if utf8.RuneCountInString(text) > MaxQueryLength {
+		return nil, ErrTooLongSearchQuery
+	}
+
+	s := Status(status)
+	switch s {
+	case "":
+		s = StatusAll
+	case StatusAll, StatusActive, StatusArchived:
+	default:
+		return nil, ErrInvalidSearchStatus
+	}
+
+	return &Query{Text: text, Status: s}, nil
+}
@@ -2,17 +2,33 @@ package session
 
 import (
 	"1337b04rd/internal/domain/errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	uuidHelper "1337b04rd/internal/app/common/utils"
 )
 
+const (
+	MinDisplayNameLength = 2
+	MaxDisplayNameLength = 30
+)
+
 type Session struct {
 	ID          uuidHelper.UUID
 	AvatarURL   string
 	DisplayName string
-	CreatedAt   time.Time
-	ExpiresAt   time.Time
+	// Tripcode is shown after the display name and copied onto every post
+	// of the session; empty when the name was set without a secret.
+	Tripcode  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// CaptchasSolved counts the CAPTCHAs the session answered right; enough
+	// of them earn it trust, see Trusted.
+	CaptchasSolved int
+	// IPHash is the salted hash of the address the session last wrote
+	// from, so that banning the session can cover the address as well.
+	IPHash string
 }
 
 func NewSession(avatarURL, displayName string, duration time.Duration) (*Session, error) {
@@ -42,3 +58,22 @@ func NewSession(avatarURL, displayName string, duration time.Duration) (*Session
 func (s *Session) IsExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
+
+// Trusted reports whether the session solved at least after CAPTCHAs.
+// Zero trusts nobody.
+func (s *Session) Trusted(after int) bool {
+	return after > 0 && s.CaptchasSolved >= after
+}
+
+// Rename sets the display name and its tripcode. Names cannot contain the
+// characters that introduce a tripcode, so a plain name never passes for
+// one with a tripcode. The length counts characters.
+func (s *Session) Rename(name, tripcode string) error {
+	n := utf8.RuneCountInString(name)
+	if n < MinDisplayNameLength || n > MaxDisplayNameLength || strings.ContainsAny(name, "#!") {
+		return errors.ErrInvalidDisplayName
+	}
+	s.DisplayName = name
+	s.Tripcode = tripcode
+	return nil
+}
@@ -0,0 +1,137 @@
+package thread
+
+import (
+	"sort"
+	"time"
+)
+
+// Policy kinds accepted in configuration.
+const (
+	PolicyTTL         = "ttl"
+	PolicyCapacity    = "capacity"
+	PolicyTTLCapacity = "ttl+capacity"
+)
+
+// ExpiryPolicy decides when active threads of one board get archived.
+type ExpiryPolicy interface {
+	// ExpiresAt returns when t expires by time alone, or nil when the
+	// policy gives it no deadline.
+	ExpiresAt(t *Thread) *time.Time
+	// Expired picks the threads that must be archived at now out of the
+	// active threads of a single board.
+	Expired(active []*Thread, now time.Time) []*Thread
+}
+
+// FixedTTL expires a thread NoReplies after creation while nobody replied,
+// and AfterReply after its last reply otherwise.
+type FixedTTL struct {
+	NoReplies  time.Duration
+	AfterReply time.Duration
+}
+
+func (p FixedTTL) ExpiresAt(t *Thread) *time.Time {
+	var at time.Time
+	if t.LastCommented == nil {
+		at = t.CreatedAt.Add(p.NoReplies)
+	} else {
+		at = t.LastCommented.Add(p.AfterReply)
+	}
+	return &at
+}
+
+func (p FixedTTL) Expired(active []*Thread, now time.Time) []*Thread {
+	var expired []*Thread
+	for _, t := range active {
+		if now.After(*p.ExpiresAt(t)) {
+			expired = append(expired, t)
+		}
+	}
+	return expired
+}
+
+// BumpCapacity keeps the Max most recently bumped threads of a board and
+// pushes the rest off. Threads have no deadline under it.
+type BumpCapacity struct {
+	Max int
+}
+
+func (p BumpCapacity) ExpiresAt(t *Thread) *time.Time {
+	return nil
+}
+
+func (p BumpCapacity) Expired(active []*Thread, now time.Time) []*Thread {
+	if p.Max <= 0 || len(active) <= p.Max {
+		return nil
+	}
+
+	sorted := make([]*Thread, len(active))
+	copy(sorted, active)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].BumpedAt.After(sorted[j].BumpedAt)
+	})
+	return sorted[p.Max:]
+}
+
+// AllOf applies policies in order; each one only sees the threads the
+// previous ones kept, so a capacity after a TTL counts live threads only.
+type AllOf []ExpiryPolicy
+
+func (p AllOf) ExpiresAt(t *Thread) *time.Time {
+	var earliest *time.Time
+	for _, policy := range p {
+		if at := policy.ExpiresAt(t); at != nil && (earliest == nil || at.Before(*earliest)) {
+			earliest = at
+		}
+	}
+	return earliest
+}
+
+func (p AllOf) Expired(active []*Thread, now time.Time) []*Thread {
+	var expired []*Thread
+	remaining := active
+	for _, policy := range p {
+		out := policy.Expired(remaining, now)
+		if len(out) == 0 {
+			continue
+		}
+		expired = append(expired, out...)
+		remaining = without(remaining, out)
+	}
+	return expired
+}
+
+func without(threads, remove []*Thread) []*Thread {
+	removed := make(map[*Thread]bool, len(remove))
+	for _, t := range remove {
+		removed[t] = true
+	}
+	kept := make([]*Thread, 0, len(threads))
+	for _, t := range threads {
+		if !removed[t] {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// ValidPolicyKind reports whether kind names a known policy.
+func ValidPolicyKind(kind string) bool {
+	switch kind {
+	case PolicyTTL, PolicyCapacity, PolicyTTLCapacity:
+		return true
+	}
+	return false
+}
+
+// NewExpiryPolicy builds the policy of the given kind. An unknown kind falls
+// back to the TTL, the historical behaviour.
+func NewExpiryPolicy(kind string, ttl FixedTTL, capacity int) ExpiryPolicy {
+	switch kind {
+	case PolicyCapacity:
+		return BumpCapacity{Max: capacity}
+	case PolicyTTLCapacity:
+		return AllOf{ttl, BumpCapacity{Max: capacity}}
+	default:
+		return ttl
+	}
+}
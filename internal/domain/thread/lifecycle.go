@@ -0,0 +1,100 @@
+package thread
+
+import (
+	"time"
+
+	. "1337b04rd/internal/domain/errors"
+)
+
+// State is the lifecycle stage of a thread.
+//
+//	active ⇄ locked
+//	active, locked → archived → purged
+//
+// Active threads take replies, locked ones are listed but read-only,
+// archived ones only show up in the archive, and purged ones are gone
+// together with their media. Locked threads expire like active ones.
+type State string
+
+const (
+	StateActive   State = "active"
+	StateLocked   State = "locked"
+	StateArchived State = "archived"
+	StatePurged   State = "purged"
+)
+
+var transitions = map[State][]State{
+	StateActive:   {StateLocked, StateArchived},
+	StateLocked:   {StateActive, StateArchived},
+	StateArchived: {StatePurged},
+}
+
+func ValidState(s State) bool {
+	switch s {
+	case StateActive, StateLocked, StateArchived, StatePurged:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a thread in state s may move to next.
+func (s State) CanTransitionTo(next State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Thread) transition(next State) error {
+	if !t.State.CanTransitionTo(next) {
+		return ErrInvalidThreadTransition
+	}
+	t.State = next
+	return nil
+}
+
+func (t *Thread) Lock() error {
+	return t.transition(StateLocked)
+}
+
+func (t *Thread) Unlock() error {
+	return t.transition(StateActive)
+}
+
+// Archive moves an active or locked thread to the archive at now.
+func (t *Thread) Archive(now time.Time) error {
+	if err := t.transition(StateArchived); err != nil {
+		return err
+	}
+	t.ArchivedAt = &now
+	t.ExpiresAt = nil
+	return nil
+}
+
+// Purge marks an archived thread for removal; the caller deletes it and
+// its media.
+func (t *Thread) Purge() error {
+	return t.transition(StatePurged)
+}
+
+// IsArchived reports whether the thread left the active lists.
+func (t *Thread) IsArchived() bool {
+	return t.State == StateArchived || t.State == StatePurged
+}
+
+func (t *Thread) IsLocked() bool {
+	return t.State == StateLocked
+}
+
+// CheckWritable tells whether the thread accepts replies and edits.
+func (t *Thread) CheckWritable() error {
+	switch {
+	case t.IsArchived():
+		return ErrThreadArchived
+	case t.IsLocked():
+		return ErrThreadLocked
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package thread
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+
+	uuidHelper "1337b04rd/internal/app/common/utils"
+)
+
+const posterIDLength = 8
+
+// PosterID derives the ID a session posts under inside one thread. The same
+// session gets a different ID in every thread, and without the server's
+// secret an ID can not be traced back to the session.
+func PosterID(secret []byte, sessionID, threadID uuidHelper.UUID) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(sessionID[:])
+	mac.Write(threadID[:])
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:posterIDLength]
+}
@@ -5,25 +5,60 @@ import (
 
 	uuidHelper "1337b04rd/internal/app/common/utils"
 	. "1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
 )
 
 type Thread struct {
 	ID            uuidHelper.UUID
+	BoardID       uuidHelper.UUID
 	Title         string
 	Content       string
 	ImageURLs     []string
 	SessionID     uuidHelper.UUID
+	Tripcode      string // the author's at posting time, if any
 	CreatedAt     time.Time
 	LastCommented *time.Time
-	IsDeleted     bool
+	EditedAt      *time.Time
+	// State only changes through the transitions in lifecycle.go.
+	State      State
+	ArchivedAt *time.Time
+	// IsPinned threads are listed first and never expire.
+	IsPinned bool
+
+	// PostNumber is assigned by the database on insert, counting the
+	// threads and comments of the board together.
+	PostNumber int64
+
+	// BumpedAt orders the catalog. Replies move it forward unless they are
+	// sage or the board's bump limit is reached; the database trigger on
+	// comments keeps it and the counters up to date.
+	BumpedAt   time.Time
+	BumpCount  int
+	ReplyCount int
+
+	// ExpiresAt is computed from the board's ExpiryPolicy when the thread
+	// is loaded; it is not stored. Nil for archived threads and for
+	// policies without a deadline.
+	ExpiresAt *time.Time
+
+	// PosterID is derived when the thread is loaded on a board that shows
+	// poster IDs, see PosterID. Empty otherwise.
+	PosterID string
+
+	// RepliedBy are the comments linking to the thread with >>. Only
+	// filled in when a single thread is loaded.
+	RepliedBy []uuidHelper.UUID
 }
 
-func NewThread(title, content string, imageURLs []string, sessionID uuidHelper.UUID) (*Thread, error) {
-	if title == "" {
-		return nil, ErrEmptyTitle
+func NewThread(boardID uuidHelper.UUID, title, content string, imageURLs []string, sessionID uuidHelper.UUID, limits post.Limits) (*Thread, error) {
+	if boardID.IsZero() {
+		return nil, ErrBoardNotFound
 	}
-	if content == "" {
-		return nil, ErrEmptyContent
+	if err := checkText(title, content, limits); err != nil {
+		return nil, err
+	}
+	if err := limits.CheckAttachments(len(imageURLs)); err != nil {
+		return nil, err
 	}
 	if sessionID.IsZero() {
 		return nil, ErrInvalidSessionID
@@ -37,34 +72,69 @@ func NewThread(title, content string, imageURLs []string, sessionID uuidHelper.U
 	now := time.Now()
 	return &Thread{
 		ID:            id,
+		BoardID:       boardID,
 		Title:         title,
 		Content:       content,
 		ImageURLs:     imageURLs,
 		SessionID:     sessionID,
 		CreatedAt:     now,
 		LastCommented: nil,
-		IsDeleted:     false,
+		State:         StateActive,
+		BumpedAt:      now,
 	}, nil
 }
 
-func (t *Thread) ShouldDelete(now time.Time) bool {
-	if t.IsDeleted {
-		return false
+// IsExpired reports whether the thread passed its ExpiresAt. Threads the
+// policy gave no deadline never expire by time.
+func (t *Thread) IsExpired(now time.Time) bool {
+	return t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+// Pin keeps a live thread on top of its board. Archived threads cannot be
+// pinned.
+func (t *Thread) Pin() error {
+	if t.IsArchived() {
+		return ErrThreadArchived
 	}
+	t.IsPinned = true
+	return nil
+}
 
-	timeSinceCreation := now.Sub(t.CreatedAt)
+func (t *Thread) Unpin() {
+	t.IsPinned = false
+}
 
-	if t.LastCommented == nil && timeSinceCreation > 10*time.Minute {
-		return true
+// CheckAuthor allows changes by the session that started the thread and
+// only within window after posting.
+func (t *Thread) CheckAuthor(sessionID uuidHelper.UUID, now time.Time, window time.Duration) error {
+	if t.SessionID != sessionID {
+		return ErrNotPostAuthor
 	}
-
-	if t.LastCommented != nil && now.Sub(*t.LastCommented) > 15*time.Minute {
-		return true
+	if now.After(t.CreatedAt.Add(window)) {
+		return ErrEditWindowClosed
 	}
+	return nil
+}
 
-	return false
+func (t *Thread) Edit(title, content string, now time.Time, limits post.Limits) error {
+	if err := checkText(title, content, limits); err != nil {
+		return err
+	}
+	t.Title = title
+	t.Content = content
+	t.EditedAt = &now
+	return nil
 }
 
-func (t *Thread) MarkAsDeleted() {
-	t.IsDeleted = true
+func checkText(title, content string, limits post.Limits) error {
+	if title == "" {
+		return ErrEmptyTitle
+	}
+	if content == "" {
+		return ErrEmptyContent
+	}
+	if err := limits.CheckTitle(title); err != nil {
+		return err
+	}
+	return limits.CheckContent(content)
 }
@@ -0,0 +1,63 @@
+// Package tripcode derives the tripcodes that let a poster prove a name is
+// theirs without an account.
+//
+//	name#secret   name !code   the same code on every installation
+//	name##secret  name !!code  keyed with the server's pepper, so it can
+//	                           not be computed offline
+//
+// Only the code is kept; the secret never leaves the request.
+package tripcode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	. "1337b04rd/internal/domain/errors"
+)
+
+const (
+	marker       = "#"
+	secureMarker = "##"
+	codeLength   = 10
+)
+
+// publicKey keys ordinary tripcodes. It is not a secret: anyone may
+// compute them, which is what makes them portable.
+var publicKey = []byte("1337b04rd tripcode")
+
+// Parse splits "name#secret" into the name and the tripcode of the secret.
+// Input without a secret comes back as the name with an empty code.
+// Secure tripcodes need a pepper; without one they are rejected.
+func Parse(input string, pepper []byte) (name, code string, err error) {
+	i := strings.Index(input, marker)
+	if i < 0 {
+		return input, "", nil
+	}
+	name = strings.TrimSpace(input[:i])
+	rest := input[i:]
+
+	if strings.HasPrefix(rest, secureMarker) {
+		secret := rest[len(secureMarker):]
+		if secret == "" {
+			return name, "", nil
+		}
+		if len(pepper) == 0 {
+			return "", "", ErrSecureTripcodeDisabled
+		}
+		return name, "!!" + derive(pepper, secret), nil
+	}
+
+	secret := rest[len(marker):]
+	if secret == "" {
+		return name, "", nil
+	}
+	return name, "!" + derive(publicKey, secret), nil
+}
+
+func derive(key []byte, secret string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:codeLength]
+}
@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/ban"
+	"1337b04rd/internal/domain/errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBanValidation(t *testing.T) {
+	sessionID, _ := utils.NewUUID()
+	modID, _ := utils.NewUUID()
+
+	b, err := ban.NewBan(&sessionID, "", "  spam  ", time.Hour, modID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Reason != "spam" || b.ExpiresAt == nil || b.BoardID != nil {
+		t.Errorf("expected an hour-long ban from every board for spam, got %+v", b)
+	}
+	if b, _ := ban.NewBan(nil, "hash", "spam", 0, modID); b == nil || b.ExpiresAt != nil {
+		t.Errorf("expected a zero duration to never end, got %+v", b)
+	}
+	if _, err := ban.NewBan(nil, "", "spam", time.Hour, modID); err != errors.ErrBanTargetRequired {
+		t.Errorf("expected a ban without a target to be refused, got %v", err)
+	}
+	if _, err := ban.NewBan(&sessionID, "", " ", time.Hour, modID); err != errors.ErrInvalidBanReason {
+		t.Errorf("expected an empty reason to be refused, got %v", err)
+	}
+	if _, err := ban.NewBan(&sessionID, "", strings.Repeat("ё", ban.MaxReasonLength), time.Hour, modID); err != nil {
+		t.Errorf("expected the limit to count runes, got %v", err)
+	}
+	if _, err := ban.NewBan(&sessionID, "", strings.Repeat("a", ban.MaxReasonLength+1), time.Hour, modID); err != errors.ErrInvalidBanReason {
+		t.Errorf("expected a long reason to be refused, got %v", err)
+	}
+	if _, err := ban.NewBan(&sessionID, "", "spam", -time.Second, modID); err != errors.ErrInvalidBanDuration {
+		t.Errorf("expected a negative duration to be refused, got %v", err)
+	}
+}
+
+func TestBanCovers(t *testing.T) {
+	sessionID, _ := utils.NewUUID()
+	modID, _ := utils.NewUUID()
+
+	b, err := ban.NewBan(&sessionID, "", "spam", time.Hour, modID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	boardID, _ := utils.NewUUID()
+	otherBoard, _ := utils.NewUUID()
+	if !b.Covers(nil) || !b.Covers(&boardID) {
+		t.Error("expected a ban from every board to cover every write")
+	}
+	b.Scope(boardID, "b")
+	if !b.Covers(&boardID) || b.Covers(&otherBoard) || b.Covers(nil) {
+		t.Error("expected a board ban to cover only writes to that board")
+	}
+}
+
+func TestHashIP(t *testing.T) {
+	salt := []byte("salt")
+	if ban.HashIP(salt, "10.0.0.1") != ban.HashIP(salt, "10.0.0.1") {
+		t.Error("expected the hash to be stable")
+	}
+	if ban.HashIP(salt, "10.0.0.1") == ban.HashIP([]byte("pepper"), "10.0.0.1") {
+		t.Error("expected the hash to depend on the salt")
+	}
+	if h := ban.HashIP(salt, "10.0.0.1"); strings.Contains(h, "10.0.0.1") || len(h) != 64 {
+		t.Errorf("expected a hex digest, got %q", h)
+	}
+	if ban.HashIP(salt, "") != "" {
+		t.Error("expected no hash for an unknown address")
+	}
+}
@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/captcha"
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptchaChallenge(t *testing.T) {
+	c, err := captcha.NewChallenge(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Answer) != captcha.AnswerLength {
+		t.Errorf("expected a %d character answer, got %q", captcha.AnswerLength, c.Answer)
+	}
+	for _, r := range c.Answer {
+		if !strings.ContainsRune(captcha.Alphabet, r) {
+			t.Errorf("answer %q has %q outside the alphabet", c.Answer, r)
+		}
+	}
+
+	if !c.Check(" " + strings.ToLower(c.Answer) + "\n") {
+		t.Error("the answer must match regardless of case and surrounding spaces")
+	}
+	if c.Check(c.Answer[1:]) || c.Check("") {
+		t.Error("a wrong answer matched")
+	}
+
+	if c.IsExpired(time.Now()) || !c.IsExpired(c.ExpiresAt) {
+		t.Error("a challenge must expire exactly at ExpiresAt")
+	}
+}
+
+func TestCaptchaImage(t *testing.T) {
+	c, err := captcha.NewChallenge(time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := c.PNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != captcha.ImageWidth || b.Dy() != captcha.ImageHeight {
+		t.Errorf("unexpected image size %v", b)
+	}
+
+	again, err := c.PNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, again) {
+		t.Error("the image of a challenge must not change between loads")
+	}
+}
@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"1337b04rd/internal/adapters/memory"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/flood"
+	"1337b04rd/internal/domain/post"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFloodRules_Claims(t *testing.T) {
+	rules := flood.Rules{PostInterval: time.Second, ThreadInterval: time.Minute, DuplicateWindow: time.Hour}
+
+	if got := len(rules.Claims(post.ActionThread, "s", "1.2.3.4", "text")); got != 6 {
+		t.Errorf("thread: expected 6 claims, got %d", got)
+	}
+	if got := len(rules.Claims(post.ActionComment, "s", "1.2.3.4", "text")); got != 4 {
+		t.Errorf("comment: expected 4 claims, got %d", got)
+	}
+	if got := len(rules.Claims(post.ActionComment, "s", "", "  ")); got != 1 {
+		t.Errorf("comment without address and content: expected 1 claim, got %d", got)
+	}
+	if got := len(rules.Claims(post.ActionComment, "s", "", "text")); got != 2 {
+		t.Errorf("comment without address: expected 2 claims, got %d", got)
+	}
+
+	// The same content from two posters takes different keys.
+	duplicates := flood.Rules{DuplicateWindow: time.Hour}
+	mine := duplicates.Claims(post.ActionComment, "s", "1.2.3.4", "text")
+	theirs := duplicates.Claims(post.ActionComment, "t", "5.6.7.8", "text")
+	for _, a := range mine {
+		for _, b := range theirs {
+			if a.Key == b.Key {
+				t.Errorf("expected the content claims of two posters to differ, both take %q", a.Key)
+			}
+		}
+	}
+	if got := len(flood.Rules{}.Claims(post.ActionThread, "s", "1.2.3.4", "text")); got != 0 {
+		t.Errorf("zero rules: expected no claims, got %d", got)
+	}
+}
+
+func TestFloodLoginRules_LoginClaims(t *testing.T) {
+	rules := flood.LoginRules{Attempts: 3, Window: time.Minute}
+
+	claims := rules.LoginClaims("1.2.3.4")
+	if len(claims) != 3 || claims[0].Key == claims[1].Key || claims[0].Err != errors.ErrTooManyLoginAttempts {
+		t.Errorf("expected 3 distinct login slots, got %+v", claims)
+	}
+	if other := rules.LoginClaims("5.6.7.8"); other[0].Key == claims[0].Key {
+		t.Errorf("expected slots per address, got %q twice", claims[0].Key)
+	}
+	if got := len(rules.LoginClaims("")); got != 0 {
+		t.Errorf("login without address: expected no claims, got %d", got)
+	}
+	if got := len(flood.LoginRules{}.LoginClaims("1.2.3.4")); got != 0 {
+		t.Errorf("zero rules: expected no claims, got %d", got)
+	}
+}
+
+func TestFloodContentHash(t *testing.T) {
+	if flood.ContentHash("Hello   World\n") != flood.ContentHash("hello world") {
+		t.Error("case and whitespace must not change the hash")
+	}
+	if flood.ContentHash("hello world") == flood.ContentHash("hello, world") {
+		t.Error("different content gave the same hash")
+	}
+	if flood.ContentHash(" \n\t") != "" {
+		t.Error("blank content must have no hash")
+	}
+}
+
+func TestMemoryFloodStore(t *testing.T) {
+	store := memory.NewFloodStore()
+	ctx := context.Background()
+	now := time.Now()
+	a := flood.Claim{Key: "a", TTL: time.Minute, Err: errors.ErrPostingTooFast}
+	b := flood.Claim{Key: "b", TTL: time.Hour, Err: errors.ErrDuplicatePost}
+	c := flood.Claim{Key: "c", TTL: time.Minute, Err: errors.ErrPostingTooFast}
+
+	if held, _, err := store.Acquire(ctx, []flood.Claim{a, b}, now); err != nil || held != "" {
+		t.Fatalf("first acquire: got %q %v", held, err)
+	}
+	held, until, err := store.Acquire(ctx, []flood.Claim{a, b, c}, now.Add(time.Second))
+	if err != nil || held != "b" || !until.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected b to be held until %v, got %q %v %v", now.Add(time.Hour), held, until, err)
+	}
+	// The refused acquire must not have taken c.
+	if held, _, _ := store.Acquire(ctx, []flood.Claim{c}, now.Add(time.Second)); held != "" {
+		t.Errorf("expected c to be free, %q is held", held)
+	}
+	if held, _, _ := store.Acquire(ctx, []flood.Claim{a}, now.Add(2*time.Minute)); held != "" {
+		t.Errorf("expected a to expire, %q is held", held)
+	}
+
+	if err := store.DeleteExpired(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if held, _, _ := store.Acquire(ctx, []flood.Claim{b}, now.Add(2*time.Hour)); held != "" {
+		t.Errorf("expected b to be deleted, %q is held", held)
+	}
+}
@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"strings"
+	"testing"
+)
+
+func TestPostLimits(t *testing.T) {
+	limits := post.Limits{MaxTitleLength: 5, MaxContentLength: 10, MaxLines: 2, MaxAttachments: 2, MaxAttachmentSize: 100}
+
+	if err := limits.CheckTitle("ёжики"); err != nil {
+		t.Errorf("title length must count runes, got %v", err)
+	}
+	if err := limits.CheckTitle("ёжики!"); err != errors.ErrTooLongTitle {
+		t.Errorf("long title: got %v", err)
+	}
+	if err := limits.CheckContent("a\nb"); err != nil {
+		t.Errorf("two lines: got %v", err)
+	}
+	if err := limits.CheckContent("a\nb\nc"); err != errors.ErrTooManyLines {
+		t.Errorf("three lines: got %v", err)
+	}
+	if err := limits.CheckContent(strings.Repeat("x", 11)); err != errors.ErrTooLongContent {
+		t.Errorf("long content: got %v", err)
+	}
+	if err := limits.CheckAttachments(3); err != errors.ErrTooManyAttachments {
+		t.Errorf("three attachments: got %v", err)
+	}
+	if err := limits.CheckAttachmentSize(101); err != errors.ErrAttachmentTooLarge {
+		t.Errorf("large attachment: got %v", err)
+	}
+	if got := limits.MaxUploadSize(); got != 2*100+1<<20 {
+		t.Errorf("max upload size: got %d", got)
+	}
+}
+
+func TestPostLimits_ZeroMeansUnlimited(t *testing.T) {
+	var limits post.Limits
+
+	if err := limits.CheckTitle(strings.Repeat("t", 10000)); err != nil {
+		t.Errorf("title: got %v", err)
+	}
+	if err := limits.CheckContent(strings.Repeat("line\n", 10000)); err != nil {
+		t.Errorf("content: got %v", err)
+	}
+	if err := limits.CheckAttachments(100); err != nil {
+		t.Errorf("attachments: got %v", err)
+	}
+	if err := limits.CheckAttachmentSize(1 << 40); err != nil {
+		t.Errorf("attachment size: got %v", err)
+	}
+	if got := limits.MaxUploadSize(); got != 0 {
+		t.Errorf("max upload size: got %d", got)
+	}
+}
@@ -0,0 +1,41 @@
+package unit
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/markup"
+	"testing"
+)
+
+func TestMarkupRender(t *testing.T) {
+	threadID, _ := utils.ParseUUID("11111111-1111-4111-8111-111111111111")
+	commentID := "22222222-2222-4222-8222-222222222222"
+
+	cases := []struct {
+		name, in, want string
+	}{
+		{"escapes html", `<script>alert("x")</script>`, `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`},
+		{"line breaks", "a\nb", "a<br>b"},
+		{"greentext", ">be me\nok", `<span class="greentext">&gt;be me</span><br>ok`},
+		{"quote", ">>" + commentID + " yes", `<a href="#c-` + commentID + `" class="quotelink">&gt;&gt;` + commentID + `</a> yes`},
+		{"quote op", ">>" + threadID.String(), `<a href="#thread" class="quotelink">&gt;&gt;` + threadID.String() + ` (OP)</a>`},
+		{"quote number", ">>123 yes", `<a href="#p123" class="quotelink">&gt;&gt;123</a> yes`},
+		{"digits starting an id", ">>123abc", `<span class="greentext">&gt;&gt;123abc</span>`},
+		{"spoiler", "a [spoiler]b[/spoiler] c", `a <span class="spoiler">b</span> c`},
+		{"unclosed spoiler", "[spoiler]b", `<span class="spoiler">b</span>`},
+		{"stray spoiler close", "b[/spoiler]", `b[/spoiler]`},
+		{"inline code", "run `<b>[spoiler]` now", `run <code>&lt;b&gt;[spoiler]</code> now`},
+		{"fenced code", "x\n```go\n>a <b>\n```\ny", "x<pre><code>&gt;a &lt;b&gt;</code></pre>y"},
+		{"link", "see https://example.com/a?b=1&c=2.", `see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer" target="_blank">https://example.com/a?b=1&amp;c=2</a>.`},
+		{"link in parentheses", "(http://example.com)", `(<a href="http://example.com" rel="nofollow noopener noreferrer" target="_blank">http://example.com</a>)`},
+		{"no javascript links", "javascript:alert(1)", "javascript:alert(1)"},
+		{"link cannot break out", `http://x.com/"onmouseover="a`, `<a href="http://x.com/" rel="nofollow noopener noreferrer" target="_blank">http://x.com/</a>&#34;onmouseover=&#34;a`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := markup.Render(tc.in, threadID); got != tc.want {
+				t.Errorf("Render(%q)\n got  %s\n want %s", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/moderator"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModeratorPasswordHash(t *testing.T) {
+	hash, err := moderator.HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") {
+		t.Errorf("expected an encoded argon2id hash, got %q", hash)
+	}
+	if again, _ := moderator.HashPassword("correct horse battery"); again == hash {
+		t.Error("two hashes of one password must differ by their salt")
+	}
+
+	if !moderator.CheckPassword(hash, "correct horse battery") {
+		t.Error("the password must match its hash")
+	}
+	if moderator.CheckPassword(hash, "correct horse batterY") {
+		t.Error("a wrong password matched")
+	}
+	for _, broken := range []string{"", "plain", strings.Replace(hash, "argon2id", "argon2i", 1), hash[:len(hash)-4]} {
+		if moderator.CheckPassword(broken, "correct horse battery") {
+			t.Errorf("malformed hash %q matched", broken)
+		}
+	}
+
+	if _, err := moderator.HashPassword("short"); err != errors.ErrWeakPassword {
+		t.Errorf("expected a short password to be refused, got %v", err)
+	}
+}
+
+func TestModeratorRoles(t *testing.T) {
+	tests := []struct {
+		role, min moderator.Role
+		want      bool
+	}{
+		{moderator.RoleAdmin, moderator.RoleJanitor, true},
+		{moderator.RoleModerator, moderator.RoleModerator, true},
+		{moderator.RoleJanitor, moderator.RoleModerator, false},
+		{moderator.RoleModerator, moderator.RoleAdmin, false},
+		{moderator.Role("root"), moderator.RoleJanitor, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.AtLeast(tt.min); got != tt.want {
+			t.Errorf("%s.AtLeast(%s) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+
+	if _, err := moderator.NewModerator("jan", "long enough pw", moderator.Role("king")); err != errors.ErrInvalidRole {
+		t.Errorf("expected an unknown role to be refused, got %v", err)
+	}
+	if _, err := moderator.NewModerator("Bad Name", "long enough pw", moderator.RoleJanitor); err != errors.ErrInvalidModeratorName {
+		t.Errorf("expected an invalid username to be refused, got %v", err)
+	}
+}
+
+func TestModeratorLoginToken(t *testing.T) {
+	m, err := moderator.NewModerator("jan", "mop and bucket", moderator.RoleJanitor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login, token, err := moderator.NewLogin(m.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.TokenHash == token || login.TokenHash != moderator.HashToken(token) {
+		t.Error("the login must store the hash of its token, not the token")
+	}
+	if login.IsExpired(time.Now()) || !login.IsExpired(login.ExpiresAt) {
+		t.Error("a login must expire exactly at ExpiresAt")
+	}
+}
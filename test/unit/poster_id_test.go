@@ -0,0 +1,32 @@
+package unit
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/thread"
+	"testing"
+)
+
+func TestPosterID(t *testing.T) {
+	secret := []byte("secret")
+	session, _ := utils.NewUUID()
+	other, _ := utils.NewUUID()
+	first, _ := utils.NewUUID()
+	second, _ := utils.NewUUID()
+
+	id := thread.PosterID(secret, session, first)
+	if len(id) != 8 {
+		t.Fatalf("expected an 8 character ID, got %q", id)
+	}
+	if again := thread.PosterID(secret, session, first); again != id {
+		t.Errorf("the same session and thread must give the same ID, got %q and %q", id, again)
+	}
+	if elsewhere := thread.PosterID(secret, session, second); elsewhere == id {
+		t.Errorf("the same session must get another ID in another thread, got %q twice", id)
+	}
+	if someone := thread.PosterID(secret, other, first); someone == id {
+		t.Errorf("two sessions got the same ID %q", id)
+	}
+	if rekeyed := thread.PosterID([]byte("other"), session, first); rekeyed == id {
+		t.Errorf("the ID must depend on the secret")
+	}
+}
@@ -0,0 +1,30 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/comment"
+	"reflect"
+	"testing"
+)
+
+func TestParsePostNumbers(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []int64
+	}{
+		{"none", "hello", nil},
+		{"several", ">>12 and >>7, >>12 again", []int64{12, 7}},
+		{"adjacent", ">>1>>2", []int64{1, 2}},
+		{"uuid is no number", ">>12345678-1234-4123-8123-123456789abc", nil},
+		{"digits before letters", ">>123abc", nil},
+		{"zero", ">>0", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := comment.ParsePostNumbers(tc.in); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParsePostNumbers(%q) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
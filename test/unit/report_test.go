@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"1337b04rd/internal/app/common/utils"
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/post"
+	"1337b04rd/internal/domain/report"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportValidation(t *testing.T) {
+	postID, _ := utils.NewUUID()
+	sessionID, _ := utils.NewUUID()
+
+	r, err := report.NewReport(postID, sessionID, report.ReasonSpam, "  buy now  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Text != "buy now" {
+		t.Errorf("expected the text to be trimmed, got %q", r.Text)
+	}
+	if _, err := report.NewReport(postID, sessionID, "boring", ""); err != errors.ErrInvalidReportReason {
+		t.Errorf("expected an unknown reason to be refused, got %v", err)
+	}
+	if _, err := report.NewReport(postID, sessionID, report.ReasonOther, strings.Repeat("ё", report.MaxTextLength)); err != nil {
+		t.Errorf("expected the limit to count runes, got %v", err)
+	}
+	if _, err := report.NewReport(postID, sessionID, report.ReasonOther, strings.Repeat("a", report.MaxTextLength+1)); err != errors.ErrReportTooLong {
+		t.Errorf("expected a long text to be refused, got %v", err)
+	}
+}
+
+func TestReportCaseScore(t *testing.T) {
+	threadID, _ := utils.NewUUID()
+	author, _ := utils.NewUUID()
+	c, err := report.NewCase(post.Locator{Number: 1, ThreadID: threadID}, author)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, reason := range []report.Reason{report.ReasonOffTopic, report.ReasonOffTopic, report.ReasonIllegal} {
+		sessionID, _ := utils.NewUUID()
+		r, err := report.NewReport(threadID, sessionID, reason, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Add(r)
+		if r.CaseID != c.ID {
+			t.Errorf("expected the report to join case %s, got %s", c.ID, r.CaseID)
+		}
+	}
+	if c.Score != 12 || c.ReportCount != 3 || c.Reasons[report.ReasonOffTopic] != 2 || c.Reasons[report.ReasonIllegal] != 1 {
+		t.Errorf("expected 3 reports scoring 12, got score %d, count %d, reasons %v", c.Score, c.ReportCount, c.Reasons)
+	}
+}
+
+func TestReportCaseClaimAndResolve(t *testing.T) {
+	threadID, _ := utils.NewUUID()
+	author, _ := utils.NewUUID()
+	jan, _ := utils.NewUUID()
+	mod, _ := utils.NewUUID()
+	c, _ := report.NewCase(post.Locator{Number: 1, ThreadID: threadID}, author)
+	now := time.Now()
+
+	if err := c.Resolve(jan, report.ResolutionDismiss, now); err != errors.ErrReportClaimed {
+		t.Errorf("expected an unclaimed case to stay open, got %v", err)
+	}
+	if err := c.Claim(jan, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Claim(mod, now.Add(time.Minute)); err != errors.ErrReportClaimed {
+		t.Errorf("expected a fresh claim to hold, got %v", err)
+	}
+	if err := c.Claim(jan, now.Add(time.Minute)); err != nil {
+		t.Errorf("expected the holder to renew the claim, got %v", err)
+	}
+
+	// A claim left alone for too long can be taken over.
+	later := now.Add(time.Minute + report.ClaimTTL)
+	if err := c.Claim(mod, later); err != nil {
+		t.Fatalf("expected a stale claim to be taken over, got %v", err)
+	}
+	if err := c.Resolve(jan, report.ResolutionDelete, later); err != errors.ErrReportClaimed {
+		t.Errorf("expected only the holder to resolve, got %v", err)
+	}
+	if err := c.Resolve(mod, "burn", later); err != errors.ErrInvalidResolution {
+		t.Errorf("expected an unknown resolution to be refused, got %v", err)
+	}
+	if err := c.Resolve(mod, report.ResolutionDelete, later); err != nil {
+		t.Fatal(err)
+	}
+	if c.IsOpen() || *c.ResolvedBy != mod {
+		t.Errorf("expected the case to be resolved by %s, got %+v", mod, c)
+	}
+	if err := c.Claim(mod, later); err != errors.ErrReportResolved {
+		t.Errorf("expected a resolved case to refuse claims, got %v", err)
+	}
+}
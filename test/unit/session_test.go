@@ -0,0 +1,28 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/session"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRename(t *testing.T) {
+	sess, err := session.NewSession("http://example.com/rick.png", "Rick", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sess.Rename(strings.Repeat("ж", session.MaxDisplayNameLength), ""); err != nil {
+		t.Errorf("expected the limit to count characters, got %v", err)
+	}
+	if err := sess.Rename("Ёж", ""); err != nil {
+		t.Errorf("expected two characters to be enough, got %v", err)
+	}
+	for _, name := range []string{"ж", strings.Repeat("a", session.MaxDisplayNameLength+1), "Rick!abc", "Rick#abc"} {
+		if err := sess.Rename(name, ""); err != errors.ErrInvalidDisplayName {
+			t.Errorf("%q: expected an invalid name, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package unit
+
+import (
+	"1337b04rd/internal/domain/errors"
+	"1337b04rd/internal/domain/tripcode"
+	"strings"
+	"testing"
+)
+
+func TestTripcodeParse(t *testing.T) {
+	pepper := []byte("pepper")
+
+	name, code, err := tripcode.Parse("Rick", pepper)
+	if err != nil || name != "Rick" || code != "" {
+		t.Errorf("plain name: got %q %q %v", name, code, err)
+	}
+
+	name, code, err = tripcode.Parse("Rick#secret", pepper)
+	if err != nil || name != "Rick" || !strings.HasPrefix(code, "!") || strings.HasPrefix(code, "!!") || len(code) != 11 {
+		t.Errorf("tripcode: got %q %q %v", name, code, err)
+	}
+	if _, again, _ := tripcode.Parse("Morty#secret", nil); again != code {
+		t.Errorf("the same secret must give the same tripcode, got %q and %q", code, again)
+	}
+	if _, other, _ := tripcode.Parse("Rick#Secret", pepper); other == code {
+		t.Errorf("different secrets gave the same tripcode %q", code)
+	}
+
+	_, secure, err := tripcode.Parse("Rick##secret", pepper)
+	if err != nil || !strings.HasPrefix(secure, "!!") || secure[2:] == code[1:] {
+		t.Errorf("secure tripcode: got %q %v", secure, err)
+	}
+	if _, otherPepper, _ := tripcode.Parse("Rick##secret", []byte("salt")); otherPepper == secure {
+		t.Errorf("secure tripcodes must depend on the pepper")
+	}
+	if _, _, err := tripcode.Parse("Rick##secret", nil); err != errors.ErrSecureTripcodeDisabled {
+		t.Errorf("expected secure tripcodes to need a pepper, got %v", err)
+	}
+
+	if name, code, _ := tripcode.Parse("Rick#", pepper); name != "Rick" || code != "" {
+		t.Errorf("empty secret: got %q %q", name, code)
+	}
+}
@@ -0,0 +1,9 @@
+package web
+
+import "embed"
+
+// Templates holds the server-rendered HTML pages so the binary can be
+// deployed without the web/ directory next to it.
+//
+//go:embed templates/*.html
+var Templates embed.FS